@@ -13,15 +13,21 @@ import (
 	"cashcowvalley/backend/internal/delivery/http/handler"
 	"cashcowvalley/backend/internal/delivery/http/middleware"
 	"cashcowvalley/backend/internal/usecase"
+	"cashcowvalley/backend/pkg/chain"
+	"cashcowvalley/backend/pkg/realtime"
 	customRedis "cashcowvalley/backend/pkg/redis"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// 1. Initialize DB and Redis
+	// 1. Initialize DB, Redis and EVM RPC
 	db := config.InitDatabase()
 	customRedis.InitRedis()
+	var chainClient chain.Writer
+	if client := chain.Dial(); client != nil {
+		chainClient = client
+	}
 
 	// 2. Setup Clean Architecture Dependencies
 	farmUC := usecase.NewFarmUsecase(db)
@@ -30,14 +36,49 @@ func main() {
 	userUC := usecase.NewUserUsecase(db)
 	authUC := usecase.NewAuthUsecase(db)
 	adminUC := usecase.NewAdminUsecase(db)
+	reconUC := usecase.NewReconciliationUsecase(db)
+	catalogUC := usecase.NewCatalogUsecase(db)
+	economyUC := usecase.NewEconomyUsecase(db)
+	ammUC := usecase.NewAMMUsecase(db)
+	emissionUC := usecase.NewEmissionUsecase(db)
+	idempotencyUC := usecase.NewIdempotencyUsecase(db)
+	transactionUC := usecase.NewTransactionUsecase(db)
+	statementUC := usecase.NewStatementUsecase(db)
+	orderBookUC := usecase.NewOrderBookUsecase(db)
+	auctionUC := usecase.NewAuctionUsecase(db)
+	marketDataUC := usecase.NewMarketDataUsecase(db)
+	realtimeUC := usecase.NewRealtimeUsecase(db)
+	depositCfg := usecase.LoadDepositConfig()
+	depositUC := usecase.NewDepositUsecase(db, chainClient, depositCfg)
+	withdrawalUC := usecase.NewWithdrawalUsecase(db, chainClient, usecase.LoadWithdrawalConfig(depositCfg.Tokens))
 
-	// Seed Dev Wallet as Root Admin
+	// Seed Dev Wallet as Root Admin, default economy config, AMM pools, platform catalog, legacy treasury & market escrow, cow hunger state, market candles & trading stats
 	authUC.SeedDevWallet(context.Background())
+	economyUC.SeedDefault(context.Background())
+	ammUC.SeedPools(context.Background())
+	catalogUC.SeedDefaults(context.Background())
+	adminUC.MigrateLegacyTreasury(context.Background())
+	marketUC.MigrateLegacyEscrow(context.Background())
+	farmUC.MigrateHungerRollout(context.Background())
+	marketDataUC.BackfillCandles(context.Background())
+	marketDataUC.BackfillTradingStats(context.Background())
 
-	gameHandler := handler.NewGameHandler(farmUC, marketUC, adWebhookUC, userUC)
-	userHandler := handler.NewUserHandler(userUC)
+	gameHandler := handler.NewGameHandler(farmUC, marketUC, adWebhookUC, userUC, depositUC, withdrawalUC, catalogUC, ammUC, orderBookUC, auctionUC, marketDataUC)
+	userHandler := handler.NewUserHandler(userUC, transactionUC, statementUC)
 	authHandler := handler.NewAuthHandler(authUC)
-	adminHandler := handler.NewAdminHandler(adminUC)
+	realtimeHandler := handler.NewRealtimeHandler(realtimeUC)
+	adminHandler := handler.NewAdminHandler(adminUC, reconUC, catalogUC, economyUC, ammUC, emissionUC, statementUC, withdrawalUC)
+
+	// Background Workers (dihentikan saat graceful shutdown)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	go reconUC.StartNightly(workerCtx)
+	go depositUC.Start(workerCtx)
+	go withdrawalUC.Start(workerCtx)
+	go idempotencyUC.StartJanitor(workerCtx)
+	go marketUC.StartExpirySweeper(workerCtx)
+	go auctionUC.StartSettlementWorker(workerCtx)
+	go realtime.Start(workerCtx)
+	go realtimeUC.StartHarvestNotifier(workerCtx)
 
 	// 3. Setup Router
 	if os.Getenv("ENV") == "production" {
@@ -53,7 +94,7 @@ func main() {
 
 	// V1 API Group
 	v1 := r.Group("/api/v1")
-	v1.Use(middleware.RateLimiter(customRedis.Client))
+	v1.Use(middleware.RateLimiter(customRedis.Client), middleware.RealtimeEvents(realtimeUC))
 	{
 		v1.GET("/health", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "UP"})
@@ -64,16 +105,29 @@ func main() {
 		v1.POST("/auth/login", authHandler.LoginWithSignatureHandler)
 		v1.POST("/auth/login-legacy", authHandler.LoginOrRegisterHandler) // Dev/testing only
 
+		// Public trading reputation
+		v1.GET("/users/:wallet/trading-profile", gameHandler.GetTradingProfileHandler)
+
 		// Webhooks (Public but Signature protected)
 		v1.POST("/webhooks/ad-reward", gameHandler.AdWebhookHandler)
 
+		// Real-time stream (SSE). EventSource tidak bisa set header, jadi klien memakai tiket sekali pakai
+		// dari POST /stream/ticket lewat query `ticket` (JWT sesi tidak pernah masuk URL). Auto-reconnect
+		// EventSource memakai URL lama yang sudah ditolak, jadi klien meminta tiket baru setiap reconnect
+		v1.GET("/stream", middleware.RequireStreamAuth(), realtimeHandler.StreamHandler)
+
 		// Protected Game Routes
 		protected := v1.Group("/")
-		protected.Use(middleware.RequireAuth())
+		protected.Use(middleware.RequireAuth(), middleware.Idempotency(idempotencyUC))
 		{
+			// Tiket stream SSE
+			protected.POST("/stream/ticket", authHandler.StreamTicketHandler)
+
 			// User / Referral
 			protected.POST("/user/referral/bind", userHandler.BindReferrerHandler)
 			protected.GET("/user/referral/stats", userHandler.GetReferralStatsHandler)
+			protected.GET("/user/transactions", userHandler.GetTransactionsHandler)
+			protected.GET("/user/statements", userHandler.GetStatementHandler)
 
 			// Farm
 			protected.GET("/farm/status", gameHandler.GetFarmStatusHandler)
@@ -83,26 +137,69 @@ func main() {
 			// Market (P2P & Platform)
 			protected.GET("/market/listings", gameHandler.GetMarketListingsHandler)
 			protected.POST("/market/buy", gameHandler.BuyItemHandler)
+			protected.POST("/market/sweep", gameHandler.SweepBuyHandler)
 			protected.POST("/market/sell", gameHandler.SellItemHandler)
+			protected.POST("/market/sell-cow", gameHandler.SellCowHandler)
+			protected.GET("/market/listings/:id/fills", gameHandler.GetListingFillsHandler)
+			protected.GET("/market/listings/:id/prices", gameHandler.GetListingPriceHistoryHandler)
+			protected.POST("/market/listings/:id/cancel", gameHandler.CancelListingHandler)
+			protected.POST("/market/listings/:id/reprice", gameHandler.RepriceListingHandler)
+			protected.POST("/market/listings/:id/offers", gameHandler.MakeOfferHandler)
+			protected.GET("/market/offers", gameHandler.GetMyOffersHandler)
+			protected.POST("/market/offers/:id/respond", gameHandler.RespondOfferHandler)
+			protected.POST("/market/offers/:id/cancel", gameHandler.CancelOfferHandler)
+			protected.GET("/market/orderbook", gameHandler.GetOrderBookHandler)
+			protected.GET("/market/candles", gameHandler.GetCandlesHandler)
+			protected.GET("/market/ticker", gameHandler.GetTickerHandler)
+			protected.GET("/market/bids", gameHandler.GetMyBidsHandler)
+			protected.POST("/market/bids", gameHandler.PlaceBidHandler)
+			protected.POST("/market/bids/:id/cancel", gameHandler.CancelBidHandler)
+			protected.GET("/market/auctions", gameHandler.GetAuctionsHandler)
+			protected.GET("/market/auctions/:id", gameHandler.GetAuctionHandler)
+			protected.POST("/market/auctions", gameHandler.CreateAuctionHandler)
+			protected.POST("/market/auctions/:id/bid", gameHandler.BidAuctionHandler)
+			protected.POST("/market/auctions/:id/cancel", gameHandler.CancelAuctionHandler)
+			protected.GET("/market/platform/catalog", gameHandler.GetPlatformCatalogHandler)
 			protected.POST("/market/platform/buy", gameHandler.BuyPlatformItemHandler)
 
 			// Gold Economy
 			protected.POST("/market/sell-milk-gold", gameHandler.SellMilkForGoldHandler)
 			protected.POST("/market/buy-inapp-gold", gameHandler.BuyInAppItemHandler)
 			protected.POST("/market/swap-gold", gameHandler.SwapGoldHandler)
+			protected.GET("/market/swap-gold/quote", gameHandler.QuoteSwapGoldHandler)
 			protected.POST("/market/stake-inapp", gameHandler.StakeInAppHandler)
 			protected.POST("/market/claim-inapp", gameHandler.ClaimInAppHandler)
 			protected.POST("/market/deposit", gameHandler.DepositHandler)
+			protected.GET("/market/deposits", gameHandler.GetDepositsHandler)
 			protected.POST("/market/withdraw", gameHandler.WithdrawHandler)
+			protected.GET("/market/withdrawals", gameHandler.GetWithdrawalsHandler)
+			protected.GET("/market/withdrawals/:id", gameHandler.GetWithdrawalHandler)
 		}
 
 		// Admin Routes (ADMIN role required)
 		admin := v1.Group("/admin")
-		admin.Use(middleware.RequireAuth(), middleware.RequireRole("ADMIN"))
+		admin.Use(middleware.RequireAuth(), middleware.RequireRole("ADMIN"), middleware.Idempotency(idempotencyUC))
 		{
 			admin.POST("/transfer", adminHandler.TransferHandler)
 			admin.GET("/users", adminHandler.ListUsersHandler)
+			admin.GET("/users/:id/statements", adminHandler.GetUserStatementHandler)
 			admin.GET("/stats", adminHandler.StatsHandler)
+			admin.GET("/treasury", adminHandler.TreasuryHandler)
+			admin.GET("/reconciliation", adminHandler.GetReconciliationHandler)
+			admin.POST("/reconciliation/run", adminHandler.RunReconciliationHandler)
+			admin.GET("/catalog", adminHandler.ListCatalogHandler)
+			admin.POST("/catalog", adminHandler.CreateCatalogItemHandler)
+			admin.PUT("/catalog/:id", adminHandler.UpdateCatalogItemHandler)
+			admin.GET("/economy", adminHandler.ListEconomyConfigsHandler)
+			admin.POST("/economy", adminHandler.ScheduleEconomyConfigHandler)
+			admin.DELETE("/economy/:version", adminHandler.CancelEconomyConfigHandler)
+			admin.GET("/amm/pools", adminHandler.ListAMMPoolsHandler)
+			admin.PUT("/amm/pools/:id/fee", adminHandler.UpdateAMMFeeHandler)
+			admin.POST("/amm/pools/:id/liquidity", adminHandler.AddAMMLiquidityHandler)
+			admin.GET("/emission", adminHandler.GetEmissionHandler)
+			admin.GET("/withdrawals", adminHandler.ListWithdrawalsHandler)
+			admin.POST("/withdrawals/:id/retry", adminHandler.RetryWithdrawalHandler)
+			admin.POST("/withdrawals/:id/refund", adminHandler.RefundWithdrawalHandler)
 		}
 	}
 
@@ -146,6 +243,7 @@ func main() {
 	<-quit
 
 	log.Println("[CASH COW VALLEY] Menerima sinyal shutdown, menunggu request selesai...")
+	stopWorkers()
 
 	// Beri waktu 10 detik untuk request yang sedang berjalan
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -59,11 +59,46 @@ func InitDatabase() *gorm.DB {
 		&domain.Cow{},
 		&domain.TxLog{},
 		&domain.MarketListing{},
+		&domain.Web2Stake{},
+		&domain.LedgerAccount{},
+		&domain.JournalEntry{},
+		&domain.Posting{},
+		&domain.ReconciliationRun{},
+		&domain.ReconciliationReport{},
+		&domain.ChainCursor{},
+		&domain.ChainDeposit{},
+		&domain.Withdrawal{},
+		&domain.SignerNonce{},
+		&domain.PlatformItem{},
+		&domain.PlatformItemPrice{},
+		&domain.PlatformPurchase{},
+		&domain.EconomyConfig{},
+		&domain.AMMPool{},
+		&domain.AMMSwap{},
+		&domain.EmissionEpoch{},
+		&domain.IdempotencyKey{},
+		&domain.MarketFill{},
+		&domain.MarketListingPriceChange{},
+		&domain.MarketBid{},
+		&domain.Auction{},
+		&domain.AuctionBid{},
+		&domain.MarketCandle{},
+		&domain.MarketOffer{},
+		&domain.TradingStat{},
+		&domain.TradingCounterparty{},
 	)
 	if err != nil {
 		log.Fatalf("[DB] Gagal melakukan migrasi: %v", err)
 	}
 
+	// Listing dari sebelum partial fill: PriceUSDT adalah harga total, sisa = seluruh quantity
+	if err := db.Exec(`UPDATE market_listings
+		SET unit_price_usdt = price_usdt / quantity,
+			remaining_quantity = CASE WHEN status = ? THEN quantity ELSE 0 END
+		WHERE unit_price_usdt = 0 AND quantity > 0`, domain.ListingOpen).Error; err != nil {
+		log.Fatalf("[DB] Gagal backfill harga per unit listing: %v", err)
+	}
+
 	log.Println("[DB] Koneksi ke PostgreSQL berhasil dan Migration Selesai!")
 	return db
 }
@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"cashcowvalley/backend/internal/domain"
 	"cashcowvalley/backend/internal/usecase"
 	"cashcowvalley/backend/pkg/utils"
 
@@ -12,11 +17,18 @@ import (
 )
 
 type AdminHandler struct {
-	adminUC *usecase.AdminUsecase
+	adminUC      *usecase.AdminUsecase
+	reconUC      *usecase.ReconciliationUsecase
+	catalogUC    *usecase.CatalogUsecase
+	economyUC    *usecase.EconomyUsecase
+	ammUC        *usecase.AMMUsecase
+	emissionUC   *usecase.EmissionUsecase
+	statementUC  *usecase.StatementUsecase
+	withdrawalUC *usecase.WithdrawalUsecase
 }
 
-func NewAdminHandler(adminUC *usecase.AdminUsecase) *AdminHandler {
-	return &AdminHandler{adminUC: adminUC}
+func NewAdminHandler(adminUC *usecase.AdminUsecase, reconUC *usecase.ReconciliationUsecase, catalogUC *usecase.CatalogUsecase, economyUC *usecase.EconomyUsecase, ammUC *usecase.AMMUsecase, emissionUC *usecase.EmissionUsecase, statementUC *usecase.StatementUsecase, withdrawalUC *usecase.WithdrawalUsecase) *AdminHandler {
+	return &AdminHandler{adminUC: adminUC, reconUC: reconUC, catalogUC: catalogUC, economyUC: economyUC, ammUC: ammUC, emissionUC: emissionUC, statementUC: statementUC, withdrawalUC: withdrawalUC}
 }
 
 // TransferHandler transfers in-app items from admin to a target user.
@@ -76,3 +88,335 @@ func (h *AdminHandler) StatsHandler(c *gin.Context) {
 
 	utils.SendSuccess(c, http.StatusOK, "Stats berhasil diambil", stats, nil)
 }
+
+// TreasuryHandler returns balances of the platform treasury accounts (LP buyback, dev, referral pool).
+// GET /admin/treasury
+func (h *AdminHandler) TreasuryHandler(c *gin.Context) {
+	balances, err := h.adminUC.ListTreasury(c.Request.Context())
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal mengambil saldo treasury", nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Saldo treasury berhasil diambil", balances, nil)
+}
+
+// GetReconciliationHandler returns the latest (or a specific) reconciliation run with its drift reports.
+// GET /admin/reconciliation?run_id=
+func (h *AdminHandler) GetReconciliationHandler(c *gin.Context) {
+	var runID *uuid.UUID
+	if raw := c.Query("run_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Run ID tidak valid", nil)
+			return
+		}
+		runID = &id
+	}
+
+	result, err := h.reconUC.GetReports(c.Request.Context(), runID)
+	if errors.Is(err, usecase.ErrNoReconciliationRun) {
+		utils.SendError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal mengambil hasil rekonsiliasi", nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Hasil rekonsiliasi berhasil diambil", result, nil)
+}
+
+// RunReconciliationHandler triggers a reconciliation run immediately.
+// POST /admin/reconciliation/run
+func (h *AdminHandler) RunReconciliationHandler(c *gin.Context) {
+	run, err := h.reconUC.Run(c.Request.Context(), "MANUAL")
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal menjalankan rekonsiliasi", nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Rekonsiliasi selesai", run, nil)
+}
+
+// ListCatalogHandler returns every platform catalog item, including inactive ones.
+// GET /admin/catalog
+func (h *AdminHandler) ListCatalogHandler(c *gin.Context) {
+	items, err := h.catalogUC.ListAll(c.Request.Context())
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal mengambil katalog", nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Katalog berhasil diambil", items, nil)
+}
+
+// CreateCatalogItemHandler adds a new item to the platform catalog.
+// POST /admin/catalog
+func (h *AdminHandler) CreateCatalogItemHandler(c *gin.Context) {
+	var req usecase.CatalogItemInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	item, err := h.catalogUC.CreateItem(c.Request.Context(), req)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Item katalog berhasil dibuat", item, nil)
+}
+
+// UpdateCatalogItemHandler replaces an item's attributes and prices.
+// PUT /admin/catalog/:id
+func (h *AdminHandler) UpdateCatalogItemHandler(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Item ID tidak valid", nil)
+		return
+	}
+
+	var req usecase.CatalogItemInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	item, err := h.catalogUC.UpdateItem(c.Request.Context(), itemID, req)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Item katalog berhasil diperbarui", item, nil)
+}
+
+// ListEconomyConfigsHandler returns the active economy config and every scheduled/past version.
+// GET /admin/economy
+func (h *AdminHandler) ListEconomyConfigsHandler(c *gin.Context) {
+	active, err := h.economyUC.Active(c.Request.Context())
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	versions, err := h.economyUC.List(c.Request.Context())
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal mengambil konfigurasi ekonomi", nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Konfigurasi ekonomi berhasil diambil", gin.H{
+		"active":   active,
+		"versions": versions,
+	}, nil)
+}
+
+// ScheduleEconomyConfigHandler creates a new economy config version effective at a given time.
+// Params is a partial object; omitted fields are copied from the latest version.
+// POST /admin/economy
+func (h *AdminHandler) ScheduleEconomyConfigHandler(c *gin.Context) {
+	adminIDStr := c.GetString("user_id")
+	adminID, err := uuid.Parse(adminIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "Admin ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		Params      json.RawMessage `json:"params" binding:"required"`
+		EffectiveAt *time.Time      `json:"effective_at"`
+		Note        string          `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	cfg, err := h.economyUC.Schedule(c.Request.Context(), adminID, req.Params, req.EffectiveAt, req.Note)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Konfigurasi ekonomi berhasil dijadwalkan", cfg, nil)
+}
+
+// CancelEconomyConfigHandler deletes a version that has not taken effect yet.
+// DELETE /admin/economy/:version
+func (h *AdminHandler) CancelEconomyConfigHandler(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Versi tidak valid", nil)
+		return
+	}
+
+	if err := h.economyUC.Cancel(c.Request.Context(), version); err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Jadwal konfigurasi ekonomi dibatalkan", nil, nil)
+}
+
+// ListAMMPoolsHandler returns every AMM pool with its reserves and fee.
+// GET /admin/amm/pools
+func (h *AdminHandler) ListAMMPoolsHandler(c *gin.Context) {
+	pools, err := h.ammUC.ListPools(c.Request.Context())
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal mengambil pool AMM", nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Pool AMM berhasil diambil", pools, nil)
+}
+
+// UpdateAMMFeeHandler changes a pool's swap fee.
+// PUT /admin/amm/pools/:id/fee
+func (h *AdminHandler) UpdateAMMFeeHandler(c *gin.Context) {
+	poolID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Pool ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		FeeBps *int `json:"fee_bps" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	pool, err := h.ammUC.UpdateFee(c.Request.Context(), poolID, *req.FeeBps)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Fee pool berhasil diperbarui", pool, nil)
+}
+
+// AddAMMLiquidityHandler mints additional reserves into a pool.
+// POST /admin/amm/pools/:id/liquidity
+func (h *AdminHandler) AddAMMLiquidityHandler(c *gin.Context) {
+	poolID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Pool ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		BaseAmount  decimal.Decimal `json:"base_amount"`
+		QuoteAmount decimal.Decimal `json:"quote_amount"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	pool, err := h.ammUC.AddLiquidity(c.Request.Context(), poolID, req.BaseAmount, req.QuoteAmount)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Likuiditas pool berhasil ditambahkan", pool, nil)
+}
+
+// ListWithdrawalsHandler returns withdrawals in a given state (default REVIEW, waiting for an operator).
+// GET /admin/withdrawals?state=
+func (h *AdminHandler) ListWithdrawalsHandler(c *gin.Context) {
+	withdrawals, err := h.withdrawalUC.ListWithdrawals(c.Request.Context(), c.Query("state"))
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal mengambil data withdraw", nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Data withdraw berhasil diambil", withdrawals, nil)
+}
+
+// RetryWithdrawalHandler puts a REVIEW withdrawal back into the worker queue.
+// POST /admin/withdrawals/:id/retry
+func (h *AdminHandler) RetryWithdrawalHandler(c *gin.Context) {
+	withdrawalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Withdraw ID tidak valid", nil)
+		return
+	}
+
+	withdrawal, err := h.withdrawalUC.RetryWithdrawal(c.Request.Context(), withdrawalID)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Withdraw dikembalikan ke antrean", withdrawal, nil)
+}
+
+// RefundWithdrawalHandler refunds a REVIEW withdrawal. If its nonce may still carry the payout, a cancel tx is
+// broadcast first and the withdrawal stays CANCELLING until the worker refunds it.
+// POST /admin/withdrawals/:id/refund
+func (h *AdminHandler) RefundWithdrawalHandler(c *gin.Context) {
+	withdrawalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Withdraw ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	withdrawal, err := h.withdrawalUC.RefundWithdrawal(c.Request.Context(), withdrawalID, req.Reason)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	if withdrawal.State == domain.WithdrawalCancelling {
+		utils.SendSuccess(c, http.StatusAccepted, "Tx pembatalan dikirim, saldo di-refund setelah tx ter-mine", withdrawal, nil)
+		return
+	}
+	utils.SendSuccess(c, http.StatusOK, "Withdraw di-refund ke saldo user", withdrawal, nil)
+}
+
+// GetEmissionHandler returns today's COW emission budget and halving schedule.
+// GET /admin/emission
+func (h *AdminHandler) GetEmissionHandler(c *gin.Context) {
+	status, err := h.emissionUC.Status(c.Request.Context())
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal mengambil status emisi", nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Status emisi COW berhasil diambil", status, nil)
+}
+
+// GetUserStatementHandler returns any user's monthly statement for support/disputes.
+// GET /admin/users/:id/statements?month=YYYY-MM&format=csv|json
+func (h *AdminHandler) GetUserStatementHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "User ID tidak valid", nil)
+		return
+	}
+
+	month, err := usecase.ParseStatementMonth(c.Query("month"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	statement, err := h.statementUC.Monthly(c.Request.Context(), userID, month)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	sendStatement(c, statement, c.Query("format"))
+}
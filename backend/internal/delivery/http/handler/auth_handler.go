@@ -5,8 +5,10 @@ import (
 	"strings"
 
 	"cashcowvalley/backend/internal/usecase"
+	"cashcowvalley/backend/pkg/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
@@ -98,3 +100,25 @@ func (h *AuthHandler) LoginOrRegisterHandler(c *gin.Context) {
 		"token":  token,
 	})
 }
+
+// StreamTicketHandler issues a short-lived, single-use ticket for opening the SSE stream.
+// Clients request a fresh ticket for every (re)connect.
+// POST /stream/ticket
+func (h *AuthHandler) StreamTicketHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	ticket, err := h.authUC.IssueStreamTicket(c.Request.Context(), userID)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Tiket stream berhasil dibuat", gin.H{
+		"ticket":     ticket,
+		"expires_in": int(usecase.StreamTicketTTL.Seconds()),
+	}, nil)
+}
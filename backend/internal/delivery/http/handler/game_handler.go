@@ -3,6 +3,9 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"cashcowvalley/backend/internal/usecase"
 	"cashcowvalley/backend/pkg/utils"
@@ -17,14 +20,28 @@ type GameHandler struct {
 	marketUC    *usecase.MarketUsecase
 	adWebhookUC *usecase.AdWebhookUsecase
 	userUC      *usecase.UserUsecase
+	depositUC   *usecase.DepositUsecase
+	withdrawUC  *usecase.WithdrawalUsecase
+	catalogUC   *usecase.CatalogUsecase
+	ammUC       *usecase.AMMUsecase
+	orderBookUC *usecase.OrderBookUsecase
+	auctionUC   *usecase.AuctionUsecase
+	marketData  *usecase.MarketDataUsecase
 }
 
-func NewGameHandler(farmUC *usecase.FarmUsecase, marketUC *usecase.MarketUsecase, adWebhookUC *usecase.AdWebhookUsecase, userUC *usecase.UserUsecase) *GameHandler {
+func NewGameHandler(farmUC *usecase.FarmUsecase, marketUC *usecase.MarketUsecase, adWebhookUC *usecase.AdWebhookUsecase, userUC *usecase.UserUsecase, depositUC *usecase.DepositUsecase, withdrawUC *usecase.WithdrawalUsecase, catalogUC *usecase.CatalogUsecase, ammUC *usecase.AMMUsecase, orderBookUC *usecase.OrderBookUsecase, auctionUC *usecase.AuctionUsecase, marketData *usecase.MarketDataUsecase) *GameHandler {
 	return &GameHandler{
 		farmUC:      farmUC,
 		marketUC:    marketUC,
 		adWebhookUC: adWebhookUC,
 		userUC:      userUC,
+		depositUC:   depositUC,
+		withdrawUC:  withdrawUC,
+		catalogUC:   catalogUC,
+		ammUC:       ammUC,
+		orderBookUC: orderBookUC,
+		auctionUC:   auctionUC,
+		marketData:  marketData,
 	}
 }
 
@@ -91,6 +108,7 @@ func (h *GameHandler) BuyItemHandler(c *gin.Context) {
 
 	var req struct {
 		ListingID string `json:"listing_id" binding:"required"`
+		Quantity  int    `json:"quantity" binding:"min=0"` // 0 / kosong = beli seluruh sisa listing
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
@@ -103,12 +121,13 @@ func (h *GameHandler) BuyItemHandler(c *gin.Context) {
 		return
 	}
 
-	if err := h.marketUC.BuyItem(c.Request.Context(), userID, listingID); err != nil {
+	fill, err := h.marketUC.BuyItem(c.Request.Context(), userID, listingID, req.Quantity)
+	if err != nil {
 		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Pembelian berhasil!", nil, nil)
+	utils.SendSuccess(c, http.StatusOK, "Pembelian berhasil!", fill, nil)
 }
 
 // AdWebhookHandler - POST /api/v1/webhooks/ad-reward
@@ -170,14 +189,64 @@ func (h *GameHandler) GetFarmStatusHandler(c *gin.Context) {
 }
 
 // GetMarketListingsHandler - GET /api/v1/market/listings
+// ?item_type=&min_price=&max_price=&min_quantity=&seller=&sort=newest|oldest|price_asc|price_desc&cursor=&limit=
+// min_price/max_price adalah harga per unit.
 func (h *GameHandler) GetMarketListingsHandler(c *gin.Context) {
-	listings, err := h.marketUC.GetListings(c.Request.Context())
+	filter := usecase.ListingFilter{
+		ItemType:     strings.ToUpper(strings.TrimSpace(c.Query("item_type"))),
+		SellerWallet: strings.TrimSpace(c.Query("seller")),
+		Sort:         strings.ToLower(strings.TrimSpace(c.Query("sort"))),
+		Cursor:       c.Query("cursor"),
+	}
+	for _, p := range []struct {
+		name string
+		dst  **decimal.Decimal
+	}{
+		{"min_price", &filter.MinUnitPrice},
+		{"max_price", &filter.MaxUnitPrice},
+	} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		v, err := decimal.NewFromString(raw)
+		if err != nil || v.IsNegative() {
+			utils.SendError(c, http.StatusBadRequest, "Format "+p.name+" tidak valid", nil)
+			return
+		}
+		*p.dst = &v
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{
+		{"min_quantity", &filter.MinQuantity},
+		{"limit", &filter.Limit},
+	} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			utils.SendError(c, http.StatusBadRequest, "Format "+p.name+" tidak valid", nil)
+			return
+		}
+		*p.dst = v
+	}
+
+	page, err := h.marketUC.SearchListings(c.Request.Context(), filter)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Listings berhasil diambil", listings, nil)
+	meta := gin.H{"limit": page.Limit, "has_more": page.HasMore}
+	if page.NextCursor != "" {
+		meta["next_cursor"] = page.NextCursor
+	}
+	// Data tetap berupa array listing agar kompatibel dengan klien lama
+	utils.SendSuccess(c, http.StatusOK, "Listings berhasil diambil", page.Listings, meta)
 }
 
 // SellItemHandler - POST /api/v1/market/sell
@@ -190,63 +259,710 @@ func (h *GameHandler) SellItemHandler(c *gin.Context) {
 	}
 
 	var req struct {
-		ItemType string `json:"item_type" binding:"required"`
-		Quantity int    `json:"quantity" binding:"required,min=1"`
-		Price    string `json:"price" binding:"required"`
+		ItemType  string `json:"item_type" binding:"required"`
+		Quantity  int    `json:"quantity" binding:"required,min=1"`
+		UnitPrice string `json:"unit_price"` // Harga per item
+		Price     string `json:"price"`      // Harga total (klien lama), dipakai jika unit_price kosong
+		ExpiresAt string `json:"expires_at"` // Opsional, RFC3339
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	var unitPrice decimal.Decimal
+	switch {
+	case req.UnitPrice != "":
+		unitPrice, err = decimal.NewFromString(req.UnitPrice)
+	case req.Price != "":
+		var total decimal.Decimal
+		total, err = decimal.NewFromString(req.Price)
+		unitPrice = total.DivRound(decimal.NewFromInt(int64(req.Quantity)), 16).Truncate(8)
+	default:
+		utils.SendError(c, http.StatusBadRequest, "unit_price wajib diisi", nil)
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format harga tidak valid", nil)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Format expires_at tidak valid (RFC3339)", nil)
+			return
+		}
+		expiresAt = &t
+	}
+
+	listing, err := h.marketUC.SellItem(c.Request.Context(), userID, req.ItemType, req.Quantity, unitPrice, expiresAt)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Item berhasil didaftarkan di marketplace!", listing, nil)
+}
+
+// SellCowHandler - POST /api/v1/market/sell-cow
+func (h *GameHandler) SellCowHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		CowID     string `json:"cow_id" binding:"required"`
+		Price     string `json:"price" binding:"required"`
+		ExpiresAt string `json:"expires_at"` // Opsional, RFC3339
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	cowID, err := uuid.Parse(req.CowID)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Cow ID tidak valid", nil)
+		return
+	}
+	price, err := decimal.NewFromString(req.Price)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format harga tidak valid", nil)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Format expires_at tidak valid (RFC3339)", nil)
+			return
+		}
+		expiresAt = &t
+	}
+
+	listing, err := h.marketUC.SellCow(c.Request.Context(), userID, cowID, price, expiresAt)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Sapi berhasil didaftarkan di marketplace!", listing, nil)
+}
+
+// GetListingFillsHandler - GET /api/v1/market/listings/:id/fills
+func (h *GameHandler) GetListingFillsHandler(c *gin.Context) {
+	listingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Listing ID tidak valid", nil)
+		return
+	}
+
+	fills, err := h.marketUC.GetListingFills(c.Request.Context(), listingID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Riwayat fill berhasil diambil", fills, nil)
+}
+
+// GetListingPriceHistoryHandler - GET /api/v1/market/listings/:id/prices
+func (h *GameHandler) GetListingPriceHistoryHandler(c *gin.Context) {
+	listingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Listing ID tidak valid", nil)
+		return
+	}
+
+	changes, err := h.marketUC.GetListingPriceHistory(c.Request.Context(), listingID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Riwayat harga listing berhasil diambil", changes, nil)
+}
+
+// CancelListingHandler - POST /api/v1/market/listings/:id/cancel
+func (h *GameHandler) CancelListingHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	listingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Listing ID tidak valid", nil)
+		return
+	}
+
+	if err := h.marketUC.CancelListing(c.Request.Context(), userID, listingID); err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Listing dibatalkan, item dikembalikan ke inventory", nil, nil)
+}
+
+// RepriceListingHandler - POST /api/v1/market/listings/:id/reprice
+func (h *GameHandler) RepriceListingHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	listingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Listing ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		UnitPrice string `json:"unit_price" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	unitPrice, err := decimal.NewFromString(req.UnitPrice)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format harga tidak valid", nil)
+		return
+	}
+
+	if err := h.marketUC.RepriceListing(c.Request.Context(), userID, listingID, unitPrice); err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Harga listing berhasil diubah", nil, nil)
+}
+
+// PlaceBidHandler - POST /api/v1/market/bids
+func (h *GameHandler) PlaceBidHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		ItemType  string `json:"item_type" binding:"required"`
+		Quantity  int    `json:"quantity" binding:"required,min=1"`
+		UnitPrice string `json:"unit_price" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	unitPrice, err := decimal.NewFromString(req.UnitPrice)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format harga tidak valid", nil)
+		return
+	}
+
+	result, err := h.orderBookUC.PlaceBid(c.Request.Context(), userID, req.ItemType, req.Quantity, unitPrice)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Bid berhasil dipasang", result, nil)
+}
+
+// SweepBuyHandler - POST /api/v1/market/sweep
+func (h *GameHandler) SweepBuyHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		ItemType     string `json:"item_type" binding:"required"`
+		Quantity     int    `json:"quantity" binding:"required,min=1"`
+		MaxTotalUSDT string `json:"max_total_usdt" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	maxTotal, err := decimal.NewFromString(req.MaxTotalUSDT)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format batas total tidak valid", nil)
+		return
+	}
+
+	result, err := h.marketUC.SweepBuy(c.Request.Context(), userID, req.ItemType, req.Quantity, maxTotal)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Sweep berhasil", result, nil)
+}
+
+// CancelBidHandler - POST /api/v1/market/bids/:id/cancel
+func (h *GameHandler) CancelBidHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	bidID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Bid ID tidak valid", nil)
+		return
+	}
+
+	if err := h.orderBookUC.CancelBid(c.Request.Context(), userID, bidID); err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Bid dibatalkan, USDT dikembalikan ke saldo", nil, nil)
+}
+
+// GetMyBidsHandler - GET /api/v1/market/bids
+func (h *GameHandler) GetMyBidsHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	bids, err := h.orderBookUC.GetMyBids(c.Request.Context(), userID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Daftar bid berhasil diambil", bids, nil)
+}
+
+// MakeOfferHandler - POST /api/v1/market/listings/:id/offers
+func (h *GameHandler) MakeOfferHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	listingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Listing ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		Quantity      int    `json:"quantity"` // 0 = seluruh sisa listing
+		UnitPrice     string `json:"unit_price" binding:"required"`
+		DurationHours int    `json:"duration_hours"` // Opsional, default 24 jam
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	unitPrice, err := decimal.NewFromString(req.UnitPrice)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format harga tidak valid", nil)
+		return
+	}
+
+	offer, err := h.marketUC.MakeOffer(c.Request.Context(), userID, listingID, req.Quantity, unitPrice, time.Duration(req.DurationHours)*time.Hour)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Offer berhasil dikirim ke penjual", offer, nil)
+}
+
+// RespondOfferHandler - POST /api/v1/market/offers/:id/respond
+func (h *GameHandler) RespondOfferHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	offerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Offer ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		Action       string `json:"action" binding:"required"`
+		CounterPrice string `json:"counter_price"` // Wajib untuk COUNTER
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
 		return
 	}
 
-	priceUSDT, err := decimal.NewFromString(req.Price)
+	counterPrice := decimal.Zero
+	if req.CounterPrice != "" {
+		counterPrice, err = decimal.NewFromString(req.CounterPrice)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Format harga counter tidak valid", nil)
+			return
+		}
+	}
+
+	offer, err := h.marketUC.RespondOffer(c.Request.Context(), userID, offerID, req.Action, counterPrice)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Offer berhasil dijawab", offer, nil)
+}
+
+// CancelOfferHandler - POST /api/v1/market/offers/:id/cancel
+func (h *GameHandler) CancelOfferHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	offerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Offer ID tidak valid", nil)
+		return
+	}
+
+	if err := h.marketUC.CancelOffer(c.Request.Context(), userID, offerID); err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Offer dibatalkan, USDT dikembalikan ke saldo", nil, nil)
+}
+
+// GetMyOffersHandler - GET /api/v1/market/offers?role=sent|received
+func (h *GameHandler) GetMyOffersHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	offers, err := h.marketUC.GetMyOffers(c.Request.Context(), userID, c.Query("role"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Daftar offer berhasil diambil", offers, nil)
+}
+
+// GetOrderBookHandler - GET /api/v1/market/orderbook?item_type=
+func (h *GameHandler) GetOrderBookHandler(c *gin.Context) {
+	book, err := h.orderBookUC.GetOrderBook(c.Request.Context(), c.Query("item_type"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Order book berhasil diambil", book, nil)
+}
+
+// CreateAuctionHandler - POST /api/v1/market/auctions
+func (h *GameHandler) CreateAuctionHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		Kind            string `json:"kind" binding:"required"`
+		ItemType        string `json:"item_type" binding:"required"`
+		Quantity        int    `json:"quantity"`
+		CowID           string `json:"cow_id"`
+		StartPrice      string `json:"start_price" binding:"required"`
+		FloorPrice      string `json:"floor_price"`   // Dutch
+		MinIncrement    string `json:"min_increment"` // English
+		DurationMinutes int    `json:"duration_minutes" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	in := usecase.CreateAuctionInput{
+		Kind:     req.Kind,
+		ItemType: req.ItemType,
+		Quantity: req.Quantity,
+		Duration: time.Duration(req.DurationMinutes) * time.Minute,
+	}
+	if req.CowID != "" {
+		cowID, err := uuid.Parse(req.CowID)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Cow ID tidak valid", nil)
+			return
+		}
+		in.CowID = &cowID
+	}
+	prices := []struct {
+		raw string
+		dst *decimal.Decimal
+	}{
+		{req.StartPrice, &in.StartPrice},
+		{req.FloorPrice, &in.FloorPrice},
+		{req.MinIncrement, &in.MinIncrement},
+	}
+	for _, p := range prices {
+		if p.raw == "" {
+			continue
+		}
+		v, err := decimal.NewFromString(p.raw)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Format harga tidak valid", nil)
+			return
+		}
+		*p.dst = v
+	}
+
+	auction, err := h.auctionUC.CreateAuction(c.Request.Context(), userID, in)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Lelang berhasil dibuka", auction, nil)
+}
+
+// GetAuctionsHandler - GET /api/v1/market/auctions
+func (h *GameHandler) GetAuctionsHandler(c *gin.Context) {
+	auctions, err := h.auctionUC.ListAuctions(c.Request.Context())
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Daftar lelang berhasil diambil", auctions, nil)
+}
+
+// GetAuctionHandler - GET /api/v1/market/auctions/:id
+func (h *GameHandler) GetAuctionHandler(c *gin.Context) {
+	auctionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Auction ID tidak valid", nil)
+		return
+	}
+
+	detail, err := h.auctionUC.GetAuction(c.Request.Context(), auctionID)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Detail lelang berhasil diambil", detail, nil)
+}
+
+// BidAuctionHandler - POST /api/v1/market/auctions/:id/bid
+func (h *GameHandler) BidAuctionHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	auctionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Auction ID tidak valid", nil)
+		return
+	}
+
+	var req struct {
+		Amount string `json:"amount" binding:"required"` // English: penawaran; Dutch: harga maksimum
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
+		return
+	}
+
+	amount, err := decimal.NewFromString(req.Amount)
 	if err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Format harga tidak valid", nil)
 		return
 	}
 
-	if err := h.marketUC.SellItem(c.Request.Context(), userID, req.ItemType, req.Quantity, priceUSDT); err != nil {
+	auction, err := h.auctionUC.PlaceBid(c.Request.Context(), userID, auctionID, amount)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Penawaran berhasil diajukan", auction, nil)
+}
+
+// CancelAuctionHandler - POST /api/v1/market/auctions/:id/cancel
+func (h *GameHandler) CancelAuctionHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	auctionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Auction ID tidak valid", nil)
+		return
+	}
+
+	if err := h.auctionUC.CancelAuction(c.Request.Context(), userID, auctionID); err != nil {
 		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Item berhasil didaftarkan di marketplace!", nil, nil)
+	utils.SendSuccess(c, http.StatusOK, "Lelang dibatalkan, item dikembalikan", nil, nil)
+}
+
+// GetCandlesHandler - GET /api/v1/market/candles?item_type=GRASS&interval=1h&from=&to=&limit=
+func (h *GameHandler) GetCandlesHandler(c *gin.Context) {
+	interval := c.DefaultQuery("interval", "1h")
+	itemType := strings.ToUpper(strings.TrimSpace(c.Query("item_type")))
+
+	var from, to *time.Time
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"from", &from},
+		{"to", &to},
+	} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Format "+p.name+" tidak valid (RFC3339)", nil)
+			return
+		}
+		*p.dst = &t
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			utils.SendError(c, http.StatusBadRequest, "Limit tidak valid", nil)
+			return
+		}
+		limit = v
+	}
+
+	candles, err := h.marketData.GetCandles(c.Request.Context(), itemType, interval, from, to, limit)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Candle berhasil diambil", candles, gin.H{"item_type": itemType, "interval": interval})
+}
+
+// GetTickerHandler - GET /api/v1/market/ticker?item_type= (tanpa item_type: semua item)
+func (h *GameHandler) GetTickerHandler(c *gin.Context) {
+	itemType := strings.ToUpper(strings.TrimSpace(c.Query("item_type")))
+	if itemType == "" {
+		tickers, err := h.marketData.GetTickers(c.Request.Context())
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+			return
+		}
+		utils.SendSuccess(c, http.StatusOK, "Ticker berhasil diambil", tickers, nil)
+		return
+	}
+
+	ticker, err := h.marketData.GetTicker(c.Request.Context(), itemType)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Ticker berhasil diambil", ticker, nil)
+}
+
+// GetTradingProfileHandler - GET /api/v1/users/:wallet/trading-profile (publik)
+func (h *GameHandler) GetTradingProfileHandler(c *gin.Context) {
+	profile, err := h.marketData.GetTradingProfile(c.Request.Context(), c.Param("wallet"))
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Profil trading berhasil diambil", profile, nil)
+}
+
+// GetPlatformCatalogHandler - GET /api/v1/market/platform/catalog
+func (h *GameHandler) GetPlatformCatalogHandler(c *gin.Context) {
+	items, err := h.catalogUC.ListOnSale(c.Request.Context())
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Katalog platform berhasil diambil", items, nil)
 }
 
 // BuyPlatformItemHandler handles purchasing items directly from the system/platform.
+// Harga diambil dari katalog server berdasarkan SKU, bukan dari client.
 func (h *GameHandler) BuyPlatformItemHandler(c *gin.Context) {
-	userIDVal, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Akses ditolak"})
+	userIDStr := c.GetString("user_id")
+	buyerID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
 		return
 	}
-	buyerID := userIDVal.(uuid.UUID)
 
 	var req struct {
-		ItemType string          `json:"item_type" binding:"required"`
-		Quantity int             `json:"quantity" binding:"required"`
-		Price    decimal.Decimal `json:"price" binding:"required"`
-		Currency string          `json:"currency" binding:"required"`
+		SKU      string `json:"sku" binding:"required"`
+		Quantity int    `json:"quantity" binding:"required"`
+		Currency string `json:"currency" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Format request tidak valid"})
+		utils.SendError(c, http.StatusBadRequest, "Format request tidak valid", nil)
 		return
 	}
 
-	err := h.marketUC.BuyFromPlatform(c.Request.Context(), buyerID, req.ItemType, req.Quantity, req.Price, req.Currency, h.userUC)
+	purchase, err := h.marketUC.BuyFromPlatform(c.Request.Context(), buyerID, req.SKU, req.Quantity, req.Currency, h.userUC)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  "error",
-			"message": err.Error(),
-		})
+		utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Berhasil melakukan pembelian dari platform",
-	})
+	utils.SendSuccess(c, http.StatusOK, "Berhasil melakukan pembelian dari platform", purchase, nil)
 }
 
 // SellMilkForGoldHandler - POST /api/v1/market/sell-milk-gold
@@ -312,18 +1028,37 @@ func (h *GameHandler) SwapGoldHandler(c *gin.Context) {
 	var req struct {
 		Amount decimal.Decimal `json:"amount" binding:"required"`
 		Target string          `json:"target" binding:"required"`
+		MinOut decimal.Decimal `json:"min_out"` // Opsional: batas bawah output (proteksi slippage)
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
 		return
 	}
 
-	if err := h.marketUC.SwapGoldToTokens(c.Request.Context(), userID, req.Amount, req.Target); err != nil {
+	swap, err := h.ammUC.SwapGoldToTokens(c.Request.Context(), userID, req.Amount, req.Target, req.MinOut)
+	if err != nil {
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Gold berhasil ditukar!", swap, nil)
+}
+
+// QuoteSwapGoldHandler - GET /api/v1/market/swap-gold/quote?amount=&target=
+func (h *GameHandler) QuoteSwapGoldHandler(c *gin.Context) {
+	amount, err := decimal.NewFromString(c.Query("amount"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format amount tidak valid", nil)
+		return
+	}
+
+	quote, err := h.ammUC.QuoteGoldSwap(c.Request.Context(), amount, c.Query("target"))
+	if err != nil {
 		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Gold berhasil ditukar!", nil, nil)
+	utils.SendSuccess(c, http.StatusOK, "Quote swap berhasil dihitung", quote, nil)
 }
 
 // StakeInAppHandler - POST /api/v1/market/stake-inapp
@@ -362,31 +1097,58 @@ func (h *GameHandler) ClaimInAppHandler(c *gin.Context) {
 }
 
 // DepositHandler - POST /api/v1/market/deposit
+// Saldo hanya dikredit dari ERC-20 Transfer on-chain yang terverifikasi, bukan dari angka yang dikirim client.
 func (h *GameHandler) DepositHandler(c *gin.Context) {
 	userIDStr := c.GetString("user_id")
-	userID, _ := uuid.Parse(userIDStr)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
 
 	var req struct {
-		Asset  string          `json:"asset" binding:"required"`
-		Amount decimal.Decimal `json:"amount" binding:"required"`
+		TxHash string `json:"tx_hash" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Format payload salah", nil)
 		return
 	}
 
-	if err := h.marketUC.Deposit(c.Request.Context(), userID, req.Asset, req.Amount); err != nil {
+	credited, err := h.depositUC.ConfirmDeposit(c.Request.Context(), userID, req.TxHash)
+	if err != nil {
 		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Deposit sukses!", nil, nil)
+	utils.SendSuccess(c, http.StatusOK, "Deposit sukses!", gin.H{"credited_transfers": credited}, nil)
+}
+
+// GetDepositsHandler - GET /api/v1/market/deposits
+func (h *GameHandler) GetDepositsHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	info, err := h.depositUC.GetDepositInfo(c.Request.Context(), userID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Data deposit berhasil diambil", info, nil)
 }
 
 // WithdrawHandler - POST /api/v1/market/withdraw
 func (h *GameHandler) WithdrawHandler(c *gin.Context) {
 	userIDStr := c.GetString("user_id")
-	userID, _ := uuid.Parse(userIDStr)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
 
 	var req struct {
 		Asset  string          `json:"asset" binding:"required"`
@@ -397,10 +1159,53 @@ func (h *GameHandler) WithdrawHandler(c *gin.Context) {
 		return
 	}
 
-	if err := h.marketUC.Withdraw(c.Request.Context(), userID, req.Asset, req.Amount); err != nil {
+	withdrawal, err := h.withdrawUC.RequestWithdrawal(c.Request.Context(), userID, req.Asset, req.Amount)
+	if err != nil {
 		utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Withdraw sukses!", nil, nil)
+	utils.SendSuccess(c, http.StatusOK, "Withdraw diterima dan sedang diproses", withdrawal, nil)
+}
+
+// GetWithdrawalsHandler - GET /api/v1/market/withdrawals
+func (h *GameHandler) GetWithdrawalsHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	withdrawals, err := h.withdrawUC.GetWithdrawals(c.Request.Context(), userID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Riwayat withdraw berhasil diambil", withdrawals, nil)
+}
+
+// GetWithdrawalHandler - GET /api/v1/market/withdrawals/:id
+func (h *GameHandler) GetWithdrawalHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	withdrawalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "ID withdraw tidak valid", nil)
+		return
+	}
+
+	withdrawal, err := h.withdrawUC.GetWithdrawal(c.Request.Context(), userID, withdrawalID)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Status withdraw berhasil diambil", withdrawal, nil)
 }
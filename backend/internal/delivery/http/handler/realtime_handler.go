@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"cashcowvalley/backend/internal/usecase"
+	"cashcowvalley/backend/pkg/realtime"
+	"cashcowvalley/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Komentar SSE berkala agar proxy/load balancer tidak menutup koneksi yang sedang idle
+const streamHeartbeatInterval = 25 * time.Second
+
+type RealtimeHandler struct {
+	realtimeUC *usecase.RealtimeUsecase
+}
+
+func NewRealtimeHandler(realtimeUC *usecase.RealtimeUsecase) *RealtimeHandler {
+	return &RealtimeHandler{realtimeUC: realtimeUC}
+}
+
+// StreamHandler membuka stream Server-Sent Events untuk user yang login. Event pertama adalah snapshot
+// saldo & inventory, selanjutnya listing.created/listing.sold (semua user) serta balance.updated,
+// inventory.updated dan harvest.ready milik user sendiri.
+func (h *RealtimeHandler) StreamHandler(c *gin.Context) {
+	userIDStr := c.GetString("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User tidak valid", nil)
+		return
+	}
+
+	// Subscribe sebelum snapshot agar perubahan di antara keduanya tidak terlewat
+	events, unsubscribe := realtime.Subscribe(userIDStr)
+	defer unsubscribe()
+
+	snapshot, err := h.realtimeUC.Snapshot(c.Request.Context(), userID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal memuat data awal stream", nil)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Matikan buffering Nginx
+	c.Status(http.StatusOK)
+
+	for _, evt := range snapshot {
+		c.SSEvent(evt.Type, evt)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case evt, ok := <-events:
+			if !ok {
+				return // Server sedang shutdown, klien akan reconnect otomatis
+			}
+			c.SSEvent(evt.Type, evt)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -1,20 +1,30 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"cashcowvalley/backend/internal/domain"
 	"cashcowvalley/backend/internal/usecase"
+	"cashcowvalley/backend/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type UserHandler struct {
-	userUC *usecase.UserUsecase
+	userUC        *usecase.UserUsecase
+	transactionUC *usecase.TransactionUsecase
+	statementUC   *usecase.StatementUsecase
 }
 
-func NewUserHandler(userUC *usecase.UserUsecase) *UserHandler {
-	return &UserHandler{userUC: userUC}
+func NewUserHandler(userUC *usecase.UserUsecase, transactionUC *usecase.TransactionUsecase, statementUC *usecase.StatementUsecase) *UserHandler {
+	return &UserHandler{userUC: userUC, transactionUC: transactionUC, statementUC: statementUC}
 }
 
 // BindReferrerHandler handles the request to link a user to an upline referrer.
@@ -68,3 +78,129 @@ func (h *UserHandler) GetReferralStatsHandler(c *gin.Context) {
 		"data":   stats,
 	})
 }
+
+// parseDateQuery menerima RFC3339 atau YYYY-MM-DD (UTC). Untuk batas akhir, tanggal tanpa jam
+// berarti sampai akhir hari tersebut.
+func parseDateQuery(raw string, endOfDay bool) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// GetTransactionsHandler returns the caller's TxLog history, newest first.
+// GET /api/v1/user/transactions?type=A,B&currency=&status=&from=&to=&cursor=&limit=
+func (h *UserHandler) GetTransactionsHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	filter := usecase.TransactionFilter{
+		Currency: strings.ToUpper(strings.TrimSpace(c.Query("currency"))),
+		Status:   domain.TxStatus(strings.ToUpper(strings.TrimSpace(c.Query("status")))),
+		Cursor:   c.Query("cursor"),
+	}
+	for _, t := range strings.Split(c.Query("type"), ",") {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+			filter.Types = append(filter.Types, t)
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			utils.SendError(c, http.StatusBadRequest, "Limit tidak valid", nil)
+			return
+		}
+		filter.Limit = limit
+	}
+	if filter.From, err = parseDateQuery(c.Query("from"), false); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format tanggal 'from' tidak valid (RFC3339 atau YYYY-MM-DD)", nil)
+		return
+	}
+	if filter.To, err = parseDateQuery(c.Query("to"), true); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Format tanggal 'to' tidak valid (RFC3339 atau YYYY-MM-DD)", nil)
+		return
+	}
+
+	page, err := h.transactionUC.ListTransactions(c.Request.Context(), userID, filter)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	meta := gin.H{"limit": page.Limit, "has_more": page.HasMore}
+	if page.NextCursor != "" {
+		meta["next_cursor"] = page.NextCursor
+	}
+	utils.SendSuccess(c, http.StatusOK, "Riwayat transaksi berhasil diambil", page, meta)
+}
+
+// sendStatement mengirim statement sebagai lampiran CSV (format=csv) atau JSON (default).
+func sendStatement(c *gin.Context, statement *usecase.Statement, format string) {
+	filename := fmt.Sprintf("statement-%s-%s", statement.WalletAddress, statement.Month)
+
+	if !strings.EqualFold(format, "csv") {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		utils.SendSuccess(c, http.StatusOK, "Statement berhasil dibuat", statement, nil)
+		return
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"date", "type", "currency", "amount", "balance", "status", "reference_id", "tx_id"})
+	for _, cur := range statement.Currencies {
+		w.Write([]string{statement.PeriodStart.Format(time.RFC3339), "OPENING_BALANCE", cur.Currency, "", cur.Opening.String(), "", "", ""})
+		for _, line := range cur.Lines {
+			ref := ""
+			if line.ReferenceID != nil {
+				ref = *line.ReferenceID
+			}
+			w.Write([]string{line.Date.UTC().Format(time.RFC3339), line.Type, line.Currency, line.Amount.String(),
+				line.Balance.String(), string(line.Status), ref, line.TxID.String()})
+		}
+		w.Write([]string{statement.PeriodEnd.Format(time.RFC3339), "CLOSING_BALANCE", cur.Currency, "", cur.Closing.String(), "", "", ""})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Gagal membuat CSV", nil)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// GetStatementHandler returns the caller's monthly statement.
+// GET /api/v1/user/statements?month=YYYY-MM&format=csv|json
+func (h *UserHandler) GetStatementHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "User ID tidak valid", nil)
+		return
+	}
+
+	month, err := usecase.ParseStatementMonth(c.Query("month"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	statement, err := h.statementUC.Monthly(c.Request.Context(), userID, month)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	sendStatement(c, statement, c.Query("format"))
+}
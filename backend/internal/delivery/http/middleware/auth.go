@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"cashcowvalley/backend/internal/usecase"
 	"cashcowvalley/backend/pkg/utils"
 
 	"github.com/gin-gonic/gin"
@@ -29,41 +30,96 @@ func RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-		jwtSecret := os.Getenv("JWT_SECRET")
-		if jwtSecret == "" {
-			jwtSecret = "super_secret_dev_key_123!" // Same fallback as in auth_uc.go
+		if !authenticate(c, parts[1], "") {
+			return
 		}
+		c.Next()
+	}
+}
 
-		token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
+// RequireStreamAuth sama dengan RequireAuth, tetapi juga menerima tiket stream lewat query `ticket`
+// karena EventSource di browser tidak bisa mengirim header Authorization. JWT sesi tidak pernah
+// diterima lewat query agar tidak tercatat di access log. Tiket hanya berlaku untuk satu koneksi,
+// jadi client harus meminta tiket baru (POST /stream/ticket) sebelum setiap reconnect.
+func RequireStreamAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				utils.SendError(c, http.StatusUnauthorized, "Akses Ditolak: Format token salah", nil)
+				c.Abort()
+				return
 			}
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			utils.SendError(c, http.StatusForbidden, "Sesi berakhir atau token tidak valid", nil)
-			c.Abort()
+			if !authenticate(c, parts[1], "") {
+				return
+			}
+			c.Next()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			utils.SendError(c, http.StatusForbidden, "Klaim token tidak valid", nil)
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			utils.SendError(c, http.StatusUnauthorized, "Akses Ditolak: Tiket stream tidak ditemukan", nil)
 			c.Abort()
 			return
 		}
+		if !authenticate(c, ticket, usecase.StreamTicketPurpose) {
+			return
+		}
+		c.Next()
+	}
+}
 
-		// BUG FIX (DL2): Konversi claims ke string secara eksplisit
-		// JWT claims bisa berupa float64/interface{} tergantung library.
-		// c.GetString() hanya bekerja jika tipe persis string.
-		c.Set("user_id", fmt.Sprintf("%v", claims["user_id"]))
-		c.Set("wallet_address", fmt.Sprintf("%v", claims["wallet_address"]))
-		c.Set("role", fmt.Sprintf("%v", claims["role"]))
+// authenticate memvalidasi JWT dan menyimpan klaimnya di context. Klaim `purpose` harus sama dengan
+// `purpose` (kosong untuk JWT sesi), sehingga tiket stream tidak bisa dipakai di endpoint lain.
+// Mengembalikan false (dan membatalkan request) jika token tidak valid.
+func authenticate(c *gin.Context, tokenString string, purpose string) bool {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		jwtSecret = "super_secret_dev_key_123!" // Same fallback as in auth_uc.go
+	}
 
-		c.Next()
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(jwtSecret), nil
+	})
+
+	if err != nil || !token.Valid {
+		utils.SendError(c, http.StatusForbidden, "Sesi berakhir atau token tidak valid", nil)
+		c.Abort()
+		return false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		utils.SendError(c, http.StatusForbidden, "Klaim token tidak valid", nil)
+		c.Abort()
+		return false
 	}
+	if tokenPurpose, _ := claims["purpose"].(string); tokenPurpose != purpose {
+		utils.SendError(c, http.StatusForbidden, "Token tidak berlaku untuk endpoint ini", nil)
+		c.Abort()
+		return false
+	}
+	if purpose == usecase.StreamTicketPurpose {
+		jti, _ := claims["jti"].(string)
+		if !usecase.ConsumeStreamTicket(c.Request.Context(), jti) {
+			utils.SendError(c, http.StatusForbidden, "Tiket stream sudah dipakai, minta tiket baru", nil)
+			c.Abort()
+			return false
+		}
+	}
+
+	// BUG FIX (DL2): Konversi claims ke string secara eksplisit
+	// JWT claims bisa berupa float64/interface{} tergantung library.
+	// c.GetString() hanya bekerja jika tipe persis string.
+	c.Set("user_id", fmt.Sprintf("%v", claims["user_id"]))
+	c.Set("wallet_address", fmt.Sprintf("%v", claims["wallet_address"]))
+	c.Set("role", fmt.Sprintf("%v", claims["role"]))
+
+	return true
 }
 
 // RequireRole enforces RBAC
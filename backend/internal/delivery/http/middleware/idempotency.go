@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"cashcowvalley/backend/internal/usecase"
+	"cashcowvalley/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	IdempotencyHeader       = "Idempotency-Key"
+	IdempotentReplayHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyRecorder menyalin body response agar bisa disimpan setelah handler selesai.
+type idempotencyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency honors the Idempotency-Key header on POST requests. Must run after RequireAuth.
+// Duplicates of a finished request get the stored response replayed; reusing a key with a
+// different body is rejected. 5xx responses are not stored so the client can retry.
+func Idempotency(idempotencyUC *usecase.IdempotencyUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyHeader))
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			utils.SendError(c, http.StatusBadRequest, "Idempotency-Key terlalu panjang (maksimal 255 karakter)", nil)
+			c.Abort()
+			return
+		}
+
+		userID, err := uuid.Parse(c.GetString("user_id"))
+		if err != nil {
+			utils.SendError(c, http.StatusUnauthorized, "User tidak valid", nil)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			utils.SendError(c, http.StatusRequestEntityTooLarge, "Request terlalu besar", nil)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		path := c.Request.URL.RequestURI()
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + "\n" + path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		record, err := idempotencyUC.Begin(c.Request.Context(), userID, key, c.Request.Method, path, requestHash)
+		switch {
+		case errors.Is(err, usecase.ErrIdempotencyMismatch):
+			utils.SendError(c, http.StatusUnprocessableEntity, err.Error(), nil)
+			c.Abort()
+			return
+		case errors.Is(err, usecase.ErrIdempotencyInProgress):
+			utils.SendError(c, http.StatusConflict, err.Error(), nil)
+			c.Abort()
+			return
+		case err != nil:
+			utils.SendError(c, http.StatusInternalServerError, "Gagal memproses Idempotency-Key", nil)
+			c.Abort()
+			return
+		}
+
+		if record != nil {
+			c.Header(IdempotentReplayHeader, "true")
+			c.Data(record.StatusCode, record.ContentType, record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		// Hasil tetap disimpan walau klien sudah memutus koneksi, agar retry-nya mendapat replay
+		saveCtx := context.WithoutCancel(c.Request.Context())
+		recorder := &idempotencyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		defer func() {
+			if r := recover(); r != nil {
+				idempotencyUC.Release(saveCtx, userID, key)
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			err = idempotencyUC.Release(saveCtx, userID, key)
+		} else {
+			err = idempotencyUC.Complete(saveCtx, userID, key, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("[IDEMPOTENCY] Gagal menyimpan hasil key %s: %v", key, err)
+		}
+	}
+}
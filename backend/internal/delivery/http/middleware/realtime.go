@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"cashcowvalley/backend/internal/usecase"
+	"cashcowvalley/backend/pkg/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RealtimeEvents memasang Collector per request. Usecase/ledger mengisi Collector di dalam transaksi,
+// dan isinya baru dipublikasikan ke stream setelah handler selesai (transaksi sudah commit).
+// Response gagal (4xx/5xx) tidak mengirim event apa pun.
+func RealtimeEvents(realtimeUC *usecase.RealtimeUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, col := realtime.WithCollector(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Set(realtime.ContextKey, col) // Handler meneruskan `c` langsung sebagai context ke usecase
+
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusBadRequest {
+			col.Discard()
+			return
+		}
+		realtimeUC.Flush(context.WithoutCancel(c.Request.Context()), col)
+	}
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// AMMPool adalah pool constant-product (x*y=k) internal antara GOLD dan satu token (COW / USDT).
+// Reserve juga dibukukan di ledger pada akun TREASURY dengan bucket LedgerBucket().
+type AMMPool struct {
+	ID            uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	BaseCurrency  string          `gorm:"type:varchar(10);uniqueIndex:idx_amm_pair;not null" json:"base_currency"`  // GOLD
+	QuoteCurrency string          `gorm:"type:varchar(10);uniqueIndex:idx_amm_pair;not null" json:"quote_currency"` // COW | USDT
+	BaseReserve   decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"base_reserve"`
+	QuoteReserve  decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"quote_reserve"`
+	FeeBps        int             `gorm:"not null;default:30" json:"fee_bps"` // Fee dalam basis point dari amount in, tetap di pool
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+func (p *AMMPool) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// LedgerBucket adalah bucket akun treasury yang menampung reserve pool, mis. "AMM_GOLD_COW".
+func (p *AMMPool) LedgerBucket() string {
+	return "AMM_" + p.BaseCurrency + "_" + p.QuoteCurrency
+}
+
+// AMMSwap mencatat satu swap beserta reserve setelahnya untuk audit harga.
+type AMMSwap struct {
+	ID                uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	PoolID            uuid.UUID       `gorm:"type:text;index;not null" json:"pool_id"`
+	UserID            uuid.UUID       `gorm:"type:text;index;not null" json:"user_id"`
+	InCurrency        string          `gorm:"type:varchar(10);not null" json:"in_currency"`
+	AmountIn          decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"amount_in"`
+	OutCurrency       string          `gorm:"type:varchar(10);not null" json:"out_currency"`
+	AmountOut         decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"amount_out"`
+	Fee               decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"fee"`
+	BaseReserveAfter  decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"base_reserve_after"`
+	QuoteReserveAfter decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"quote_reserve_after"`
+	EntryID           uuid.UUID       `gorm:"type:text" json:"entry_id"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+func (s *AMMSwap) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
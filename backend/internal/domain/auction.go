@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Jenis lelang
+const (
+	AuctionEnglish = "ENGLISH" // Harga naik, penawar tertinggi saat EndsAt menang
+	AuctionDutch   = "DUTCH"   // Harga turun dari StartPrice ke FloorPrice, pembeli pertama langsung menang
+)
+
+// Status Auction
+const (
+	AuctionOpen      = "OPEN"
+	AuctionSettled   = "SETTLED"   // Terjual, item/sapi sudah diserahkan ke pemenang
+	AuctionUnsold    = "UNSOLD"    // Berakhir tanpa penawar, item dikembalikan ke penjual
+	AuctionCancelled = "CANCELLED" // Ditarik penjual sebelum ada penawaran
+)
+
+// Status AuctionBid
+const (
+	AuctionBidActive   = "ACTIVE" // Penawaran tertinggi saat ini, USDT ditahan di escrow MARKET
+	AuctionBidOutbid   = "OUTBID" // Sudah dilampaui, USDT dikembalikan
+	AuctionBidWon      = "WON"
+	AuctionBidRefunded = "REFUNDED" // Lot tidak bisa diserahkan (sapi mati sebelum settlement), USDT dikembalikan
+)
+
+// Auction adalah lelang GRASS/MILK (satu lot berisi Quantity item) atau satu ekor sapi (CATTLE).
+// Item/sapi ditahan di escrow sejak lelang dibuat; USDT penawar tertinggi ditahan di escrow MARKET.
+type Auction struct {
+	ID               uuid.UUID        `gorm:"type:text;primaryKey" json:"id"`
+	SellerID         uuid.UUID        `gorm:"type:text;index;not null" json:"seller_id"`
+	Kind             string           `gorm:"type:varchar(10);not null" json:"kind"`
+	ItemType         string           `gorm:"type:varchar(50);not null" json:"item_type"`
+	Quantity         int              `gorm:"not null" json:"quantity"`
+	CowID            *uuid.UUID       `gorm:"type:text;index" json:"cow_id,omitempty"`
+	StartPriceUSDT   decimal.Decimal  `gorm:"type:numeric(24,8);not null" json:"start_price_usdt"`    // Harga total lot
+	FloorPriceUSDT   decimal.Decimal  `gorm:"type:numeric(24,8);default:0" json:"floor_price_usdt"`   // Dutch: harga terendah
+	MinIncrementUSDT decimal.Decimal  `gorm:"type:numeric(24,8);default:0" json:"min_increment_usdt"` // English: kenaikan minimum
+	HighestBidUSDT   decimal.Decimal  `gorm:"type:numeric(24,8);default:0" json:"highest_bid_usdt"`   // English: penawaran tertinggi
+	HighestBidderID  *uuid.UUID       `gorm:"type:text" json:"highest_bidder_id,omitempty"`           // English
+	SettledPriceUSDT *decimal.Decimal `gorm:"type:numeric(24,8)" json:"settled_price_usdt,omitempty"` // Harga akhir jika terjual
+	BuyerFeeUSDT     decimal.Decimal  `gorm:"type:numeric(24,8);default:0" json:"buyer_fee_usdt"`     // Fee taker pemenang
+	SellerFeeUSDT    decimal.Decimal  `gorm:"type:numeric(24,8);default:0" json:"seller_fee_usdt"`    // Fee maker penjual
+	WinnerID         *uuid.UUID       `gorm:"type:text;index" json:"winner_id,omitempty"`
+	Status           string           `gorm:"type:varchar(20);index:idx_auction_due,priority:1;default:'OPEN'" json:"status"`
+	StartsAt         time.Time        `gorm:"not null" json:"starts_at"`
+	EndsAt           time.Time        `gorm:"index:idx_auction_due,priority:2;not null" json:"ends_at"`
+	CurrentPriceUSDT decimal.Decimal  `gorm:"-" json:"current_price_usdt"` // Dihitung saat ditampilkan
+	Cow              *CowSnapshot     `gorm:"-" json:"cow,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+}
+
+func (a *Auction) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsLive bernilai true selama lelang masih menerima penawaran pada `now`.
+func (a *Auction) IsLive(now time.Time) bool {
+	return a.Status == AuctionOpen && now.Before(a.EndsAt)
+}
+
+// DutchPrice menghitung harga lelang Dutch pada `now`: turun linear dari StartPrice ke FloorPrice
+// sepanjang StartsAt..EndsAt.
+func (a *Auction) DutchPrice(now time.Time) decimal.Decimal {
+	if !now.After(a.StartsAt) {
+		return a.StartPriceUSDT
+	}
+	if !now.Before(a.EndsAt) {
+		return a.FloorPriceUSDT
+	}
+	elapsed := decimal.NewFromInt(int64(now.Sub(a.StartsAt)))
+	duration := decimal.NewFromInt(int64(a.EndsAt.Sub(a.StartsAt)))
+	drop := a.StartPriceUSDT.Sub(a.FloorPriceUSDT).Mul(elapsed).Div(duration)
+	return a.StartPriceUSDT.Sub(drop).Truncate(8)
+}
+
+// MinNextBid adalah penawaran minimum yang diterima lelang English berikutnya.
+func (a *Auction) MinNextBid() decimal.Decimal {
+	if a.HighestBidderID == nil {
+		return a.StartPriceUSDT
+	}
+	return a.HighestBidUSDT.Add(a.MinIncrementUSDT)
+}
+
+// AuctionBid adalah riwayat penawaran lelang English.
+type AuctionBid struct {
+	ID         uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	AuctionID  uuid.UUID       `gorm:"type:text;index;not null" json:"auction_id"`
+	BidderID   uuid.UUID       `gorm:"type:text;index;not null" json:"bidder_id"`
+	AmountUSDT decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"amount_usdt"`
+	FeeRate    decimal.Decimal `gorm:"type:numeric(10,6);default:0" json:"fee_rate"` // Cadangan fee taker yang ikut ditahan di escrow
+	Status     string          `gorm:"type:varchar(20);default:'ACTIVE'" json:"status"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func (b *AuctionBid) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// PlatformItem adalah satu produk di toko platform. Harga selalu diambil dari tabel ini,
+// bukan dari request client.
+type PlatformItem struct {
+	ID           uuid.UUID           `gorm:"type:text;primaryKey" json:"id"`
+	SKU          string              `gorm:"type:varchar(50);uniqueIndex;not null" json:"sku"`
+	Name         string              `gorm:"type:varchar(100);not null" json:"name"`
+	ItemType     string              `gorm:"type:varchar(20);not null" json:"item_type"` // COW | GRASS
+	UnitQuantity int                 `gorm:"not null;default:1" json:"unit_quantity"`    // Jumlah item yang dikirim per 1 pembelian
+	Stock        *int                `json:"stock"`                                      // nil = tanpa batas
+	Sold         int                 `gorm:"not null;default:0" json:"sold"`
+	PerUserLimit *int                `json:"per_user_limit"` // Maksimal pembelian per user sepanjang waktu, nil = tanpa batas
+	StartsAt     *time.Time          `json:"starts_at"`      // Awal sale window, nil = langsung tersedia
+	EndsAt       *time.Time          `json:"ends_at"`        // Akhir sale window, nil = tidak berakhir
+	IsActive     bool                `gorm:"not null;default:true" json:"is_active"`
+	Prices       []PlatformItemPrice `gorm:"foreignKey:ItemID" json:"prices"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+func (p *PlatformItem) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// OnSale mengecek sale window dan status aktif pada waktu `now`.
+func (p *PlatformItem) OnSale(now time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// PlatformItemPrice adalah harga satu item dalam satu currency pembayaran (USDT / COW).
+type PlatformItemPrice struct {
+	ID       uuid.UUID       `gorm:"type:text;primaryKey" json:"-"`
+	ItemID   uuid.UUID       `gorm:"type:text;uniqueIndex:idx_platform_item_currency;not null" json:"-"`
+	Currency string          `gorm:"type:varchar(10);uniqueIndex:idx_platform_item_currency;not null" json:"currency"`
+	Price    decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"price"`
+}
+
+func (p *PlatformItemPrice) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// PlatformPurchase mencatat setiap pembelian untuk batas per user dan audit harga.
+type PlatformPurchase struct {
+	ID        uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	UserID    uuid.UUID       `gorm:"type:text;index:idx_platform_purchase_user_item;not null" json:"user_id"`
+	ItemID    uuid.UUID       `gorm:"type:text;index:idx_platform_purchase_user_item;not null" json:"item_id"`
+	Quantity  int             `gorm:"not null" json:"quantity"`
+	Currency  string          `gorm:"type:varchar(10);not null" json:"currency"`
+	UnitPrice decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"unit_price"`
+	Total     decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"total"`
+	EntryID   uuid.UUID       `gorm:"type:text" json:"entry_id"` // JournalEntry PLATFORM_BUY
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (p *PlatformPurchase) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ChainCursor menyimpan blok terakhir yang sudah diproses oleh sebuah indexer on-chain.
+type ChainCursor struct {
+	Name      string `gorm:"type:varchar(50);primaryKey"`
+	LastBlock uint64 `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+// ChainDeposit adalah satu ERC-20 Transfer ke alamat deposit platform yang sudah terkonfirmasi.
+// UserID nil berarti pengirim tidak cocok dengan wallet terdaftar (menunggu penanganan admin).
+type ChainDeposit struct {
+	ID          uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	UserID      *uuid.UUID      `gorm:"type:text;index" json:"user_id,omitempty"`
+	TxHash      string          `gorm:"type:varchar(66);uniqueIndex:idx_chain_deposit_log;not null" json:"tx_hash"`
+	LogIndex    uint            `gorm:"uniqueIndex:idx_chain_deposit_log;not null" json:"log_index"`
+	BlockNumber uint64          `gorm:"index;not null" json:"block_number"`
+	Token       string          `gorm:"type:varchar(42);not null" json:"token"`
+	Currency    string          `gorm:"type:varchar(10);not null" json:"currency"`
+	FromAddress string          `gorm:"type:varchar(42);index;not null" json:"from_address"`
+	ToAddress   string          `gorm:"type:varchar(42);not null" json:"to_address"`
+	Amount      decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"amount"`
+	Status      TxStatus        `gorm:"type:varchar(20);default:'PENDING'" json:"status"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func (d *ChainDeposit) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
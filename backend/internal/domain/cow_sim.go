@@ -0,0 +1,136 @@
+package domain
+
+import "time"
+
+// HungerState adalah status lapar sapi, dihitung dari waktu sejak terakhir diberi makan.
+type HungerState string
+
+const (
+	HungerFed      HungerState = "FED"      // Produksi penuh, happiness tidak turun
+	HungerHungry   HungerState = "HUNGRY"   // Produksi 50%, happiness turun 1 per jam
+	HungerStarving HungerState = "STARVING" // Tidak berproduksi, happiness turun 3 per jam
+)
+
+// Model simulasi sapi. Semua perhitungan deterministik dan hanya bergantung pada waktu, sehingga
+// nilai yang dibaca (GetFarmStatus) selalu sama dengan nilai yang akan disimpan saat ditulis.
+const (
+	MaxHappiness = 100
+
+	CowHungryAfter   = 12 * time.Hour // Sejak terakhir diberi makan (atau sejak lahir)
+	CowStarvingAfter = 24 * time.Hour
+
+	hungryDecayPerHour   = 1
+	starvingDecayPerHour = 3
+
+	hungryYieldPercent = 50 // Persentase produksi per jam saat HUNGRY (STARVING = 0)
+)
+
+// fedAt adalah acuan status lapar: waktu terakhir diberi makan, atau waktu lahir jika belum pernah.
+func (c *Cow) fedAt() time.Time {
+	if c.LastFedAt != nil {
+		return *c.LastFedAt
+	}
+	return c.CreatedAt
+}
+
+// harvestAnchor adalah awal periode produksi yang belum dipanen.
+func (c *Cow) harvestAnchor() time.Time {
+	if c.LastHarvestedAt != nil {
+		return *c.LastHarvestedAt
+	}
+	return c.CreatedAt
+}
+
+// HungerAt mengembalikan status lapar sapi pada `now`.
+func (c *Cow) HungerAt(now time.Time) HungerState {
+	switch sinceFed := now.Sub(c.fedAt()); {
+	case sinceFed >= CowStarvingAfter:
+		return HungerStarving
+	case sinceFed >= CowHungryAfter:
+		return HungerHungry
+	default:
+		return HungerFed
+	}
+}
+
+// hungerDecay adalah total penurunan happiness sejak diberi makan hingga `sinceFed`, dihitung per jam
+// penuh. Karena bernilai bulat, selisih dua titik waktu selalu sama berapa kali pun nilainya di-settle.
+func hungerDecay(sinceFed time.Duration) int {
+	hours := int(sinceFed / time.Hour)
+	hungry := min(max(hours-int(CowHungryAfter/time.Hour), 0), int((CowStarvingAfter-CowHungryAfter)/time.Hour))
+	starving := max(hours-int(CowStarvingAfter/time.Hour), 0)
+	return hungry*hungryDecayPerHour + starving*starvingDecayPerHour
+}
+
+// CurrentHappiness menghitung happiness pada `now`: nilai tersimpan dikurangi decay lapar sejak
+// HappinessSettledAt (atau sejak terakhir diberi makan jika lebih baru).
+func (c *Cow) CurrentHappiness(now time.Time) int {
+	fed := c.fedAt()
+	from := fed
+	if c.HappinessSettledAt != nil && c.HappinessSettledAt.After(fed) {
+		from = *c.HappinessSettledAt
+	}
+	if !now.After(from) {
+		return c.Happiness
+	}
+	decay := hungerDecay(now.Sub(fed)) - hungerDecay(from.Sub(fed))
+	return max(c.Happiness-decay, 0)
+}
+
+// SettleHappiness menyimpan decay sampai `now` ke Happiness. Wajib dipanggil sebelum Happiness diubah.
+func (c *Cow) SettleHappiness(now time.Time) {
+	c.Happiness = c.CurrentHappiness(now)
+	c.HappinessSettledAt = &now
+}
+
+// AddHappiness menambah happiness (setelah settle) dengan batas MaxHappiness.
+func (c *Cow) AddHappiness(now time.Time, delta int) {
+	c.SettleHappiness(now)
+	c.Happiness = min(max(c.Happiness+delta, 0), MaxHappiness)
+}
+
+// productiveMinutes adalah menit produksi penuh sejak diberi makan hingga `sinceFed`
+// (FED 100%, HUNGRY 50%, STARVING 0%).
+func productiveMinutes(sinceFed time.Duration) int64 {
+	minutes := int64(sinceFed / time.Minute)
+	hungryStart := int64(CowHungryAfter / time.Minute)
+	starvingStart := int64(CowStarvingAfter / time.Minute)
+	fed := min(minutes, hungryStart)
+	hungry := min(max(minutes-hungryStart, 0), starvingStart-hungryStart)
+	return fed + hungry*hungryYieldPercent/100
+}
+
+// lostMinutes adalah menit produksi yang hilang karena lapar pada periode yang belum dipanen.
+func (c *Cow) lostMinutes(now time.Time) int64 {
+	fed, from := c.fedAt(), c.harvestAnchor()
+	if from.Before(fed) {
+		from = fed // Bagian sebelum makan terakhir sudah diperhitungkan saat Feed
+	}
+	if !now.After(from) {
+		return 0
+	}
+	elapsed := int64(now.Sub(fed)/time.Minute) - int64(from.Sub(fed)/time.Minute)
+	return elapsed - (productiveMinutes(now.Sub(fed)) - productiveMinutes(from.Sub(fed)))
+}
+
+// ProductiveHours adalah jam produksi penuh sejak panen terakhir hingga `now`, setelah potongan lapar.
+func (c *Cow) ProductiveHours(now time.Time) int {
+	from := c.harvestAnchor()
+	if !now.After(from) {
+		return 0
+	}
+	minutes := int64(now.Sub(from)/time.Minute) - c.lostMinutes(now)
+	return int(max(minutes, 0) / 60)
+}
+
+// Feed memberi makan sapi pada `now`. Produksi yang hilang karena lapar sejak panen terakhir dikunci
+// dengan memajukan LastHarvestedAt, sehingga memberi makan tepat sebelum panen tidak menghapus potongannya.
+func (c *Cow) Feed(now time.Time, happiness int) {
+	c.SettleHappiness(now)
+	if lost := c.lostMinutes(now); lost > 0 {
+		anchor := c.harvestAnchor().Add(time.Duration(lost) * time.Minute)
+		c.LastHarvestedAt = &anchor
+	}
+	c.LastFedAt = &now
+	c.Happiness = min(c.Happiness+happiness, MaxHappiness)
+}
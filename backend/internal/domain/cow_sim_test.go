@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHungerDecay(t *testing.T) {
+	cases := []struct {
+		sinceFed time.Duration
+		want     int
+	}{
+		{0, 0},
+		{11*time.Hour + 59*time.Minute, 0},
+		{12 * time.Hour, 0},
+		{13*time.Hour + 59*time.Minute, 1}, // Hanya jam penuh yang dihitung
+		{24 * time.Hour, 12},
+		{25 * time.Hour, 15},
+		{30 * time.Hour, 30},
+	}
+	for _, tc := range cases {
+		if got := hungerDecay(tc.sinceFed); got != tc.want {
+			t.Errorf("hungerDecay(%s) = %d, harus %d", tc.sinceFed, got, tc.want)
+		}
+	}
+}
+
+func TestProductiveMinutes(t *testing.T) {
+	cases := []struct {
+		sinceFed time.Duration
+		want     int64
+	}{
+		{0, 0},
+		{6 * time.Hour, 360},
+		{12 * time.Hour, 720},
+		{18 * time.Hour, 900}, // 6 jam HUNGRY = 3 jam produksi penuh
+		{24 * time.Hour, 1080},
+		{48 * time.Hour, 1080}, // STARVING tidak berproduksi
+	}
+	for _, tc := range cases {
+		if got := productiveMinutes(tc.sinceFed); got != tc.want {
+			t.Errorf("productiveMinutes(%s) = %d, harus %d", tc.sinceFed, got, tc.want)
+		}
+	}
+}
+
+func TestFeedShiftsHarvestAnchor(t *testing.T) {
+	born := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name          string
+		lastHarvested time.Duration // Relatif terhadap waktu lahir; 0 = belum pernah dipanen
+		feedAt        time.Duration
+		wantAnchor    time.Duration // 0 = LastHarvestedAt tidak berubah
+		wantHappiness int
+	}{
+		{"masih kenyang", 0, 6 * time.Hour, 0, 70},
+		{"hungry", 0, 18 * time.Hour, 3 * time.Hour, 64},
+		{"starving", 0, 30 * time.Hour, 12 * time.Hour, 40},
+		{"panen saat hungry", 20 * time.Hour, 30 * time.Hour, 28 * time.Hour, 40},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cow := Cow{Happiness: 50, CreatedAt: born}
+			if tc.lastHarvested > 0 {
+				harvested := born.Add(tc.lastHarvested)
+				cow.LastHarvestedAt = &harvested
+			}
+			now := born.Add(tc.feedAt)
+			before := cow.ProductiveHours(now)
+
+			cow.Feed(now, 20)
+
+			var anchor time.Duration
+			if cow.LastHarvestedAt != nil {
+				anchor = cow.LastHarvestedAt.Sub(born)
+			}
+			if tc.wantAnchor == 0 {
+				tc.wantAnchor = tc.lastHarvested
+			}
+			if anchor != tc.wantAnchor {
+				t.Fatalf("LastHarvestedAt = lahir+%s, harus lahir+%s", anchor, tc.wantAnchor)
+			}
+			// Memberi makan tepat sebelum panen tidak boleh menghapus potongan lapar
+			if after := cow.ProductiveHours(now); after != before {
+				t.Fatalf("ProductiveHours berubah setelah Feed: %d -> %d", before, after)
+			}
+			if cow.HungerAt(now) != HungerFed || !cow.LastFedAt.Equal(now) {
+				t.Fatalf("sapi harus FED sejak %s, dapat %s sejak %v", now, cow.HungerAt(now), cow.LastFedAt)
+			}
+			if cow.Happiness != tc.wantHappiness {
+				t.Fatalf("Happiness = %d, harus %d", cow.Happiness, tc.wantHappiness)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// EconomyParams adalah satu set parameter ekonomi (kurs, harga, reward, pembagian hasil).
+// Kurs Gold -> COW/USDT tidak ada di sini karena ditentukan oleh pool AMM (lihat AMMPool).
+type EconomyParams struct {
+	MilkToGold          decimal.Decimal            `json:"milk_to_gold"`            // Gold yang diterima per 1 Milk
+	InAppGoldPrices     map[string]decimal.Decimal `json:"inapp_gold_prices"`       // Harga item in-app dalam Gold
+	StakeGoldMilkHourly decimal.Decimal            `json:"stake_gold_milk_hourly"`  // Milk per 1 Gold di-stake per jam
+	StakeMilkGoldHourly decimal.Decimal            `json:"stake_milk_gold_hourly"`  // Gold per 1 Milk di-stake per jam
+	PlatformLPShare     decimal.Decimal            `json:"platform_lp_share"`       // Porsi LP buyback dari penjualan platform
+	PlatformRefShare    decimal.Decimal            `json:"platform_referral_share"` // Porsi upline (roll-up ke dev jika tidak ada)
+	PlatformDevShare    decimal.Decimal            `json:"platform_dev_share"`      // Porsi treasury dev
+
+	// Fee marketplace P2P per sisi, dari nilai trade (0.002 = 0.2%). 0 = tanpa fee.
+	// Maker adalah order yang sudah resting di buku, taker yang mengeksekusinya.
+	// Fee dibagi ke treasury LP buyback / referral pool / dev dengan porsi Platform*Share.
+	MarketMakerFee decimal.Decimal `json:"market_maker_fee"`
+	MarketTakerFee decimal.Decimal `json:"market_taker_fee"`
+
+	// Emisi COW ke pemain (alokasi Farming Rewards 40%)
+	COWDailyEmission    decimal.Decimal `json:"cow_daily_emission"`    // Budget harian era pertama
+	COWLifetimeEmission decimal.Decimal `json:"cow_lifetime_emission"` // Total emisi maksimal sepanjang waktu
+	COWHalvingDays      int             `json:"cow_halving_days"`      // Budget harian dibagi dua setiap N hari sejak genesis
+	COWEmissionGenesis  time.Time       `json:"cow_emission_genesis"`  // Hari pertama era 0 (UTC)
+}
+
+// DefaultEconomyParams adalah nilai yang sebelumnya di-hardcode di market_uc.go (versi 1).
+func DefaultEconomyParams() EconomyParams {
+	return EconomyParams{
+		MilkToGold: decimal.NewFromInt(5),
+		InAppGoldPrices: map[string]decimal.Decimal{
+			"GRASS":    decimal.NewFromInt(10),
+			"BABY_COW": decimal.NewFromInt(500),
+			"COW":      decimal.NewFromInt(2000),
+			"LAND":     decimal.NewFromInt(1000),
+			"VITAMIN":  decimal.NewFromInt(50),
+		},
+		StakeGoldMilkHourly: decimal.RequireFromString("0.0001"),
+		StakeMilkGoldHourly: decimal.RequireFromString("0.1"),
+		PlatformLPShare:     decimal.RequireFromString("0.70"),
+		PlatformRefShare:    decimal.RequireFromString("0.20"),
+		PlatformDevShare:    decimal.RequireFromString("0.10"),
+		// 40% dari 1.000.000 COW; 548/hari dengan halving tahunan konvergen ke ~400.000 (548 x 365 x 2)
+		COWDailyEmission:    decimal.NewFromInt(548),
+		COWLifetimeEmission: decimal.NewFromInt(400000),
+		COWHalvingDays:      365,
+		COWEmissionGenesis:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// FillDefaults mengisi field yang kosong (versi lama yang disimpan sebelum field tersebut ada)
+// dengan nilai DefaultEconomyParams.
+func (p *EconomyParams) FillDefaults() {
+	def := DefaultEconomyParams()
+	if p.MilkToGold.IsZero() {
+		p.MilkToGold = def.MilkToGold
+	}
+	if p.InAppGoldPrices == nil {
+		p.InAppGoldPrices = def.InAppGoldPrices
+	}
+	if p.StakeGoldMilkHourly.IsZero() {
+		p.StakeGoldMilkHourly = def.StakeGoldMilkHourly
+	}
+	if p.StakeMilkGoldHourly.IsZero() {
+		p.StakeMilkGoldHourly = def.StakeMilkGoldHourly
+	}
+	if p.PlatformLPShare.IsZero() && p.PlatformRefShare.IsZero() && p.PlatformDevShare.IsZero() {
+		p.PlatformLPShare, p.PlatformRefShare, p.PlatformDevShare = def.PlatformLPShare, def.PlatformRefShare, def.PlatformDevShare
+	}
+	if p.COWDailyEmission.IsZero() {
+		p.COWDailyEmission = def.COWDailyEmission
+	}
+	if p.COWLifetimeEmission.IsZero() {
+		p.COWLifetimeEmission = def.COWLifetimeEmission
+	}
+	if p.COWHalvingDays == 0 {
+		p.COWHalvingDays = def.COWHalvingDays
+	}
+	if p.COWEmissionGenesis.IsZero() {
+		p.COWEmissionGenesis = def.COWEmissionGenesis
+	}
+}
+
+// EconomyConfig adalah satu versi EconomyParams yang berlaku mulai EffectiveAt.
+// Versi yang aktif adalah versi dengan EffectiveAt terbaru yang sudah lewat.
+type EconomyConfig struct {
+	ID          uuid.UUID     `gorm:"type:text;primaryKey" json:"id"`
+	Version     int           `gorm:"uniqueIndex;not null" json:"version"`
+	Params      EconomyParams `gorm:"type:text;serializer:json;not null" json:"params"`
+	EffectiveAt time.Time     `gorm:"index;not null" json:"effective_at"`
+	Note        string        `gorm:"type:varchar(255)" json:"note,omitempty"`
+	CreatedBy   *uuid.UUID    `gorm:"type:text" json:"created_by,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+func (e *EconomyConfig) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
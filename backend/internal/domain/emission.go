@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// EmissionEpoch adalah budget emisi COW untuk satu hari UTC.
+// Budget dibekukan saat baris dibuat sehingga perubahan EconomyConfig berlaku mulai epoch berikutnya.
+type EmissionEpoch struct {
+	Day       string          `gorm:"type:varchar(10);primaryKey" json:"day"` // YYYY-MM-DD (UTC)
+	Era       int             `gorm:"not null" json:"era"`                    // Jumlah halving yang sudah terjadi
+	Budget    decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"budget"`
+	Emitted   decimal.Decimal `gorm:"type:numeric(24,8);not null;default:0" json:"emitted"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IdempotencyState string
+
+const (
+	IdempotencyInProgress IdempotencyState = "IN_PROGRESS" // Request pertama masih diproses handler
+	IdempotencyCompleted  IdempotencyState = "COMPLETED"   // Response tersimpan dan di-replay untuk duplikat
+)
+
+// IdempotencyKey menyimpan hasil request POST yang dikirim dengan header Idempotency-Key.
+// Key berlaku per user sehingga dua user boleh memakai key yang sama.
+type IdempotencyKey struct {
+	UserID       uuid.UUID        `gorm:"type:text;primaryKey" json:"user_id"`
+	Key          string           `gorm:"column:idempotency_key;type:varchar(255);primaryKey" json:"key"`
+	Method       string           `gorm:"type:varchar(10);not null" json:"method"`
+	Path         string           `gorm:"type:varchar(255);not null" json:"path"`
+	RequestHash  string           `gorm:"type:varchar(64);not null" json:"request_hash"` // sha256(method, path, body)
+	State        IdempotencyState `gorm:"type:varchar(20);not null" json:"state"`
+	StatusCode   int              `json:"status_code"`
+	ContentType  string           `gorm:"type:varchar(100)" json:"-"`
+	ResponseBody []byte           `json:"-"`
+	ExpiresAt    time.Time        `gorm:"index;not null" json:"expires_at"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Currency codes yang dibukukan di ledger.
+// COW adalah token on-chain yang di-project ke kolom User.Points.
+const (
+	CurrencyGold  = "GOLD"
+	CurrencyUSDT  = "USDT"
+	CurrencyCOW   = "COW"
+	CurrencyGrass = "GRASS"
+	CurrencyMilk  = "MILK"
+)
+
+type AccountKind string
+
+const (
+	AccountUser     AccountKind = "USER"     // Saldo milik pemain (di-project ke User / Inventory)
+	AccountTreasury AccountKind = "TREASURY" // Kas platform (LP buyback, dev, referral pool, pool AMM)
+	AccountEscrow   AccountKind = "ESCROW"   // Dana/item yang ditahan sementara (listing, staking)
+	AccountSystem   AccountKind = "SYSTEM"   // Sumber/muara ekonomi (mint, burn, on-chain), boleh negatif
+)
+
+// Bucket standar untuk akun non-user.
+const (
+	BucketMint         = "MINT"          // Emisi in-game (panen, iklan, reward)
+	BucketBurn         = "BURN"          // Item/currency yang dikonsumsi game
+	BucketExternal     = "EXTERNAL"      // Batas on-chain (deposit & withdraw)
+	BucketAdmin        = "ADMIN"         // Mint manual oleh admin
+	BucketOpening      = "OPENING"       // Saldo awal sebelum ledger aktif
+	BucketMarket       = "MARKET"        // Escrow P2P: item listing (ask) & USDT bid
+	BucketStaking      = "STAKING"       // Escrow staking Web2
+	BucketWithdrawal   = "WITHDRAWAL"    // Escrow withdrawal yang sedang diproses on-chain
+	BucketLPBuyback    = "LP_BUYBACK"    // Treasury 70% penjualan platform
+	BucketDev          = "DEV"           // Treasury 10% (+ roll-up referral)
+	BucketReferralPool = "REFERRAL_POOL" // Treasury porsi referral dari fee marketplace P2P
+)
+
+// TreasuryBuckets adalah treasury platform beserta kegunaannya (pool AMM punya bucket treasury sendiri).
+var TreasuryBuckets = map[string]string{
+	BucketLPBuyback:    "Likuiditas & buyback token COW",
+	BucketDev:          "Operasional & pengembangan",
+	BucketReferralPool: "Dana program referral",
+}
+
+// LedgerAccount adalah satu akun double-entry untuk satu pemilik dan satu currency.
+// Balance adalah cache dari SUM(postings.amount) dan selalu diperbarui dalam transaksi yang sama.
+type LedgerAccount struct {
+	ID        uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	Code      string          `gorm:"type:varchar(120);uniqueIndex;not null" json:"code"`
+	Kind      AccountKind     `gorm:"type:varchar(20);index;not null" json:"kind"`
+	OwnerID   *uuid.UUID      `gorm:"type:text;index" json:"owner_id,omitempty"`
+	Bucket    string          `gorm:"type:varchar(50)" json:"bucket,omitempty"`
+	Currency  string          `gorm:"type:varchar(10);not null" json:"currency"`
+	Balance   decimal.Decimal `gorm:"type:numeric(24,8);default:0" json:"balance"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+func (a *LedgerAccount) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// JournalEntry adalah satu kejadian ekonomi. Jumlah Postings per currency selalu nol.
+type JournalEntry struct {
+	ID            uuid.UUID `gorm:"type:text;primaryKey" json:"id"`
+	Type          string    `gorm:"type:varchar(50);index;not null" json:"type"`
+	ReferenceID   *string   `gorm:"type:varchar(255);uniqueIndex" json:"reference_id,omitempty"` // Idempotency
+	ConfigVersion *int      `gorm:"index" json:"config_version,omitempty"`                       // Versi EconomyConfig yang dipakai
+	CreatedAt     time.Time `json:"created_at"`
+
+	Postings []Posting `gorm:"foreignKey:EntryID" json:"postings,omitempty"`
+}
+
+func (j *JournalEntry) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+// Posting adalah satu kaki dari JournalEntry. Amount bertanda: positif menambah saldo akun.
+type Posting struct {
+	ID        uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	EntryID   uuid.UUID       `gorm:"type:text;index;not null" json:"entry_id"`
+	AccountID uuid.UUID       `gorm:"type:text;index;not null" json:"account_id"`
+	Currency  string          `gorm:"type:varchar(10);not null" json:"currency"`
+	Amount    decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"amount"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (p *Posting) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
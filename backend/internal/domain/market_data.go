@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Interval candle yang didukung
+const (
+	Candle1m = "1m"
+	Candle1h = "1h"
+	Candle1d = "1d"
+)
+
+// CandleIntervals memetakan interval ke lebar bucket-nya (bucket 1d mengikuti hari UTC).
+var CandleIntervals = map[string]time.Duration{
+	Candle1m: time.Minute,
+	Candle1h: time.Hour,
+	Candle1d: 24 * time.Hour,
+}
+
+// MarketCandle adalah agregat OHLCV harga per unit untuk satu item pada satu bucket waktu.
+// Volume dalam jumlah item, QuoteVolume dalam USDT.
+type MarketCandle struct {
+	ItemType    string          `gorm:"type:varchar(50);primaryKey" json:"item_type"`
+	Interval    string          `gorm:"column:period;type:varchar(4);primaryKey" json:"interval"`
+	BucketStart time.Time       `gorm:"primaryKey" json:"bucket_start"`
+	Open        decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"open"`
+	High        decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"high"`
+	Low         decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"low"`
+	Close       decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"close"`
+	Volume      int64           `gorm:"not null;default:0" json:"volume"`
+	QuoteVolume decimal.Decimal `gorm:"type:numeric(24,8);not null;default:0" json:"quote_volume"`
+	Trades      int64           `gorm:"not null;default:0" json:"trades"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Ticker adalah ringkasan 24 jam terakhir untuk satu item.
+type Ticker struct {
+	ItemType       string          `json:"item_type"`
+	LastPrice      decimal.Decimal `json:"last_price"`
+	LastTradeAt    *time.Time      `json:"last_trade_at,omitempty"`
+	High24h        decimal.Decimal `json:"high_24h"`
+	Low24h         decimal.Decimal `json:"low_24h"`
+	Volume24h      int64           `json:"volume_24h"`
+	QuoteVolume24h decimal.Decimal `json:"quote_volume_24h"`
+	Trades24h      int64           `json:"trades_24h"`
+	Change24h      decimal.Decimal `json:"change_24h"`
+	ChangePct24h   decimal.Decimal `json:"change_pct_24h"`
+}
@@ -20,11 +20,11 @@ type User struct {
 	ID              uuid.UUID       `gorm:"type:text;primaryKey"`
 	WalletAddress   string          `gorm:"type:varchar(42);uniqueIndex;not null"`
 	Role            Role            `gorm:"type:varchar(20);default:'F2P'"`
-	Points          decimal.Decimal `gorm:"type:numeric(18,2);default:0"`
-	GoldBalance     decimal.Decimal `gorm:"type:numeric(18,2);default:0"`
+	Points          decimal.Decimal `gorm:"type:numeric(24,8);default:0"` // Projection of ledger COW account
+	GoldBalance     decimal.Decimal `gorm:"type:numeric(24,8);default:0"` // Projection of ledger GOLD account
 	DailyAdCount    int             `gorm:"default:0"`
 	LastAdDate      *time.Time      // To reset DailyAdCount every 24h
-	USDTBalance     decimal.Decimal `gorm:"type:numeric(18,2);default:0"`
+	USDTBalance     decimal.Decimal `gorm:"type:numeric(24,8);default:0"` // Projection of ledger USDT account
 	Nonce           string          `gorm:"type:varchar(255);not null"`
 	ReferrerID      *uuid.UUID      `gorm:"type:text;index"`
 	LastAdWatchedAt *time.Time      // Web2 Care Mechanic (Vitamins)
@@ -50,8 +50,8 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 type Inventory struct {
 	ID        uuid.UUID `gorm:"type:text;primaryKey" json:"id"`
 	UserID    uuid.UUID `gorm:"type:text;uniqueIndex;not null" json:"user_id"`
-	Grass     int       `gorm:"default:0" json:"grass"`
-	Milk      int       `gorm:"default:0" json:"milk"`
+	Grass     int       `gorm:"default:0" json:"grass"` // Projection of ledger GRASS account
+	Milk      int       `gorm:"default:0" json:"milk"`  // Projection of ledger MILK account
 	LandSlots int       `gorm:"default:1" json:"land_slots"`
 	HasBarn   bool      `gorm:"default:false" json:"has_barn"`
 }
@@ -76,20 +76,60 @@ type Cow struct {
 	OwnerID          uuid.UUID `gorm:"type:text;index;not null"`
 	Type             CowType   `gorm:"type:varchar(20);default:'STANDARD'"`
 	Level            int       `gorm:"default:1"`
-	Happiness        int       `gorm:"default:100"`
+	Happiness        int       `gorm:"default:100"` // Nilai pada HappinessSettledAt; gunakan CurrentHappiness untuk nilai saat ini
 	ExpectedLifespan time.Time `gorm:"not null"`
 	LastFedAt        *time.Time
 	LastHarvestedAt  *time.Time
+	ListingID        *uuid.UUID `gorm:"type:text;index"` // Listing/Auction yang sedang menjual sapi ini (tidak bisa dipanen, tetap bisa diberi makan)
 	CreatedAt        time.Time
+
+	HappinessSettledAt *time.Time  // Terakhir decay lapar disimpan ke Happiness; nil = sapi lama sebelum simulasi lapar (lihat MigrateHungerRollout)
+	Hunger             HungerState `gorm:"-"` // Diisi saat status farm ditampilkan
 }
 
 func (c *Cow) BeforeCreate(tx *gorm.DB) error {
 	if c.ID == uuid.Nil {
 		c.ID = uuid.New()
 	}
+	if c.HappinessSettledAt == nil {
+		now := time.Now()
+		c.HappinessSettledAt = &now
+	}
 	return nil
 }
 
+// IsListed bernilai true selama sapi ada di listing marketplace yang aktif.
+func (c *Cow) IsListed() bool {
+	return c.ListingID != nil
+}
+
+// CowSnapshot adalah ringkasan sapi yang ditampilkan pada listing marketplace.
+type CowSnapshot struct {
+	ID                     uuid.UUID   `json:"id"`
+	Type                   CowType     `json:"type"`
+	Level                  int         `json:"level"`
+	Happiness              int         `json:"happiness"`
+	Hunger                 HungerState `json:"hunger_state"`
+	ExpectedLifespan       time.Time   `json:"expected_lifespan"`
+	RemainingLifespanHours int         `json:"remaining_lifespan_hours"`
+}
+
+func (c *Cow) Snapshot(now time.Time) CowSnapshot {
+	remaining := int(c.ExpectedLifespan.Sub(now).Hours())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return CowSnapshot{
+		ID:                     c.ID,
+		Type:                   c.Type,
+		Level:                  c.Level,
+		Happiness:              c.CurrentHappiness(now),
+		Hunger:                 c.HungerAt(now),
+		ExpectedLifespan:       c.ExpectedLifespan,
+		RemainingLifespanHours: remaining,
+	}
+}
+
 type TxStatus string
 
 const (
@@ -100,14 +140,16 @@ const (
 )
 
 type TxLog struct {
-	ID          uuid.UUID       `gorm:"type:text;primaryKey"`
-	UserID      uuid.UUID       `gorm:"type:text;index;not null"`
-	Type        string          `gorm:"type:varchar(50);not null"`
-	Amount      decimal.Decimal `gorm:"type:numeric(18,2);not null"`
-	Currency    string          `gorm:"type:varchar(10);not null"`
-	Status      TxStatus        `gorm:"type:varchar(20);default:'PENDING'"`
-	ReferenceID *string         `gorm:"type:varchar(255);uniqueIndex"` // Idempotency
-	CreatedAt   time.Time
+	ID            uuid.UUID       `gorm:"type:text;primaryKey"`
+	UserID        uuid.UUID       `gorm:"type:text;index;not null"`
+	Type          string          `gorm:"type:varchar(50);not null"`
+	Amount        decimal.Decimal `gorm:"type:numeric(24,8);not null"` // Signed when EntryID is set (negative = debit)
+	Currency      string          `gorm:"type:varchar(10);not null"`
+	Status        TxStatus        `gorm:"type:varchar(20);default:'PENDING'"`
+	ReferenceID   *string         `gorm:"type:varchar(255);uniqueIndex"` // Idempotency
+	EntryID       *uuid.UUID      `gorm:"type:text;index"`               // Ledger JournalEntry that produced this row
+	ConfigVersion *int            `gorm:"index"`                         // EconomyConfig version used to price this row
+	CreatedAt     time.Time
 }
 
 func (t *TxLog) BeforeCreate(tx *gorm.DB) error {
@@ -117,14 +159,35 @@ func (t *TxLog) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Status MarketListing
+const (
+	ListingOpen            = "OPEN"
+	ListingPartiallyFilled = "PARTIALLY_FILLED" // Sebagian sudah dibeli, sisa masih bisa dibeli
+	ListingSold            = "SOLD"
+	ListingCancelled       = "CANCELLED" // Ditarik penjual, sisa item dikembalikan dari escrow
+	ListingExpired         = "EXPIRED"   // Lewat ExpiresAt, sisa item dikembalikan oleh sweeper
+)
+
+// ItemCattle adalah ItemType listing untuk satu ekor sapi (Cow), bukan token COW.
+// Sapi tidak punya akun ledger: escrow-nya adalah Cow.ListingID dan kepemilikan pindah lewat Cow.OwnerID.
+const ItemCattle = "CATTLE"
+
 type MarketListing struct {
-	ID        uuid.UUID       `gorm:"type:text;primaryKey"`
-	SellerID  uuid.UUID       `gorm:"type:text;index;not null"`
-	ItemType  string          `gorm:"type:varchar(50);default:'GRASS'"`
-	Quantity  int             `gorm:"not null"`
-	PriceUSDT decimal.Decimal `gorm:"type:numeric(18,4);not null"`
-	Status    string          `gorm:"type:varchar(20);default:'OPEN'"`
-	CreatedAt time.Time
+	ID                uuid.UUID       `gorm:"type:text;primaryKey"`
+	SellerID          uuid.UUID       `gorm:"type:text;index;not null"`
+	ItemType          string          `gorm:"type:varchar(50);default:'GRASS';index:idx_listing_book,priority:1"`
+	Quantity          int             `gorm:"not null"`                                                                // Jumlah awal saat listing dibuat
+	RemainingQuantity int             `gorm:"not null;default:0"`                                                      // Sisa di escrow yang masih bisa dibeli
+	UnitPriceUSDT     decimal.Decimal `gorm:"type:numeric(24,8);not null;default:0;index:idx_listing_book,priority:3"` // Harga per 1 item
+	PriceUSDT         decimal.Decimal `gorm:"type:numeric(18,4);not null"`                                             // Quantity x UnitPriceUSDT (kompatibilitas klien lama)
+	Status            string          `gorm:"type:varchar(20);default:'OPEN';index:idx_listing_book,priority:2;index:idx_listing_recent,priority:1"`
+	ExpiresAt         *time.Time      `gorm:"index"`           // Opsional; nil = berlaku sampai dibatalkan
+	CowID             *uuid.UUID      `gorm:"type:text;index"` // Hanya untuk ItemType CATTLE
+	CreatedAt         time.Time       `gorm:"index:idx_listing_recent,priority:2"`
+	UpdatedAt         time.Time
+
+	Cow         *CowSnapshot    `gorm:"-"` // Diisi saat listing sapi ditampilkan
+	SellerStats *TradingProfile `gorm:"-"` // Reputasi penjual, diisi saat pencarian marketplace
 }
 
 func (m *MarketListing) BeforeCreate(tx *gorm.DB) error {
@@ -134,6 +197,60 @@ func (m *MarketListing) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// IsActive bernilai true selama masih ada sisa item yang bisa dibeli.
+func (m *MarketListing) IsActive() bool {
+	return m.Status == ListingOpen || m.Status == ListingPartiallyFilled
+}
+
+// IsExpired bernilai true jika listing punya ExpiresAt yang sudah lewat pada `now`.
+func (m *MarketListing) IsExpired(now time.Time) bool {
+	return m.ExpiresAt != nil && !m.ExpiresAt.After(now)
+}
+
+// MarketFill adalah satu transaksi (penuh atau parsial) terhadap sebuah listing.
+// BidID terisi jika listing dicocokkan dengan bid oleh order book (bukan dibeli manual).
+type MarketFill struct {
+	ID            uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	ListingID     uuid.UUID       `gorm:"type:text;index;not null" json:"listing_id"`
+	BidID         *uuid.UUID      `gorm:"type:text;index" json:"bid_id,omitempty"`
+	TakerSide     string          `gorm:"type:varchar(4);default:'BUY'" json:"taker_side"`
+	BuyerID       uuid.UUID       `gorm:"type:text;index;not null" json:"buyer_id"`
+	SellerID      uuid.UUID       `gorm:"type:text;index;not null" json:"seller_id"`
+	ItemType      string          `gorm:"type:varchar(50);not null" json:"item_type"`
+	Quantity      int             `gorm:"not null" json:"quantity"`
+	UnitPriceUSDT decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"unit_price_usdt"`
+	TotalUSDT     decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"total_usdt"`
+	BuyerFeeUSDT  decimal.Decimal `gorm:"type:numeric(24,8);default:0" json:"buyer_fee_usdt"`
+	SellerFeeUSDT decimal.Decimal `gorm:"type:numeric(24,8);default:0" json:"seller_fee_usdt"`
+	EntryID       uuid.UUID       `gorm:"type:text;not null" json:"entry_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func (f *MarketFill) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
+
+// MarketListingPriceChange adalah riwayat perubahan harga listing (reprice) untuk audit.
+// Sengaja bukan TxLog: perubahan harga tidak memindahkan saldo apa pun.
+type MarketListingPriceChange struct {
+	ID               uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	ListingID        uuid.UUID       `gorm:"type:text;index;not null" json:"listing_id"`
+	SellerID         uuid.UUID       `gorm:"type:text;index;not null" json:"seller_id"`
+	OldUnitPriceUSDT decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"old_unit_price_usdt"`
+	NewUnitPriceUSDT decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"new_unit_price_usdt"`
+	CreatedAt        time.Time       `json:"created_at"`
+}
+
+func (p *MarketListingPriceChange) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
 type Web2Stake struct {
 	ID            uuid.UUID       `gorm:"type:text;primaryKey"`
 	UserID        uuid.UUID       `gorm:"type:text;index;not null"`
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Status MarketOffer
+const (
+	OfferPending   = "PENDING"   // Menunggu jawaban penjual
+	OfferCountered = "COUNTERED" // Penjual mengajukan harga lain, menunggu jawaban pembeli
+	OfferAccepted  = "ACCEPTED"  // Diselesaikan sebagai fill pada listing
+	OfferRejected  = "REJECTED"  // Ditolak penjual (atau counter ditolak pembeli), USDT dikembalikan
+	OfferCancelled = "CANCELLED" // Ditarik pembeli, USDT dikembalikan
+	OfferExpired   = "EXPIRED"   // Lewat ExpiresAt, listing tidak aktif, atau sisa/harga listing tidak lagi cocok; USDT dikembalikan
+)
+
+// Aksi jawaban atas sebuah offer
+const (
+	OfferActionAccept  = "ACCEPT"
+	OfferActionReject  = "REJECT"
+	OfferActionCounter = "COUNTER"
+)
+
+// MarketOffer adalah tawaran harga di bawah harga listing. HeldUSDT (nilai tawaran + cadangan fee taker)
+// ditahan di escrow MARKET selama offer aktif dan dikembalikan penuh jika offer tidak berakhir ACCEPTED.
+type MarketOffer struct {
+	ID               uuid.UUID        `gorm:"type:text;primaryKey" json:"id"`
+	ListingID        uuid.UUID        `gorm:"type:text;index;not null" json:"listing_id"`
+	BuyerID          uuid.UUID        `gorm:"type:text;index;not null" json:"buyer_id"`
+	SellerID         uuid.UUID        `gorm:"type:text;index;not null" json:"seller_id"`
+	ItemType         string           `gorm:"type:varchar(50);not null" json:"item_type"`
+	Quantity         int              `gorm:"not null" json:"quantity"`
+	UnitPriceUSDT    decimal.Decimal  `gorm:"type:numeric(24,8);not null" json:"unit_price_usdt"`     // Harga tawaran pembeli per item
+	CounterPriceUSDT *decimal.Decimal `gorm:"type:numeric(24,8)" json:"counter_price_usdt,omitempty"` // Harga counter penjual per item
+	HeldUSDT         decimal.Decimal  `gorm:"type:numeric(24,8);not null" json:"held_usdt"`           // Yang ditahan di escrow
+	Status           string           `gorm:"type:varchar(20);index:idx_offer_due,priority:1;default:'PENDING'" json:"status"`
+	ExpiresAt        time.Time        `gorm:"index:idx_offer_due,priority:2;not null" json:"expires_at"`
+	FillID           *uuid.UUID       `gorm:"type:text" json:"fill_id,omitempty"` // Terisi jika ACCEPTED
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+}
+
+func (o *MarketOffer) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsActive bernilai true selama offer masih menunggu jawaban salah satu pihak.
+func (o *MarketOffer) IsActive() bool {
+	return o.Status == OfferPending || o.Status == OfferCountered
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Status MarketBid. Nilai OPEN, PARTIALLY_FILLED dan CANCELLED sama dengan MarketListing agar baris lama tetap valid.
+const (
+	BidOpen            = "OPEN"
+	BidPartiallyFilled = "PARTIALLY_FILLED" // Sebagian sudah terisi, sisa masih resting di buku
+	BidFilled          = "FILLED"
+	BidCancelled       = "CANCELLED" // Ditarik pembeli, sisa USDT dikembalikan dari escrow
+)
+
+// Sisi taker sebuah fill
+const (
+	TakerBuy  = "BUY"
+	TakerSell = "SELL"
+)
+
+// MarketBid adalah order beli GRASS/MILK. Selama bid aktif, escrow MARKET menahan nilai sisa bid
+// (RemainingQuantity x UnitPriceUSDT) ditambah cadangan fee sebesar FeeRate dari nilai itu (lihat bidHold).
+// Sisi ask order book adalah MarketListing.
+type MarketBid struct {
+	ID                uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	BuyerID           uuid.UUID       `gorm:"type:text;index;not null" json:"buyer_id"`
+	ItemType          string          `gorm:"type:varchar(50);index:idx_bid_book,priority:1;not null" json:"item_type"`
+	Quantity          int             `gorm:"not null" json:"quantity"`
+	RemainingQuantity int             `gorm:"not null" json:"remaining_quantity"`
+	UnitPriceUSDT     decimal.Decimal `gorm:"type:numeric(24,8);index:idx_bid_book,priority:3;not null" json:"unit_price_usdt"`
+	FeeRate           decimal.Decimal `gorm:"type:numeric(10,6);default:0" json:"fee_rate"` // Cadangan fee yang ikut ditahan di escrow (fee tertinggi saat bid dipasang)
+	Status            string          `gorm:"type:varchar(20);index:idx_bid_book,priority:2;default:'OPEN'" json:"status"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+}
+
+func (b *MarketBid) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsActive bernilai true selama bid masih bisa dicocokkan.
+func (b *MarketBid) IsActive() bool {
+	return b.Status == BidOpen || b.Status == BidPartiallyFilled
+}
+
+// OrderBookLevel adalah total quantity pada satu level harga.
+type OrderBookLevel struct {
+	UnitPriceUSDT decimal.Decimal `json:"unit_price_usdt"`
+	Quantity      int64           `json:"quantity"`
+	Orders        int64           `json:"orders"`
+}
+
+type OrderBook struct {
+	ItemType string           `json:"item_type"`
+	Bids     []OrderBookLevel `json:"bids"` // Harga tertinggi dulu
+	Asks     []OrderBookLevel `json:"asks"` // Harga terendah dulu
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ReconciliationRun adalah satu eksekusi job rekonsiliasi saldo.
+type ReconciliationRun struct {
+	ID           uuid.UUID  `gorm:"type:text;primaryKey" json:"id"`
+	Trigger      string     `gorm:"type:varchar(20);not null" json:"trigger"` // NIGHTLY atau MANUAL
+	Status       TxStatus   `gorm:"type:varchar(20);default:'PENDING'" json:"status"`
+	UsersChecked int        `gorm:"default:0" json:"users_checked"`
+	Mismatches   int        `gorm:"default:0" json:"mismatches"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+func (r *ReconciliationRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// Jenis pemeriksaan ReconciliationReport
+const (
+	ReconcileProjection = "PROJECTION"      // Kolom saldo User/Inventory vs histori TxLog
+	ReconcileOpening    = "OPENING_BALANCE" // Saldo pembukaan ledger vs histori TxLog sebelum ledger aktif
+)
+
+// ReconciliationReport mencatat satu saldo yang tidak bisa dijelaskan oleh histori TxLog.
+// Drift = Stored - Expected (positif berarti pemain punya saldo lebih dari yang tercatat).
+type ReconciliationReport struct {
+	ID        uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	RunID     uuid.UUID       `gorm:"type:text;index;not null" json:"run_id"`
+	UserID    uuid.UUID       `gorm:"type:text;index;not null" json:"user_id"`
+	Currency  string          `gorm:"type:varchar(10);not null" json:"currency"`
+	Kind      string          `gorm:"type:varchar(20);default:'PROJECTION'" json:"kind"`
+	Expected  decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"expected"`
+	Stored    decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"stored"`
+	Drift     decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"drift"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (r *ReconciliationReport) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
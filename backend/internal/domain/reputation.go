@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// TradingStat adalah agregat riwayat fill P2P satu user, diperbarui di setiap fill dan di-backfill dari
+// riwayat trade saat pertama kali dijalankan.
+type TradingStat struct {
+	UserID         uuid.UUID       `gorm:"type:text;primaryKey"`
+	TradesAsSeller int64           `gorm:"not null;default:0"`
+	TradesAsBuyer  int64           `gorm:"not null;default:0"`
+	VolumeUSDT     decimal.Decimal `gorm:"type:numeric(24,8);not null;default:0"` // Nilai trade sebagai pembeli + penjual
+	SellSeconds    int64           `gorm:"not null;default:0"`                    // Total detik dari listing dibuat hingga terisi
+	UpdatedAt      time.Time
+}
+
+// TradingCounterparty menghitung jumlah fill antara dua user, dicatat dari sisi masing-masing user.
+type TradingCounterparty struct {
+	UserID         uuid.UUID `gorm:"type:text;primaryKey"`
+	CounterpartyID uuid.UUID `gorm:"type:text;primaryKey"`
+	Trades         int64     `gorm:"not null;default:0"`
+}
+
+// TradingProfile adalah reputasi trading publik seorang user.
+type TradingProfile struct {
+	WalletAddress        string          `json:"wallet_address,omitempty"`
+	CompletedTrades      int64           `json:"completed_trades"`
+	TradesAsSeller       int64           `json:"trades_as_seller"`
+	TradesAsBuyer        int64           `json:"trades_as_buyer"`
+	VolumeUSDT           decimal.Decimal `json:"volume_usdt"`
+	ListingsCreated      int64           `json:"listings_created"`
+	ListingsCancelled    int64           `json:"listings_cancelled"`
+	CancelRate           decimal.Decimal `json:"cancel_rate"`              // ListingsCancelled / ListingsCreated (0-1)
+	AvgTimeToSellSeconds int64           `json:"avg_time_to_sell_seconds"` // Rata-rata per fill sebagai penjual
+	UniqueCounterparties int64           `json:"unique_counterparties"`
+	RepeatCounterparties int64           `json:"repeat_counterparties"` // Lawan trade dengan 2 fill atau lebih
+	MemberSince          time.Time       `json:"member_since"`
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type WithdrawalState string
+
+// Alur: REQUESTED -> HELD -> SIGNED -> BROADCAST -> CONFIRMED
+// Setiap state sebelum CONFIRMED bisa berakhir di FAILED (saldo di-refund dari escrow).
+// Setelah batas percobaan, HELD/SIGNED/BROADCAST pindah ke REVIEW dan menunggu operator.
+// Refund operator atas withdrawal yang nonce-nya belum terbukti terpakai melewati CANCELLING lebih dulu.
+const (
+	WithdrawalRequested  WithdrawalState = "REQUESTED"  // Permintaan tercatat, saldo belum ditahan
+	WithdrawalHeld       WithdrawalState = "HELD"       // Saldo dipindah ke escrow WITHDRAWAL
+	WithdrawalSigned     WithdrawalState = "SIGNED"     // Tx ERC-20 ditandatangani dengan nonce terkelola
+	WithdrawalBroadcast  WithdrawalState = "BROADCAST"  // Tx sudah dikirim ke mempool
+	WithdrawalConfirmed  WithdrawalState = "CONFIRMED"  // Receipt sukses dengan N konfirmasi
+	WithdrawalFailed     WithdrawalState = "FAILED"     // Revert / tidak bisa diproses, saldo dikembalikan
+	WithdrawalReview     WithdrawalState = "REVIEW"     // Butuh tindakan operator, saldo tetap di escrow (tidak pernah di-refund otomatis)
+	WithdrawalCancelling WithdrawalState = "CANCELLING" // Refund operator menunggu tx pembatalan (self-transfer 0, nonce sama) ter-mine
+)
+
+// Withdrawal adalah satu permintaan payout on-chain ke wallet user.
+// Status mengikuti TxStatus: PENDING selama diproses, SUCCESS saat CONFIRMED,
+// ROLLED_BACK saat FAILED dan saldo sudah dikembalikan.
+type Withdrawal struct {
+	ID               uuid.UUID       `gorm:"type:text;primaryKey" json:"id"`
+	UserID           uuid.UUID       `gorm:"type:text;index;not null" json:"user_id"`
+	Currency         string          `gorm:"type:varchar(10);not null" json:"currency"`
+	Amount           decimal.Decimal `gorm:"type:numeric(24,8);not null" json:"amount"`
+	ToAddress        string          `gorm:"type:varchar(42);not null" json:"to_address"`
+	State            WithdrawalState `gorm:"type:varchar(20);index;not null" json:"state"`
+	Status           TxStatus        `gorm:"type:varchar(20);default:'PENDING'" json:"status"`
+	FromAddress      *string         `gorm:"type:varchar(42)" json:"from_address,omitempty"`
+	Nonce            *uint64         `json:"nonce,omitempty"`
+	TxHash           *string         `gorm:"type:varchar(66);uniqueIndex" json:"tx_hash,omitempty"`
+	RawTx            string          `gorm:"type:text" json:"-"`                               // Tx bertanda (hex) untuk re-broadcast
+	ReplacedTxHashes string          `gorm:"type:text" json:"replaced_tx_hashes,omitempty"`    // Hash tx lama dengan nonce sama yang diganti fee bump (dipisah koma)
+	BroadcastAt      *time.Time      `json:"broadcast_at,omitempty"`                           // Terakhir dikirim atau di-bump
+	CancelTxHash     *string         `gorm:"type:varchar(66)" json:"cancel_tx_hash,omitempty"` // Tx pembatalan saat CANCELLING
+	CancelRawTx      string          `gorm:"type:text" json:"-"`
+	BlockNumber      *uint64         `json:"block_number,omitempty"`
+	Attempts         int             `gorm:"default:0" json:"attempts"`
+	LastError        string          `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+func (w *Withdrawal) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+// SignerNonce adalah nonce berikutnya untuk hot wallet withdrawal.
+// Dialokasikan di dalam transaksi yang sama dengan penandatanganan sehingga tidak pernah dipakai dua kali.
+type SignerNonce struct {
+	Address   string `gorm:"type:varchar(42);primaryKey"`
+	NextNonce uint64 `gorm:"not null"`
+	UpdatedAt time.Time
+}
@@ -0,0 +1,439 @@
+// Package ledger adalah buku besar double-entry untuk seluruh ekonomi Cash Cow Valley.
+//
+// Setiap perubahan saldo (GOLD, USDT, COW, GRASS, MILK) wajib lewat Post sebagai satu
+// JournalEntry yang seimbang per currency. Kolom saldo di User dan Inventory hanyalah
+// projection dari akun USER di ledger dan diperbarui di transaksi yang sama.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/pkg/realtime"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnbalanced        = errors.New("ledger: journal entry tidak seimbang")
+	ErrEmptyEntry        = errors.New("ledger: journal entry tidak memiliki posting")
+	ErrDuplicateEntry    = errors.New("ledger: journal entry dengan reference ini sudah ada")
+	ErrInsufficientFunds = errors.New("ledger: saldo akun tidak mencukupi")
+	ErrUnknownCurrency   = errors.New("ledger: currency tidak dikenali")
+)
+
+// Account mengidentifikasi satu akun ledger tanpa perlu tahu ID database-nya.
+type Account struct {
+	Kind     domain.AccountKind
+	OwnerID  uuid.UUID // Hanya untuk AccountUser
+	Bucket   string    // Untuk TREASURY / ESCROW / SYSTEM
+	Currency string
+}
+
+// User mengembalikan akun saldo milik pemain.
+func User(userID uuid.UUID, currency string) Account {
+	return Account{Kind: domain.AccountUser, OwnerID: userID, Currency: currency}
+}
+
+// Treasury mengembalikan akun kas platform.
+func Treasury(bucket, currency string) Account {
+	return Account{Kind: domain.AccountTreasury, Bucket: bucket, Currency: currency}
+}
+
+// Escrow mengembalikan akun penahanan (listing, staking).
+func Escrow(bucket, currency string) Account {
+	return Account{Kind: domain.AccountEscrow, Bucket: bucket, Currency: currency}
+}
+
+// System mengembalikan akun sumber/muara ekonomi. Saldonya boleh negatif.
+func System(bucket, currency string) Account {
+	return Account{Kind: domain.AccountSystem, Bucket: bucket, Currency: currency}
+}
+
+// Code adalah kunci unik akun di tabel ledger_accounts, mis. "USER:<uuid>:GOLD".
+func (a Account) Code() string {
+	if a.Kind == domain.AccountUser {
+		return fmt.Sprintf("%s:%s:%s", a.Kind, a.OwnerID, a.Currency)
+	}
+	return fmt.Sprintf("%s:%s:%s", a.Kind, a.Bucket, a.Currency)
+}
+
+// Leg adalah satu sisi perpindahan nilai. Amount positif menambah saldo akun.
+type Leg struct {
+	Account     Account
+	Amount      decimal.Decimal
+	TxType      string          // Override TxLog.Type untuk leg user (default: Entry.Type)
+	ReferenceID *string         // Disalin ke TxLog.ReferenceID untuk leg user (idempotency per user)
+	Status      domain.TxStatus // Status TxLog untuk leg user (default: SUCCESS), mis. PENDING untuk hold withdrawal
+}
+
+// Entry adalah permintaan posting ke ledger.
+type Entry struct {
+	Type          string
+	ReferenceID   *string // Idempotency untuk seluruh JournalEntry
+	ConfigVersion *int    // Versi EconomyConfig yang dipakai menghitung entry (disalin ke TxLog)
+	Legs          []Leg
+}
+
+// Transfer adalah helper untuk entry dua kaki: amount berpindah dari akun `from` ke `to`.
+func Transfer(entryType string, from, to Account, amount decimal.Decimal) Entry {
+	return Entry{
+		Type: entryType,
+		Legs: []Leg{
+			{Account: from, Amount: amount.Neg()},
+			{Account: to, Amount: amount},
+		},
+	}
+}
+
+// Post membukukan entry di dalam transaksi `tx` milik pemanggil.
+// Semua akun dikunci (FOR UPDATE) dengan urutan Code untuk mencegah deadlock,
+// saldo akun non-SYSTEM tidak boleh negatif, projection User/Inventory diperbarui,
+// dan setiap leg milik user menghasilkan satu baris TxLog bertanda.
+func Post(tx *gorm.DB, e Entry) (*domain.JournalEntry, error) {
+	legs := make([]Leg, 0, len(e.Legs))
+	sums := map[string]decimal.Decimal{}
+	for _, l := range e.Legs {
+		if l.Amount.IsZero() {
+			continue
+		}
+		if _, ok := projectionColumn[l.Account.Currency]; !ok {
+			return nil, ErrUnknownCurrency
+		}
+		sums[l.Account.Currency] = sums[l.Account.Currency].Add(l.Amount)
+		legs = append(legs, l)
+	}
+	if len(legs) == 0 {
+		return nil, ErrEmptyEntry
+	}
+	for _, sum := range sums {
+		if !sum.IsZero() {
+			return nil, ErrUnbalanced
+		}
+	}
+
+	if err := checkReference(tx, e.ReferenceID); err != nil {
+		return nil, err
+	}
+
+	// Urutkan berdasarkan Code agar dua transaksi paralel selalu mengunci akun dengan urutan yang sama
+	sort.SliceStable(legs, func(i, j int) bool { return legs[i].Account.Code() < legs[j].Account.Code() })
+
+	if err := lockProjections(tx, legs); err != nil {
+		return nil, err
+	}
+
+	// Saldo pembukaan akun USER baru ikut dikunci dalam satu putaran terurut bersama leg entry,
+	// sehingga SYSTEM:OPENING tidak pernah dikunci di luar urutan Code
+	openings, err := openingEntries(tx, legs)
+	if err != nil {
+		return nil, err
+	}
+	all := legs
+	for _, o := range openings {
+		all = append(all, o.Legs...)
+	}
+	accounts, err := lockAccounts(tx, all)
+	if err != nil {
+		return nil, err
+	}
+
+	var touched []uuid.UUID
+	for _, o := range openings {
+		if _, err := apply(tx, o, accounts, &touched); err != nil {
+			return nil, err
+		}
+	}
+	entry, err := apply(tx, Entry{Type: e.Type, ReferenceID: e.ReferenceID, ConfigVersion: e.ConfigVersion, Legs: legs}, accounts, &touched)
+	if err != nil {
+		return nil, err
+	}
+
+	// Saldo user yang berubah dikirim ke stream real-time setelah transaksi pemanggil commit
+	realtime.Touch(tx.Statement.Context, touched...)
+
+	return entry, nil
+}
+
+// checkReference menolak entry yang ReferenceID-nya sudah pernah dibukukan.
+func checkReference(tx *gorm.DB, ref *string) error {
+	if ref == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&domain.JournalEntry{}).Where("reference_id = ?", *ref).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return nil
+}
+
+// apply membuat JournalEntry untuk `e` dan membukukan leg-nya ke akun yang sudah dikunci.
+// Leg `e` harus sudah tervalidasi (seimbang, tanpa amount nol).
+func apply(tx *gorm.DB, e Entry, accounts map[string]*domain.LedgerAccount, touched *[]uuid.UUID) (*domain.JournalEntry, error) {
+	entry := domain.JournalEntry{Type: e.Type, ReferenceID: e.ReferenceID, ConfigVersion: e.ConfigVersion}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+
+	for _, l := range e.Legs {
+		acc := accounts[l.Account.Code()]
+		acc.Balance = acc.Balance.Add(l.Amount)
+		if acc.Kind != domain.AccountSystem && acc.Balance.IsNegative() {
+			return nil, fmt.Errorf("%w (%s)", ErrInsufficientFunds, acc.Code)
+		}
+		if err := tx.Model(acc).Update("balance", acc.Balance).Error; err != nil {
+			return nil, err
+		}
+
+		posting := domain.Posting{
+			EntryID:   entry.ID,
+			AccountID: acc.ID,
+			Currency:  acc.Currency,
+			Amount:    l.Amount,
+		}
+		if err := tx.Create(&posting).Error; err != nil {
+			return nil, err
+		}
+		entry.Postings = append(entry.Postings, posting)
+
+		if acc.Kind != domain.AccountUser {
+			continue
+		}
+
+		if err := project(tx, l.Account.OwnerID, acc.Currency, acc.Balance); err != nil {
+			return nil, err
+		}
+
+		txType := l.TxType
+		if txType == "" {
+			txType = e.Type
+		}
+		status := l.Status
+		if status == "" {
+			status = domain.TxSuccess
+		}
+		entryID := entry.ID
+		if err := tx.Create(&domain.TxLog{
+			UserID:        l.Account.OwnerID,
+			Type:          txType,
+			Amount:        l.Amount,
+			Currency:      acc.Currency,
+			Status:        status,
+			ReferenceID:   l.ReferenceID,
+			EntryID:       &entryID,
+			ConfigVersion: e.ConfigVersion,
+		}).Error; err != nil {
+			return nil, err
+		}
+		*touched = append(*touched, l.Account.OwnerID)
+	}
+	return &entry, nil
+}
+
+// Balance membaca saldo akun tanpa lock. Akun yang belum pernah dibuat dianggap nol,
+// kecuali akun USER yang saldonya diambil dari projection lama.
+func Balance(tx *gorm.DB, a Account) (decimal.Decimal, error) {
+	var acc domain.LedgerAccount
+	err := tx.Where("code = ?", a.Code()).First(&acc).Error
+	if err == nil {
+		return acc.Balance, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return decimal.Zero, err
+	}
+	if a.Kind == domain.AccountUser {
+		return readProjection(tx, a.OwnerID, a.Currency)
+	}
+	return decimal.Zero, nil
+}
+
+// lockProjections mengunci baris users lalu inventories milik semua leg USER sebelum akun ledger.
+// Urutan global (users -> inventories -> ledger_accounts) mencegah deadlock dengan usecase
+// yang sudah mengunci baris User/Inventory lebih dulu.
+func lockProjections(tx *gorm.DB, legs []Leg) error {
+	var userIDs, inventoryIDs []uuid.UUID
+	for _, l := range legs {
+		if l.Account.Kind != domain.AccountUser {
+			continue
+		}
+		if isInventoryCurrency(l.Account.Currency) {
+			inventoryIDs = append(inventoryIDs, l.Account.OwnerID)
+		} else {
+			userIDs = append(userIDs, l.Account.OwnerID)
+		}
+	}
+
+	if len(userIDs) > 0 {
+		var users []domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("id IN ?", userIDs).Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+	}
+	if len(inventoryIDs) > 0 {
+		var invs []domain.Inventory
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("user_id IN ?", inventoryIDs).Order("user_id").Find(&invs).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openingEntries menyiapkan entry OPENING_BALANCE untuk akun USER yang belum ada: saldo di kolom
+// projection (dari era sebelum ledger) dibukukan agar tidak hilang. Dipanggil setelah lockProjections
+// sehingga projection tidak berubah sebelum dibukukan.
+func openingEntries(tx *gorm.DB, legs []Leg) ([]Entry, error) {
+	var entries []Entry
+	seen := map[string]bool{}
+	for _, l := range legs {
+		a := l.Account
+		code := a.Code()
+		if a.Kind != domain.AccountUser || seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		var count int64
+		if err := tx.Model(&domain.LedgerAccount{}).Where("code = ?", code).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			continue
+		}
+		opening, err := readProjection(tx, a.OwnerID, a.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if opening.IsZero() {
+			continue
+		}
+
+		ref := "opening:" + code
+		if err := checkReference(tx, &ref); err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Type:        "OPENING_BALANCE",
+			ReferenceID: &ref,
+			Legs: []Leg{
+				{Account: System(domain.BucketOpening, a.Currency), Amount: opening.Neg()},
+				{Account: a, Amount: opening},
+			},
+		})
+	}
+	return entries, nil
+}
+
+// lockAccounts mengunci (FOR UPDATE) semua akun yang disentuh `legs` dengan urutan Code,
+// membuat akun yang belum ada dengan saldo nol.
+func lockAccounts(tx *gorm.DB, legs []Leg) (map[string]*domain.LedgerAccount, error) {
+	byCode := map[string]Account{}
+	codes := make([]string, 0, len(legs))
+	for _, l := range legs {
+		code := l.Account.Code()
+		if _, ok := byCode[code]; !ok {
+			byCode[code] = l.Account
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	accounts := make(map[string]*domain.LedgerAccount, len(codes))
+	for _, code := range codes {
+		acc, err := lockAccount(tx, byCode[code])
+		if err != nil {
+			return nil, err
+		}
+		accounts[code] = acc
+	}
+	return accounts, nil
+}
+
+// lockAccount mengambil akun dengan FOR UPDATE, membuatnya jika belum ada.
+func lockAccount(tx *gorm.DB, a Account) (*domain.LedgerAccount, error) {
+	var acc domain.LedgerAccount
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", a.Code()).First(&acc).Error
+	if err == nil {
+		return &acc, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	acc = domain.LedgerAccount{
+		Code:     a.Code(),
+		Kind:     a.Kind,
+		Bucket:   a.Bucket,
+		Currency: a.Currency,
+	}
+	if a.Kind == domain.AccountUser {
+		ownerID := a.OwnerID
+		acc.OwnerID = &ownerID
+	}
+	if err := tx.Create(&acc).Error; err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// projectionColumn memetakan currency ke kolom saldo lama yang kini menjadi projection.
+var projectionColumn = map[string]string{
+	domain.CurrencyGold:  "gold_balance",
+	domain.CurrencyUSDT:  "usdt_balance",
+	domain.CurrencyCOW:   "points",
+	domain.CurrencyGrass: "grass",
+	domain.CurrencyMilk:  "milk",
+}
+
+// isInventoryCurrency true untuk item yang disimpan sebagai integer di tabel inventories.
+func isInventoryCurrency(currency string) bool {
+	return currency == domain.CurrencyGrass || currency == domain.CurrencyMilk
+}
+
+func readProjection(tx *gorm.DB, userID uuid.UUID, currency string) (decimal.Decimal, error) {
+	var value decimal.NullDecimal
+	query := tx.Model(&domain.User{}).Where("id = ?", userID)
+	if isInventoryCurrency(currency) {
+		query = tx.Model(&domain.Inventory{}).Where("user_id = ?", userID)
+	}
+	if err := query.Select(projectionColumn[currency]).Scan(&value).Error; err != nil {
+		return decimal.Zero, err
+	}
+	if !value.Valid {
+		return decimal.Zero, nil
+	}
+	return value.Decimal, nil
+}
+
+func project(tx *gorm.DB, userID uuid.UUID, currency string, balance decimal.Decimal) error {
+	col := projectionColumn[currency]
+	if !isInventoryCurrency(currency) {
+		return tx.Model(&domain.User{}).Where("id = ?", userID).Update(col, balance).Error
+	}
+
+	res := tx.Model(&domain.Inventory{}).Where("user_id = ?", userID).Update(col, balance.IntPart())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+
+	// Pemain lama tanpa inventory: buat on-the-fly seperti di BuyItem
+	inv := domain.Inventory{UserID: userID}
+	if currency == domain.CurrencyGrass {
+		inv.Grass = int(balance.IntPart())
+	} else {
+		inv.Milk = int(balance.IntPart())
+	}
+	return tx.Create(&inv).Error
+}
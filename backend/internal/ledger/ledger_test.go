@@ -0,0 +1,176 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/glebarez/sqlite"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&domain.User{}, &domain.Inventory{}, &domain.TxLog{},
+		&domain.LedgerAccount{}, &domain.JournalEntry{}, &domain.Posting{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func newTestUser(t *testing.T, db *gorm.DB, wallet string) domain.User {
+	t.Helper()
+	user := domain.User{WalletAddress: wallet, Nonce: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := db.Create(&domain.Inventory{UserID: user.ID}).Error; err != nil {
+		t.Fatalf("create inventory: %v", err)
+	}
+	return user
+}
+
+func mustBalance(t *testing.T, db *gorm.DB, a Account) decimal.Decimal {
+	t.Helper()
+	balance, err := Balance(db, a)
+	if err != nil {
+		t.Fatalf("balance %s: %v", a.Code(), err)
+	}
+	return balance
+}
+
+func TestPostRejectsInvalidEntries(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "0xa")
+	mint := System(domain.BucketMint, domain.CurrencyGold)
+
+	cases := []struct {
+		name string
+		legs []Leg
+		want error
+	}{
+		{"tidak seimbang", []Leg{
+			{Account: mint, Amount: decimal.NewFromInt(-5)},
+			{Account: User(user.ID, domain.CurrencyGold), Amount: decimal.NewFromInt(4)},
+		}, ErrUnbalanced},
+		{"seimbang lintas currency", []Leg{
+			{Account: mint, Amount: decimal.NewFromInt(-5)},
+			{Account: User(user.ID, domain.CurrencyUSDT), Amount: decimal.NewFromInt(5)},
+		}, ErrUnbalanced},
+		{"tanpa posting", []Leg{
+			{Account: mint, Amount: decimal.Zero},
+		}, ErrEmptyEntry},
+		{"currency tidak dikenal", []Leg{
+			{Account: System(domain.BucketMint, "DOGE"), Amount: decimal.NewFromInt(-1)},
+			{Account: User(user.ID, "DOGE"), Amount: decimal.NewFromInt(1)},
+		}, ErrUnknownCurrency},
+		{"saldo user negatif", []Leg{
+			{Account: User(user.ID, domain.CurrencyGold), Amount: decimal.NewFromInt(-1)},
+			{Account: mint, Amount: decimal.NewFromInt(1)},
+		}, ErrInsufficientFunds},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				_, err := Post(tx, Entry{Type: "TEST", Legs: tc.legs})
+				return err
+			})
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, harus %v", err, tc.want)
+			}
+		})
+	}
+
+	var entries int64
+	db.Model(&domain.JournalEntry{}).Count(&entries)
+	if entries != 0 {
+		t.Fatalf("entry yang ditolak tidak boleh tersimpan, ada %d", entries)
+	}
+}
+
+func TestPostProjectsInventoryAsIntPart(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "0xa")
+
+	for _, currency := range []string{domain.CurrencyGrass, domain.CurrencyMilk} {
+		entry := Transfer("TEST", System(domain.BucketMint, currency), User(user.ID, currency), decimal.RequireFromString("2.75"))
+		if _, err := Post(db, entry); err != nil {
+			t.Fatalf("post %s: %v", currency, err)
+		}
+		if got := mustBalance(t, db, User(user.ID, currency)); !got.Equal(decimal.RequireFromString("2.75")) {
+			t.Fatalf("saldo ledger %s = %s, harus 2.75", currency, got)
+		}
+	}
+
+	var inv domain.Inventory
+	db.First(&inv, "user_id = ?", user.ID)
+	if inv.Grass != 2 || inv.Milk != 2 {
+		t.Fatalf("projection inventory = grass %d milk %d, harus 2 dan 2", inv.Grass, inv.Milk)
+	}
+}
+
+func TestPostIdempotentReference(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "0xa")
+
+	ref := "deposit:abc"
+	entry := Transfer("DEPOSIT", System(domain.BucketMint, domain.CurrencyUSDT), User(user.ID, domain.CurrencyUSDT), decimal.NewFromInt(3))
+	entry.ReferenceID = &ref
+	if _, err := Post(db, entry); err != nil {
+		t.Fatalf("post pertama: %v", err)
+	}
+	if _, err := Post(db, entry); !errors.Is(err, ErrDuplicateEntry) {
+		t.Fatalf("post kedua err = %v, harus ErrDuplicateEntry", err)
+	}
+	if got := mustBalance(t, db, User(user.ID, domain.CurrencyUSDT)); !got.Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("saldo = %s, harus 3 (dikredit sekali)", got)
+	}
+}
+
+func TestPostOpensLegacyBalance(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "0xa")
+	// Saldo dari era sebelum ledger hanya ada di kolom projection
+	db.Model(&user).Update("gold_balance", decimal.NewFromInt(10))
+	db.Model(&domain.Inventory{}).Where("user_id = ?", user.ID).Update("grass", 4)
+
+	burn := System(domain.BucketBurn, domain.CurrencyGold)
+	if _, err := Post(db, Transfer("SPEND", User(user.ID, domain.CurrencyGold), burn, decimal.NewFromInt(7))); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if got := mustBalance(t, db, User(user.ID, domain.CurrencyGold)); !got.Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("saldo GOLD = %s, harus 3", got)
+	}
+	if got := mustBalance(t, db, System(domain.BucketOpening, domain.CurrencyGold)); !got.Equal(decimal.NewFromInt(-10)) {
+		t.Fatalf("saldo OPENING = %s, harus -10", got)
+	}
+	var opening domain.JournalEntry
+	if err := db.First(&opening, "reference_id = ?", "opening:"+User(user.ID, domain.CurrencyGold).Code()).Error; err != nil || opening.Type != "OPENING_BALANCE" {
+		t.Fatalf("entry OPENING_BALANCE tidak ditemukan: %+v err=%v", opening, err)
+	}
+
+	// Akun yang sudah dibuka tidak dibuka ulang; saldo lama tetap dipakai untuk cek saldo negatif
+	if _, err := Post(db, Transfer("SPEND", User(user.ID, domain.CurrencyGold), burn, decimal.NewFromInt(4))); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("belanja melebihi saldo err = %v, harus ErrInsufficientFunds", err)
+	}
+	if _, err := Post(db, Transfer("FEED", User(user.ID, domain.CurrencyGrass), System(domain.BucketBurn, domain.CurrencyGrass), decimal.NewFromInt(1))); err != nil {
+		t.Fatalf("post GRASS: %v", err)
+	}
+	var inv domain.Inventory
+	db.First(&inv, "user_id = ?", user.ID)
+	if inv.Grass != 3 {
+		t.Fatalf("projection GRASS = %d, harus 3", inv.Grass)
+	}
+	var user2 domain.User
+	db.First(&user2, "id = ?", user.ID)
+	if !user2.GoldBalance.Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("projection GOLD = %s, harus 3", user2.GoldBalance)
+	}
+}
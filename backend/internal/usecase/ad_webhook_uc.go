@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -93,40 +94,41 @@ func (uc *AdWebhookUsecase) ProcessAdReward(ctx context.Context, rawPayload stri
 		user.DailyAdCount++
 		user.LastAdWatchedAt = &now
 
-		// Reward Gold
-		goldReward := decimal.NewFromInt(10) // 10 Gold per ad
-		user.GoldBalance = user.GoldBalance.Add(goldReward)
-
-		if err := tx.Save(&user).Error; err != nil {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"daily_ad_count":     user.DailyAdCount,
+			"last_ad_date":       user.LastAdDate,
+			"last_ad_watched_at": user.LastAdWatchedAt,
+		}).Error; err != nil {
 			return err
 		}
 
+		// Reward Gold
+		goldReward := decimal.NewFromInt(10) // 10 Gold per ad
+		entry := ledger.Transfer("AD_REWARD_GOLD",
+			ledger.System(domain.BucketMint, domain.CurrencyGold),
+			ledger.User(uid, domain.CurrencyGold),
+			goldReward)
+
 		// Temukan sapi pertama milik user yang butuh di-boost
 		var cow domain.Cow
 		errCow := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("owner_id = ? AND type = ?", uid, domain.TypeStandard).Order("created_at ASC").First(&cow).Error
+			Where("owner_id = ? AND type = ? AND listing_id IS NULL", uid, domain.TypeStandard).Order("created_at ASC").First(&cow).Error
 
-		if errCow == nil && cow.Happiness < 100 {
-			cow.Happiness += 50
-			if cow.Happiness > 100 {
-				cow.Happiness = 100
-			}
+		if errCow == nil && cow.CurrentHappiness(now) < domain.MaxHappiness {
+			cow.AddHappiness(now, 50)
 			if err := tx.Save(&cow).Error; err != nil {
 				return err
 			}
 		} else {
 			// BUG FIX (DL3): Jika sapi sudah 100% bahagia atau tidak ada sapi standar, berikan Grass sebagai reward
-			var inv domain.Inventory
-			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-				Where("user_id = ?", uid).First(&inv).Error; err != nil {
-				return errors.New("Inventory tidak ditemukan")
-			}
-			inv.Grass += 5
-			if err := tx.Save(&inv).Error; err != nil {
-				return err
-			}
+			grassReward := decimal.NewFromInt(5)
+			entry.Legs = append(entry.Legs,
+				ledger.Leg{Account: ledger.System(domain.BucketMint, domain.CurrencyGrass), Amount: grassReward.Neg()},
+				ledger.Leg{Account: ledger.User(uid, domain.CurrencyGrass), Amount: grassReward},
+			)
 		}
 
-		return nil
+		_, err := ledger.Post(tx, entry)
+		return err
 	})
 }
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -25,6 +27,8 @@ func NewAdminUsecase(db *gorm.DB) *AdminUsecase {
 // Admin balance is not deducted — items are minted/created.
 func (uc *AdminUsecase) TransferItem(ctx context.Context, adminID uuid.UUID, targetWallet string, itemType string, amount decimal.Decimal) error {
 	targetWallet = strings.ToLower(strings.TrimSpace(targetWallet))
+	// Presisi ledger (8 desimal), agar budget emisi yang dipakai sama dengan jumlah yang diposting
+	amount = amount.Truncate(8)
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return errors.New("amount must be greater than 0")
 	}
@@ -36,34 +40,59 @@ func (uc *AdminUsecase) TransferItem(ctx context.Context, adminID uuid.UUID, tar
 			return fmt.Errorf("target user not found: %s", targetWallet)
 		}
 
-		switch strings.ToUpper(itemType) {
+		itemType = strings.ToUpper(itemType)
+		refID := fmt.Sprintf("admin-transfer-%s-%s-%s", adminID.String()[:8], target.ID.String()[:8], uuid.NewString()[:8])
+
+		var currency string
+		switch itemType {
 		case "GOLD":
-			tx.Model(&target).Update("gold_balance", gorm.Expr("gold_balance + ?", amount))
+			currency = domain.CurrencyGold
 		case "USDT":
-			tx.Model(&target).Update("usdt_balance", gorm.Expr("usdt_balance + ?", amount))
+			currency = domain.CurrencyUSDT
 		case "COW_TOKEN":
-			tx.Model(&target).Update("points", gorm.Expr("points + ?", amount))
-		case "GRASS":
-			tx.Model(&domain.Inventory{}).Where("user_id = ?", target.ID).Update("grass", gorm.Expr("grass + ?", amount.IntPart()))
-		case "MILK":
-			tx.Model(&domain.Inventory{}).Where("user_id = ?", target.ID).Update("milk", gorm.Expr("milk + ?", amount.IntPart()))
+			currency = domain.CurrencyCOW
+			// Transfer admin juga memakai budget emisi COW (ditolak jika melebihi sisa hari ini)
+			economy, err := activeEconomyConfig(tx, time.Now())
+			if err != nil {
+				return err
+			}
+			emission, err := lockCOWEmission(tx, economy.Params, time.Now())
+			if err != nil {
+				return err
+			}
+			if err := emission.Consume(amount); err != nil {
+				return fmt.Errorf("COW emission budget exceeded: %s remaining today", emission.Remaining())
+			}
+		case "GRASS", "MILK":
+			currency = itemType
+			amount = decimal.NewFromInt(amount.IntPart())
 		case "LAND":
-			tx.Model(&domain.Inventory{}).Where("user_id = ?", target.ID).Update("land_slots", gorm.Expr("land_slots + ?", amount.IntPart()))
+			// Slot lahan bukan currency, tetap dicatat sebagai TxLog biasa
+			if err := tx.Model(&domain.Inventory{}).Where("user_id = ?", target.ID).
+				Update("land_slots", gorm.Expr("land_slots + ?", amount.IntPart())).Error; err != nil {
+				return err
+			}
+			return tx.Create(&domain.TxLog{
+				UserID:      target.ID,
+				Type:        "ADMIN_TRANSFER_LAND",
+				Amount:      amount,
+				Currency:    "LAND",
+				Status:      domain.TxSuccess,
+				ReferenceID: &refID,
+			}).Error
 		default:
 			return fmt.Errorf("unknown item type: %s", itemType)
 		}
 
-		// Log the transaction
-		refID := fmt.Sprintf("admin-transfer-%s-%s-%s", adminID.String()[:8], target.ID.String()[:8], uuid.NewString()[:8])
-		txLog := domain.TxLog{
-			UserID:      target.ID,
-			Type:        fmt.Sprintf("ADMIN_TRANSFER_%s", strings.ToUpper(itemType)),
-			Amount:      amount,
-			Currency:    strings.ToUpper(itemType),
-			Status:      domain.TxSuccess,
-			ReferenceID: &refID,
-		}
-		return tx.Create(&txLog).Error
+		// Item dicetak dari akun SYSTEM:ADMIN, ledger menulis TxLog untuk target
+		entry := ledger.Transfer(fmt.Sprintf("ADMIN_TRANSFER_%s", itemType),
+			ledger.System(domain.BucketAdmin, currency),
+			ledger.User(target.ID, currency),
+			amount)
+		entry.ReferenceID = &refID
+		entry.Legs[1].ReferenceID = &refID
+		_, err := ledger.Post(tx, entry)
+		return err
 	})
 }
 
@@ -0,0 +1,381 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ammFeeDenominator = 10000
+	ammMaxFeeBps      = 1000 // 10%
+)
+
+// defaultAMMPools adalah likuiditas awal yang menyamai kurs tetap lama
+// (100 Gold = 1 COW, 10.000 Gold = 1 USDT) dengan fee 0,3%.
+var defaultAMMPools = []domain.AMMPool{
+	{
+		BaseCurrency:  domain.CurrencyGold,
+		QuoteCurrency: domain.CurrencyCOW,
+		BaseReserve:   decimal.NewFromInt(1000000),
+		QuoteReserve:  decimal.NewFromInt(10000),
+		FeeBps:        30,
+	},
+	{
+		BaseCurrency:  domain.CurrencyGold,
+		QuoteCurrency: domain.CurrencyUSDT,
+		BaseReserve:   decimal.NewFromInt(10000000),
+		QuoteReserve:  decimal.NewFromInt(1000),
+		FeeBps:        30,
+	},
+}
+
+// AMMUsecase mengelola pool constant-product (x*y=k) yang menggantikan kurs tetap Gold -> COW/USDT.
+// Fee tetap di pool sehingga k tumbuh setiap swap.
+type AMMUsecase struct {
+	db *gorm.DB
+}
+
+func NewAMMUsecase(db *gorm.DB) *AMMUsecase {
+	return &AMMUsecase{db: db}
+}
+
+// SwapQuote adalah hasil perhitungan swap terhadap reserve saat ini.
+type SwapQuote struct {
+	InCurrency     string          `json:"in_currency"`
+	OutCurrency    string          `json:"out_currency"`
+	AmountIn       decimal.Decimal `json:"amount_in"`
+	Fee            decimal.Decimal `json:"fee"`
+	AmountOut      decimal.Decimal `json:"amount_out"`
+	SpotPrice      decimal.Decimal `json:"spot_price"`      // Output per 1 input sebelum swap
+	ExecutionPrice decimal.Decimal `json:"execution_price"` // Output per 1 input yang didapat
+	PriceImpact    decimal.Decimal `json:"price_impact"`    // 0.01 = 1%
+
+	// Hanya untuk swap ke COW: input diperkecil jika output melebihi sisa budget emisi
+	EmissionRemaining *decimal.Decimal `json:"emission_remaining,omitempty"`
+	ProRated          bool             `json:"pro_rated,omitempty"`
+}
+
+// quoteSwap menghitung output untuk `amountIn` base (Gold) yang masuk ke pool.
+// Output dibulatkan ke bawah agar pembulatan selalu menguntungkan pool.
+func quoteSwap(pool *domain.AMMPool, amountIn decimal.Decimal) (*SwapQuote, error) {
+	if !pool.BaseReserve.IsPositive() || !pool.QuoteReserve.IsPositive() {
+		return nil, errors.New("Likuiditas pool kosong")
+	}
+
+	fee := amountIn.Mul(decimal.NewFromInt(int64(pool.FeeBps))).Div(decimal.NewFromInt(ammFeeDenominator)).Truncate(8)
+	inAfterFee := amountIn.Sub(fee)
+
+	// out = y * dx / (x + dx)
+	amountOut := pool.QuoteReserve.Mul(inAfterFee).DivRound(pool.BaseReserve.Add(inAfterFee), 16).Truncate(8)
+	if !amountOut.IsPositive() {
+		return nil, errors.New("Jumlah terlalu kecil untuk ditukar")
+	}
+	if amountOut.GreaterThanOrEqual(pool.QuoteReserve) {
+		return nil, errors.New("Likuiditas pool tidak mencukupi")
+	}
+
+	spot := pool.QuoteReserve.DivRound(pool.BaseReserve, 16)
+	execution := amountOut.DivRound(amountIn, 16)
+	return &SwapQuote{
+		InCurrency:     pool.BaseCurrency,
+		OutCurrency:    pool.QuoteCurrency,
+		AmountIn:       amountIn,
+		Fee:            fee,
+		AmountOut:      amountOut,
+		SpotPrice:      spot,
+		ExecutionPrice: execution,
+		PriceImpact:    decimal.NewFromInt(1).Sub(execution.DivRound(spot, 16)).Round(6),
+	}, nil
+}
+
+// prorateForEmission memperkecil input swap agar output COW tidak melebihi sisa budget emisi.
+// Gold yang tidak terpakai tetap di saldo user.
+func prorateForEmission(pool *domain.AMMPool, quote *SwapQuote, remaining decimal.Decimal) (*SwapQuote, error) {
+	quote.EmissionRemaining = &remaining
+	if quote.AmountOut.LessThanOrEqual(remaining) {
+		return quote, nil
+	}
+	if !remaining.IsPositive() {
+		return nil, ErrEmissionExhausted
+	}
+
+	amountIn := quote.AmountIn.Mul(remaining).DivRound(quote.AmountOut, 16).Truncate(8)
+	prorated, err := quoteSwap(pool, amountIn)
+	if err != nil {
+		return nil, ErrEmissionExhausted
+	}
+	// Kurva cekung: output bisa sedikit di atas sisa budget, kelebihannya tetap di pool
+	if prorated.AmountOut.GreaterThan(remaining) {
+		prorated.AmountOut = remaining
+		prorated.ExecutionPrice = remaining.DivRound(amountIn, 16)
+	}
+	prorated.EmissionRemaining = &remaining
+	prorated.ProRated = true
+	return prorated, nil
+}
+
+// SeedPools membuat pool default yang belum ada. Likuiditas awal diambil dari liquiditySource, sehingga pool
+// yang butuh COW baru dibuat setelah treasury LP_BUYBACK cukup (dicoba ulang setiap startup).
+func (uc *AMMUsecase) SeedPools(ctx context.Context) {
+	for _, def := range defaultAMMPools {
+		pool := def
+		err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&domain.AMMPool{}).Where("base_currency = ? AND quote_currency = ?",
+				pool.BaseCurrency, pool.QuoteCurrency).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			if err := tx.Create(&pool).Error; err != nil {
+				return err
+			}
+			if err := postLiquidity(tx, &pool, pool.BaseReserve, pool.QuoteReserve); err != nil {
+				return err
+			}
+			log.Printf("[SEED] AMM pool %s/%s created", pool.BaseCurrency, pool.QuoteCurrency)
+			return nil
+		})
+		if err != nil {
+			log.Printf("[SEED] Failed to create AMM pool %s/%s: %v", pool.BaseCurrency, pool.QuoteCurrency, err)
+		}
+	}
+}
+
+// liquiditySource adalah akun asal likuiditas pool. COW tidak boleh dicetak di luar budget emisi, jadi
+// diambil dari saldo treasury LP_BUYBACK yang memang dialokasikan untuk likuiditas; currency lain
+// dicetak dari SYSTEM:ADMIN.
+func liquiditySource(currency string) ledger.Account {
+	if currency == domain.CurrencyCOW {
+		return ledger.Treasury(domain.BucketLPBuyback, currency)
+	}
+	return ledger.System(domain.BucketAdmin, currency)
+}
+
+// postLiquidity membukukan penambahan reserve dari liquiditySource ke akun treasury pool.
+func postLiquidity(tx *gorm.DB, pool *domain.AMMPool, baseAmount, quoteAmount decimal.Decimal) error {
+	_, err := ledger.Post(tx, ledger.Entry{
+		Type: "AMM_LIQUIDITY",
+		Legs: []ledger.Leg{
+			{Account: liquiditySource(pool.BaseCurrency), Amount: baseAmount.Neg()},
+			{Account: ledger.Treasury(pool.LedgerBucket(), pool.BaseCurrency), Amount: baseAmount},
+			{Account: liquiditySource(pool.QuoteCurrency), Amount: quoteAmount.Neg()},
+			{Account: ledger.Treasury(pool.LedgerBucket(), pool.QuoteCurrency), Amount: quoteAmount},
+		},
+	})
+	if errors.Is(err, ledger.ErrInsufficientFunds) {
+		return fmt.Errorf("insufficient %s treasury balance for pool liquidity", domain.BucketLPBuyback)
+	}
+	return err
+}
+
+func findGoldPool(tx *gorm.DB, target string) (*domain.AMMPool, error) {
+	var pool domain.AMMPool
+	if err := tx.Where("base_currency = ? AND quote_currency = ?", domain.CurrencyGold, target).
+		First(&pool).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("Invalid target currency")
+		}
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// QuoteGoldSwap menghitung hasil swap Gold -> target tanpa mengubah reserve.
+func (uc *AMMUsecase) QuoteGoldSwap(ctx context.Context, goldAmount decimal.Decimal, target string) (*SwapQuote, error) {
+	if goldAmount.LessThanOrEqual(decimal.Zero) {
+		return nil, errors.New("Amount must be positive")
+	}
+
+	db := uc.db.WithContext(ctx)
+	pool, err := findGoldPool(db, target)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := quoteSwap(pool, goldAmount.Truncate(8))
+	if err != nil || target != domain.CurrencyCOW {
+		return quote, err
+	}
+
+	status, err := NewEmissionUsecase(uc.db).Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return prorateForEmission(pool, quote, status.Remaining)
+}
+
+// SwapGoldToTokens menukar Gold ke COW/USDT melalui pool AMM.
+// minOut > 0 membatalkan swap jika output di bawah nilai tersebut (proteksi slippage).
+// Swap ke COW dibatasi budget emisi harian/lifetime; jika tersisa sebagian, swap di-pro-rata.
+func (uc *AMMUsecase) SwapGoldToTokens(ctx context.Context, userID uuid.UUID, goldAmount decimal.Decimal, target string, minOut decimal.Decimal) (*domain.AMMSwap, error) {
+	if goldAmount.LessThanOrEqual(decimal.Zero) {
+		return nil, errors.New("Amount must be positive")
+	}
+	if minOut.IsNegative() {
+		return nil, errors.New("min_out cannot be negative")
+	}
+	goldAmount = goldAmount.Truncate(8)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	var swap domain.AMMSwap
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		// Kunci pool lebih dulu: semua swap di pool yang sama berjalan berurutan terhadap reserve terbaru
+		pool, err := findGoldPool(tx.Clauses(clause.Locking{Strength: "UPDATE"}), target)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		economy, err := activeEconomyConfig(tx, now)
+		if err != nil {
+			return err
+		}
+		var emission *cowEmission
+		if target == domain.CurrencyCOW {
+			if emission, err = lockCOWEmission(tx, economy.Params, now); err != nil {
+				return err
+			}
+		}
+
+		var user domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
+			return errors.New("User not found")
+		}
+		if user.GoldBalance.LessThan(goldAmount) {
+			return errors.New("Insufficient Gold balance")
+		}
+
+		quote, err := quoteSwap(pool, goldAmount)
+		if err != nil {
+			return err
+		}
+		if emission != nil {
+			if quote, err = prorateForEmission(pool, quote, emission.Remaining()); err != nil {
+				return err
+			}
+		}
+		if quote.AmountOut.LessThan(minOut) {
+			return fmt.Errorf("Slippage terlalu besar: output %s %s di bawah min_out %s", quote.AmountOut, target, minOut)
+		}
+
+		if emission != nil {
+			if err := emission.Consume(quote.AmountOut); err != nil {
+				return err
+			}
+		}
+
+		entry, err := ledger.Post(tx, ledger.Entry{
+			Type:          "GOLD_SWAP",
+			ConfigVersion: &economy.Version,
+			Legs: []ledger.Leg{
+				{Account: ledger.User(userID, domain.CurrencyGold), Amount: quote.AmountIn.Neg()},
+				{Account: ledger.Treasury(pool.LedgerBucket(), domain.CurrencyGold), Amount: quote.AmountIn},
+				{Account: ledger.Treasury(pool.LedgerBucket(), target), Amount: quote.AmountOut.Neg()},
+				{Account: ledger.User(userID, target), Amount: quote.AmountOut},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		pool.BaseReserve = pool.BaseReserve.Add(quote.AmountIn)
+		pool.QuoteReserve = pool.QuoteReserve.Sub(quote.AmountOut)
+		if err := tx.Model(pool).Updates(map[string]interface{}{
+			"base_reserve":  pool.BaseReserve,
+			"quote_reserve": pool.QuoteReserve,
+		}).Error; err != nil {
+			return err
+		}
+
+		swap = domain.AMMSwap{
+			PoolID:            pool.ID,
+			UserID:            userID,
+			InCurrency:        domain.CurrencyGold,
+			AmountIn:          quote.AmountIn,
+			OutCurrency:       target,
+			AmountOut:         quote.AmountOut,
+			Fee:               quote.Fee,
+			BaseReserveAfter:  pool.BaseReserve,
+			QuoteReserveAfter: pool.QuoteReserve,
+			EntryID:           entry.ID,
+		}
+		return tx.Create(&swap).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &swap, nil
+}
+
+// ListPools mengembalikan semua pool beserta reserve-nya.
+func (uc *AMMUsecase) ListPools(ctx context.Context) ([]domain.AMMPool, error) {
+	var pools []domain.AMMPool
+	if err := uc.db.WithContext(ctx).Order("base_currency, quote_currency").Find(&pools).Error; err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// UpdateFee mengubah fee swap sebuah pool (basis point).
+func (uc *AMMUsecase) UpdateFee(ctx context.Context, poolID uuid.UUID, feeBps int) (*domain.AMMPool, error) {
+	if feeBps < 0 || feeBps > ammMaxFeeBps {
+		return nil, fmt.Errorf("fee_bps must be between 0 and %d", ammMaxFeeBps)
+	}
+
+	var pool domain.AMMPool
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", poolID).First(&pool).Error; err != nil {
+			return errors.New("pool not found")
+		}
+		pool.FeeBps = feeBps
+		return tx.Model(&pool).Update("fee_bps", feeBps).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// AddLiquidity menambah reserve pool. Rasio yang berbeda dari reserve saat ini akan menggeser harga.
+func (uc *AMMUsecase) AddLiquidity(ctx context.Context, poolID uuid.UUID, baseAmount, quoteAmount decimal.Decimal) (*domain.AMMPool, error) {
+	if baseAmount.IsNegative() || quoteAmount.IsNegative() || (baseAmount.IsZero() && quoteAmount.IsZero()) {
+		return nil, errors.New("amounts must be non-negative and not both zero")
+	}
+	baseAmount, quoteAmount = baseAmount.Truncate(8), quoteAmount.Truncate(8)
+
+	var pool domain.AMMPool
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", poolID).First(&pool).Error; err != nil {
+			return errors.New("pool not found")
+		}
+		if err := postLiquidity(tx, &pool, baseAmount, quoteAmount); err != nil {
+			return err
+		}
+
+		pool.BaseReserve = pool.BaseReserve.Add(baseAmount)
+		pool.QuoteReserve = pool.QuoteReserve.Add(quoteAmount)
+		return tx.Model(&pool).Updates(map[string]interface{}{
+			"base_reserve":  pool.BaseReserve,
+			"quote_reserve": pool.QuoteReserve,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
@@ -0,0 +1,709 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+	"cashcowvalley/backend/pkg/realtime"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	minAuctionDuration = 10 * time.Minute
+	maxAuctionDuration = 7 * 24 * time.Hour
+
+	// Penawaran di menit-menit terakhir memperpanjang lelang English (anti-sniping)
+	auctionSnipeWindow = 2 * time.Minute
+
+	auctionSettleInterval  = 15 * time.Second
+	auctionSettleBatchSize = 100
+)
+
+type AuctionUsecase struct {
+	db *gorm.DB
+}
+
+func NewAuctionUsecase(db *gorm.DB) *AuctionUsecase {
+	return &AuctionUsecase{db: db}
+}
+
+// CreateAuctionInput adalah parameter lelang baru. Harga adalah harga total satu lot.
+type CreateAuctionInput struct {
+	Kind         string
+	ItemType     string
+	Quantity     int
+	CowID        *uuid.UUID
+	StartPrice   decimal.Decimal
+	FloorPrice   decimal.Decimal // Dutch
+	MinIncrement decimal.Decimal // English, default 0.01
+	Duration     time.Duration
+}
+
+type AuctionDetail struct {
+	Auction domain.Auction      `json:"auction"`
+	Bids    []domain.AuctionBid `json:"bids"`
+}
+
+// validateAuctionPrice memvalidasi harga total lot lelang.
+func validateAuctionPrice(price decimal.Decimal) error {
+	minPrice := decimal.NewFromFloat(0.01)
+	maxPrice := decimal.NewFromInt(1000000)
+	if price.LessThan(minPrice) || price.GreaterThan(maxPrice) {
+		return errors.New("Harga lelang harus antara 0.01 dan 1000000 USDT")
+	}
+	if !price.Equal(price.Truncate(8)) {
+		return errors.New("Harga lelang maksimal 8 angka desimal")
+	}
+	return nil
+}
+
+func (in *CreateAuctionInput) validate() error {
+	switch in.Kind {
+	case domain.AuctionEnglish:
+		if in.MinIncrement.IsZero() {
+			in.MinIncrement = decimal.NewFromFloat(0.01)
+		}
+		if err := validateAuctionPrice(in.MinIncrement); err != nil {
+			return errors.New("Kenaikan minimum tidak valid")
+		}
+	case domain.AuctionDutch:
+		if err := validateAuctionPrice(in.FloorPrice); err != nil {
+			return err
+		}
+		if !in.FloorPrice.LessThan(in.StartPrice) {
+			return errors.New("Harga terendah lelang Dutch harus di bawah harga awal")
+		}
+	default:
+		return errors.New("Jenis lelang tidak valid, hanya ENGLISH atau DUTCH")
+	}
+
+	switch in.ItemType {
+	case domain.CurrencyGrass, domain.CurrencyMilk:
+		if in.Quantity <= 0 {
+			return errors.New("Jumlah item harus lebih dari 0")
+		}
+	case domain.ItemCattle:
+		if in.CowID == nil {
+			return errors.New("cow_id wajib diisi untuk lelang sapi")
+		}
+		in.Quantity = 1
+	default:
+		return errors.New("Tipe item tidak valid, hanya GRASS, MILK atau CATTLE")
+	}
+
+	if in.Duration < minAuctionDuration || in.Duration > maxAuctionDuration {
+		return errors.New("Durasi lelang harus antara 10 menit dan 7 hari")
+	}
+	return validateAuctionPrice(in.StartPrice)
+}
+
+// CreateAuction membuka lelang dan memindahkan item (atau mengunci sapi) ke escrow marketplace.
+func (uc *AuctionUsecase) CreateAuction(ctx context.Context, sellerID uuid.UUID, in CreateAuctionInput) (*domain.Auction, error) {
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+
+	// Lock yang sama dengan SellItem: satu operasi listing per seller dalam satu waktu
+	lockKey := "market_sell:" + sellerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return nil, errors.New("Proses listing sedang berjalan...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	auction := domain.Auction{
+		SellerID:         sellerID,
+		Kind:             in.Kind,
+		ItemType:         in.ItemType,
+		Quantity:         in.Quantity,
+		CowID:            in.CowID,
+		StartPriceUSDT:   in.StartPrice,
+		FloorPriceUSDT:   in.FloorPrice,
+		MinIncrementUSDT: in.MinIncrement,
+		Status:           domain.AuctionOpen,
+		StartsAt:         now,
+		EndsAt:           now.Add(in.Duration),
+	}
+
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		if in.ItemType == domain.ItemCattle {
+			var cow domain.Cow
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND owner_id = ?", in.CowID, sellerID).First(&cow).Error; err != nil {
+				return errors.New("Sapi tidak ditemukan atau bukan milik Anda")
+			}
+			if cow.IsListed() {
+				return errors.New("Sapi sudah dijual di marketplace")
+			}
+			if !cow.ExpectedLifespan.After(auction.EndsAt) {
+				return errors.New("Masa hidup sapi berakhir sebelum lelang selesai")
+			}
+
+			if err := tx.Create(&auction).Error; err != nil {
+				return errors.New("Gagal membuat lelang")
+			}
+			if err := tx.Model(&cow).Update("listing_id", auction.ID).Error; err != nil {
+				return err
+			}
+
+			refID := "cow_auction:" + auction.ID.String()
+			snapshot := cow.Snapshot(now)
+			auction.Cow = &snapshot
+			return tx.Create(&domain.TxLog{
+				UserID:      sellerID,
+				Type:        "COW_LISTED",
+				Amount:      decimal.NewFromInt(-1),
+				Currency:    domain.ItemCattle,
+				Status:      domain.TxSuccess,
+				ReferenceID: &refID,
+			}).Error
+		}
+
+		if err := tx.Create(&auction).Error; err != nil {
+			return errors.New("Gagal membuat lelang")
+		}
+
+		// Ledger menolak saldo negatif, jadi item yang kurang otomatis menggagalkan lelang
+		entryRef := "auction_create:" + auction.ID.String()
+		entry := ledger.Transfer("AUCTION_CREATE",
+			ledger.User(sellerID, in.ItemType),
+			ledger.Escrow(domain.BucketMarket, in.ItemType),
+			decimal.NewFromInt(int64(in.Quantity)))
+		entry.ReferenceID = &entryRef
+		if _, err := ledger.Post(tx, entry); err != nil {
+			if errors.Is(err, ledger.ErrInsufficientFunds) {
+				return errors.New("Item tidak cukup untuk dilelang")
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	auction.CurrentPriceUSDT = currentAuctionPrice(&auction, now)
+	return &auction, nil
+}
+
+func currentAuctionPrice(a *domain.Auction, now time.Time) decimal.Decimal {
+	if a.Kind == domain.AuctionDutch {
+		return a.DutchPrice(now)
+	}
+	return a.MinNextBid()
+}
+
+// PlaceBid mengajukan penawaran. English: `amount` harus >= MinNextBid, USDT ditahan di escrow dan
+// penawar sebelumnya langsung di-refund. Dutch: `amount` adalah harga maksimum yang mau dibayar;
+// jika harga saat ini <= amount lelang langsung terjual pada harga saat ini.
+func (uc *AuctionUsecase) PlaceBid(ctx context.Context, bidderID, auctionID uuid.UUID, amount decimal.Decimal) (*domain.Auction, error) {
+	if err := validateAuctionPrice(amount); err != nil {
+		return nil, err
+	}
+
+	// Lock yang sama dengan BuyItem: satu pembelian per buyer dalam satu waktu
+	lockKey := "market_buy:" + bidderID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return nil, errors.New("Transaksi pembelian sedang diproses...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	var auction domain.Auction
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", auctionID).First(&auction).Error; err != nil {
+			return errors.New("Lelang tidak ditemukan")
+		}
+		now := time.Now()
+		if !auction.IsLive(now) {
+			return errors.New("Lelang sudah berakhir")
+		}
+		if auction.SellerID == bidderID {
+			return errors.New("Tidak dapat menawar lelang sendiri")
+		}
+
+		if auction.Kind == domain.AuctionDutch {
+			price := auction.DutchPrice(now)
+			if amount.LessThan(price) {
+				return errors.New("Harga lelang saat ini di atas harga maksimum Anda")
+			}
+			return buyDutchAuction(tx, &auction, bidderID, price, now)
+		}
+		return placeEnglishBid(tx, &auction, bidderID, amount, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	auction.CurrentPriceUSDT = currentAuctionPrice(&auction, time.Now())
+	return &auction, nil
+}
+
+func placeEnglishBid(tx *gorm.DB, auction *domain.Auction, bidderID uuid.UUID, amount decimal.Decimal, now time.Time) error {
+	if amount.LessThan(auction.MinNextBid()) {
+		return errors.New("Penawaran minimal " + auction.MinNextBid().String() + " USDT")
+	}
+
+	userIDs := []uuid.UUID{bidderID}
+	if auction.HighestBidderID != nil {
+		userIDs = append(userIDs, *auction.HighestBidderID)
+	}
+	if err := lockUsersSorted(tx, userIDs...); err != nil {
+		return err
+	}
+
+	fees, err := loadMarketFees(tx, now)
+	if err != nil {
+		return err
+	}
+	// Pemenang lelang selalu taker, jadi cadangan yang ditahan bersama bid adalah fee taker
+	bid := domain.AuctionBid{AuctionID: auction.ID, BidderID: bidderID, AmountUSDT: amount, FeeRate: fees.Taker, Status: domain.AuctionBidActive}
+	var prev domain.AuctionBid
+	if auction.HighestBidderID != nil {
+		if err := tx.Where("auction_id = ? AND status = ?", auction.ID, domain.AuctionBidActive).
+			First(&prev).Error; err != nil {
+			return errors.New("Penawaran tertinggi tidak ditemukan")
+		}
+	}
+
+	// Penawar tertinggi yang menaikkan tawarannya cukup menambah selisih dana yang ditahan
+	needed := auctionBidHold(&bid)
+	if auction.HighestBidderID != nil && *auction.HighestBidderID == bidderID {
+		needed = needed.Sub(auctionBidHold(&prev))
+	}
+	var bidder domain.User
+	if err := tx.Where("id = ?", bidderID).First(&bidder).Error; err != nil {
+		return errors.New("User tidak ditemukan")
+	}
+	if bidder.USDTBalance.LessThan(needed) {
+		return errors.New("Saldo USDT tidak mencukupi")
+	}
+
+	if err := tx.Create(&bid).Error; err != nil {
+		return err
+	}
+
+	// Satu leg bersih per akun: menahan `amount` lalu mengembalikan tawaran lama ke akun yang sama akan
+	// gagal di cek saldo negatif per leg jika saldo bidder hanya cukup untuk selisihnya
+	legs := []ledger.Leg{
+		{Account: ledger.User(bidderID, domain.CurrencyUSDT), Amount: needed.Neg()},
+		{Account: ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT), Amount: needed},
+	}
+	if auction.HighestBidderID != nil {
+		if *auction.HighestBidderID != bidderID {
+			legs = append(legs,
+				ledger.Leg{Account: ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT), Amount: auctionBidHold(&prev).Neg()},
+				ledger.Leg{Account: ledger.User(*auction.HighestBidderID, domain.CurrencyUSDT), Amount: auctionBidHold(&prev), TxType: "AUCTION_OUTBID_REFUND"},
+			)
+		}
+		if err := tx.Model(&domain.AuctionBid{}).
+			Where("auction_id = ? AND status = ? AND id <> ?", auction.ID, domain.AuctionBidActive, bid.ID).
+			Update("status", domain.AuctionBidOutbid).Error; err != nil {
+			return err
+		}
+	}
+	entryRef := "auction_bid:" + bid.ID.String()
+	if _, err := ledger.Post(tx, ledger.Entry{Type: "AUCTION_BID", ReferenceID: &entryRef, Legs: legs}); err != nil {
+		return err
+	}
+
+	auction.HighestBidUSDT = amount
+	auction.HighestBidderID = &bidderID
+	if auction.EndsAt.Sub(now) < auctionSnipeWindow {
+		endsAt := now.Add(auctionSnipeWindow)
+		if auction.ItemType == domain.ItemCattle {
+			// EndsAt harus tetap sebelum sapi mati: settlement baru berjalan setelah EndsAt (dengan jeda
+			// worker hingga auctionSettleInterval), jadi sisakan satu jendela anti-sniping sebagai margin
+			var cow domain.Cow
+			if err := tx.Where("id = ?", *auction.CowID).First(&cow).Error; err != nil {
+				return errors.New("Sapi pada lelang ini tidak ditemukan")
+			}
+			if limit := cow.ExpectedLifespan.Add(-auctionSnipeWindow); endsAt.After(limit) {
+				endsAt = limit
+			}
+		}
+		if endsAt.After(auction.EndsAt) {
+			auction.EndsAt = endsAt
+		}
+	}
+	return tx.Model(auction).Updates(map[string]interface{}{
+		"highest_bid_usdt":  auction.HighestBidUSDT,
+		"highest_bidder_id": auction.HighestBidderID,
+		"ends_at":           auction.EndsAt,
+	}).Error
+}
+
+// auctionCowDead bernilai true jika lelang CATTLE berisi sapi yang sudah melewati masa hidupnya pada `now`.
+func auctionCowDead(tx *gorm.DB, auction *domain.Auction, now time.Time) (bool, error) {
+	if auction.ItemType != domain.ItemCattle {
+		return false, nil
+	}
+	var cow domain.Cow
+	if err := tx.Where("id = ?", *auction.CowID).First(&cow).Error; err != nil {
+		return false, errors.New("Sapi pada lelang ini tidak ditemukan")
+	}
+	return !cow.ExpectedLifespan.After(now), nil
+}
+
+func buyDutchAuction(tx *gorm.DB, auction *domain.Auction, buyerID uuid.UUID, price decimal.Decimal, now time.Time) error {
+	if dead, err := auctionCowDead(tx, auction, now); err != nil || dead {
+		if err == nil {
+			err = errors.New("Sapi sudah melewati masa hidupnya")
+		}
+		return err
+	}
+	if err := lockUsersSorted(tx, buyerID, auction.SellerID); err != nil {
+		return err
+	}
+	fees, err := loadMarketFees(tx, now)
+	if err != nil {
+		return err
+	}
+	buyerFee := fees.charge(price, true)
+	var buyer domain.User
+	if err := tx.Where("id = ?", buyerID).First(&buyer).Error; err != nil {
+		return errors.New("User tidak ditemukan")
+	}
+	if buyer.USDTBalance.LessThan(price.Add(buyerFee)) {
+		return errors.New("Saldo USDT tidak mencukupi")
+	}
+
+	if err := tx.Create(&domain.AuctionBid{
+		AuctionID:  auction.ID,
+		BidderID:   buyerID,
+		AmountUSDT: price,
+		FeeRate:    fees.Taker,
+		Status:     domain.AuctionBidWon,
+	}).Error; err != nil {
+		return err
+	}
+
+	payment := []ledger.Leg{
+		{Account: ledger.User(buyerID, domain.CurrencyUSDT), Amount: price.Add(buyerFee).Neg(), TxType: "AUCTION_BUY"},
+	}
+	return settleAuctionSale(tx, fees, auction, buyerID, price, buyerFee, payment, now)
+}
+
+// auctionBidHold adalah USDT yang ditahan escrow untuk sebuah penawaran English: nilai tawaran ditambah
+// cadangan fee taker. Refund outbid melepas nilai yang sama persis dengan yang ditahan.
+func auctionBidHold(bid *domain.AuctionBid) decimal.Decimal {
+	return bid.AmountUSDT.Add(bid.AmountUSDT.Mul(bid.FeeRate).Truncate(8))
+}
+
+// settleAuctionSale membukukan pembayaran pemenang (`payment`, sudah termasuk `buyerFee`), membayar penjual
+// setelah dipotong fee maker, menyerahkan lot ke pemenang dan menutup lelang.
+func settleAuctionSale(tx *gorm.DB, fees *marketFees, auction *domain.Auction, winnerID uuid.UUID, price, buyerFee decimal.Decimal, payment []ledger.Leg, now time.Time) error {
+	sellerFee := fees.charge(price, false)
+	legs := append(payment,
+		ledger.Leg{Account: ledger.User(auction.SellerID, domain.CurrencyUSDT), Amount: price.Sub(sellerFee), TxType: "AUCTION_SALE"},
+	)
+	legs = append(legs, fees.treasuryLegs(buyerFee.Add(sellerFee))...)
+	if auction.ItemType != domain.ItemCattle {
+		items := decimal.NewFromInt(int64(auction.Quantity))
+		legs = append(legs,
+			ledger.Leg{Account: ledger.Escrow(domain.BucketMarket, auction.ItemType), Amount: items.Neg()},
+			ledger.Leg{Account: ledger.User(winnerID, auction.ItemType), Amount: items, TxType: "AUCTION_WIN"},
+		)
+	}
+	entryRef := "auction_settle:" + auction.ID.String()
+	if _, err := ledger.Post(tx, ledger.Entry{Type: "AUCTION_SETTLE", ReferenceID: &entryRef, ConfigVersion: &fees.Version, Legs: legs}); err != nil {
+		return err
+	}
+	if auction.ItemType == domain.ItemCattle {
+		if err := transferListedCow(tx, *auction.CowID, auction.ID, winnerID, "cow_auction_win:"+auction.ID.String()); err != nil {
+			return err
+		}
+	}
+
+	auction.Status = domain.AuctionSettled
+	auction.WinnerID = &winnerID
+	auction.SettledPriceUSDT = &price
+	auction.BuyerFeeUSDT = buyerFee
+	auction.SellerFeeUSDT = sellerFee
+	return tx.Model(auction).Updates(map[string]interface{}{
+		"status":             auction.Status,
+		"winner_id":          auction.WinnerID,
+		"settled_price_usdt": auction.SettledPriceUSDT,
+		"buyer_fee_usdt":     auction.BuyerFeeUSDT,
+		"seller_fee_usdt":    auction.SellerFeeUSDT,
+	}).Error
+}
+
+// refundAuctionWinner mengembalikan seluruh dana yang ditahan penawar tertinggi dan lot ke penjual,
+// lalu menutup lelang sebagai UNSOLD.
+func refundAuctionWinner(tx *gorm.DB, auction *domain.Auction, bid *domain.AuctionBid) error {
+	entryRef := "auction_refund:" + auction.ID.String()
+	entry := ledger.Transfer("AUCTION_REFUND",
+		ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT),
+		ledger.User(bid.BidderID, domain.CurrencyUSDT),
+		auctionBidHold(bid))
+	entry.ReferenceID = &entryRef
+	if _, err := ledger.Post(tx, entry); err != nil {
+		return err
+	}
+	if err := tx.Model(bid).Update("status", domain.AuctionBidRefunded).Error; err != nil {
+		return err
+	}
+	return returnAuctionLot(tx, auction, domain.AuctionUnsold, "AUCTION_UNSOLD")
+}
+
+// returnAuctionLot mengembalikan lot dari escrow ke penjual dan menutup lelang dengan `status`.
+func returnAuctionLot(tx *gorm.DB, auction *domain.Auction, status, txType string) error {
+	entryRef := "auction_return:" + auction.ID.String()
+	if auction.ItemType == domain.ItemCattle {
+		if err := returnListedCow(tx, *auction.CowID, auction.ID, auction.SellerID, entryRef); err != nil {
+			return err
+		}
+	} else {
+		entry := ledger.Transfer(txType,
+			ledger.Escrow(domain.BucketMarket, auction.ItemType),
+			ledger.User(auction.SellerID, auction.ItemType),
+			decimal.NewFromInt(int64(auction.Quantity)))
+		entry.ReferenceID = &entryRef
+		if _, err := ledger.Post(tx, entry); err != nil {
+			return err
+		}
+	}
+
+	auction.Status = status
+	return tx.Model(auction).Update("status", status).Error
+}
+
+// CancelAuction menarik lelang yang belum punya penawaran dan mengembalikan lot ke penjual.
+func (uc *AuctionUsecase) CancelAuction(ctx context.Context, sellerID, auctionID uuid.UUID) error {
+	lockKey := "market_sell:" + sellerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return errors.New("Proses listing sedang berjalan...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	return uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		var auction domain.Auction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", auctionID).First(&auction).Error; err != nil {
+			return errors.New("Lelang tidak ditemukan")
+		}
+		if auction.SellerID != sellerID {
+			return errors.New("Lelang ini bukan milik Anda")
+		}
+		if auction.Status != domain.AuctionOpen {
+			return errors.New("Lelang sudah tidak aktif")
+		}
+		if auction.HighestBidderID != nil {
+			return errors.New("Lelang yang sudah memiliki penawaran tidak dapat dibatalkan")
+		}
+		if err := lockUsersSorted(tx, sellerID); err != nil {
+			return err
+		}
+		return returnAuctionLot(tx, &auction, domain.AuctionCancelled, "AUCTION_CANCEL")
+	})
+}
+
+// ListAuctions mengembalikan lelang yang masih berjalan, yang paling cepat berakhir dulu.
+func (uc *AuctionUsecase) ListAuctions(ctx context.Context) ([]domain.Auction, error) {
+	now := time.Now()
+	var auctions []domain.Auction
+	if err := uc.db.WithContext(ctx).Where("status = ? AND ends_at > ?", domain.AuctionOpen, now).
+		Order("ends_at").Limit(100).Find(&auctions).Error; err != nil {
+		return nil, errors.New("Gagal mengambil data lelang")
+	}
+	if err := attachAuctionCows(uc.db.WithContext(ctx), auctions, now); err != nil {
+		return nil, errors.New("Gagal mengambil data lelang")
+	}
+	return auctions, nil
+}
+
+// GetAuction mengembalikan satu lelang beserta riwayat penawarannya (terbaru dulu).
+func (uc *AuctionUsecase) GetAuction(ctx context.Context, auctionID uuid.UUID) (*AuctionDetail, error) {
+	db := uc.db.WithContext(ctx)
+	var detail AuctionDetail
+	if err := db.Where("id = ?", auctionID).First(&detail.Auction).Error; err != nil {
+		return nil, errors.New("Lelang tidak ditemukan")
+	}
+	auctions := []domain.Auction{detail.Auction}
+	if err := attachAuctionCows(db, auctions, time.Now()); err != nil {
+		return nil, err
+	}
+	detail.Auction = auctions[0]
+
+	if err := db.Where("auction_id = ?", auctionID).Order("created_at DESC").Find(&detail.Bids).Error; err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+func attachAuctionCows(db *gorm.DB, auctions []domain.Auction, now time.Time) error {
+	cowIDs := make([]uuid.UUID, 0)
+	for i := range auctions {
+		auctions[i].CurrentPriceUSDT = currentAuctionPrice(&auctions[i], now)
+		if auctions[i].CowID != nil {
+			cowIDs = append(cowIDs, *auctions[i].CowID)
+		}
+	}
+	if len(cowIDs) == 0 {
+		return nil
+	}
+
+	var cows []domain.Cow
+	if err := db.Where("id IN ?", cowIDs).Find(&cows).Error; err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]domain.CowSnapshot, len(cows))
+	for i := range cows {
+		byID[cows[i].ID] = cows[i].Snapshot(now)
+	}
+	for i := range auctions {
+		if auctions[i].CowID == nil {
+			continue
+		}
+		if snapshot, ok := byID[*auctions[i].CowID]; ok {
+			auctions[i].Cow = &snapshot
+		}
+	}
+	return nil
+}
+
+// SettleDueAuctions menutup lelang yang sudah lewat EndsAt. English dengan penawar: USDT escrow ke
+// penjual dan lot ke pemenang; tanpa penawar (atau Dutch yang tidak laku): lot kembali ke penjual.
+func (uc *AuctionUsecase) SettleDueAuctions(ctx context.Context) (int, error) {
+	var ids []uuid.UUID
+	if err := uc.db.WithContext(ctx).Model(&domain.Auction{}).
+		Where("status = ? AND ends_at <= ?", domain.AuctionOpen, time.Now()).
+		Order("ends_at").Limit(auctionSettleBatchSize).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, id := range ids {
+		ok, err := uc.settleAuction(ctx, id)
+		if err != nil {
+			log.Printf("[AUCTION] Gagal menutup lelang %s: %v", id, err)
+			continue
+		}
+		if ok {
+			settled++
+		}
+	}
+	return settled, nil
+}
+
+func (uc *AuctionUsecase) settleAuction(ctx context.Context, auctionID uuid.UUID) (bool, error) {
+	ctx, events := realtime.WithCollector(ctx)
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	settled := false
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		var auction domain.Auction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", auctionID).First(&auction).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		// Dikunci ulang: penawaran anti-sniping bisa saja memperpanjang EndsAt
+		if auction.Status != domain.AuctionOpen || now.Before(auction.EndsAt) {
+			return nil
+		}
+		settled = true
+
+		if auction.HighestBidderID == nil {
+			if err := lockUsersSorted(tx, auction.SellerID); err != nil {
+				return err
+			}
+			return returnAuctionLot(tx, &auction, domain.AuctionUnsold, "AUCTION_UNSOLD")
+		}
+
+		// Urutan kunci leksikografis yang sama dengan BuyItem untuk mencegah deadlock
+		winnerID := *auction.HighestBidderID
+		if err := lockUsersSorted(tx, auction.SellerID, winnerID); err != nil {
+			return err
+		}
+		var bid domain.AuctionBid
+		if err := tx.Where("auction_id = ? AND status = ?", auction.ID, domain.AuctionBidActive).
+			First(&bid).Error; err != nil {
+			return err
+		}
+
+		// Sapi yang mati sebelum settlement tidak boleh dijual: dana pemenang dikembalikan utuh
+		dead, err := auctionCowDead(tx, &auction, now)
+		if err != nil {
+			return err
+		}
+		if dead {
+			log.Printf("[AUCTION] Sapi lelang %s mati sebelum settlement, dana penawar dikembalikan", auction.ID)
+			return refundAuctionWinner(tx, &auction, &bid)
+		}
+
+		if err := tx.Model(&bid).Update("status", domain.AuctionBidWon).Error; err != nil {
+			return err
+		}
+		fees, err := loadMarketFees(tx, now)
+		if err != nil {
+			return err
+		}
+
+		// Fee taker dibatasi cadangan yang ditahan saat menawar; sisa cadangan dikembalikan ke pemenang
+		price := auction.HighestBidUSDT
+		held := auctionBidHold(&bid)
+		buyerFee := decimal.Min(fees.charge(price, true), held.Sub(price))
+		payment := []ledger.Leg{
+			{Account: ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT), Amount: held.Neg()},
+			{Account: ledger.User(winnerID, domain.CurrencyUSDT), Amount: held.Sub(price).Sub(buyerFee), TxType: "AUCTION_BID_REFUND"},
+		}
+		return settleAuctionSale(tx, fees, &auction, winnerID, price, buyerFee, payment, now)
+	})
+	if err == nil {
+		flushRealtime(ctx, uc.db, events)
+	}
+	return settled, err
+}
+
+// StartSettlementWorker menjalankan SettleDueAuctions secara berkala. Aman untuk banyak replica:
+// hanya replica yang memegang Redlock yang mengeksekusi pada satu putaran.
+func (uc *AuctionUsecase) StartSettlementWorker(ctx context.Context) {
+	ticker := time.NewTicker(auctionSettleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		lockKey := "auction_settlement"
+		token, acquired := customRedis.AcquireLock(ctx, lockKey, auctionSettleInterval)
+		if !acquired {
+			continue
+		}
+
+		count, err := uc.SettleDueAuctions(ctx)
+		customRedis.ReleaseLock(ctx, lockKey, token)
+		if err != nil {
+			log.Printf("[AUCTION] Gagal menjalankan settlement lelang: %v", err)
+			continue
+		}
+		if count > 0 {
+			log.Printf("[AUCTION] %d lelang ditutup", count)
+		}
+	}
+}
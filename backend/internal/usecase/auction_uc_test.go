@@ -0,0 +1,278 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// openCowAuction membuka lelang English 10 menit atas sapi baru milik seller (masa hidup 1 jam).
+func openCowAuction(t *testing.T, db *gorm.DB, sellerID uuid.UUID) (*domain.Auction, *domain.Cow) {
+	t.Helper()
+	cow := domain.Cow{OwnerID: sellerID, Type: domain.TypeGolden, ExpectedLifespan: time.Now().Add(time.Hour)}
+	if err := db.Create(&cow).Error; err != nil {
+		t.Fatalf("create cow: %v", err)
+	}
+	auction, err := NewAuctionUsecase(db).CreateAuction(context.Background(), sellerID, CreateAuctionInput{
+		Kind:       domain.AuctionEnglish,
+		ItemType:   domain.ItemCattle,
+		CowID:      &cow.ID,
+		StartPrice: decimal.NewFromInt(10),
+		Duration:   10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("create auction: %v", err)
+	}
+	return auction, &cow
+}
+
+func withinSecond(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -time.Second && d < time.Second
+}
+
+func TestAntiSnipeExtensionStaysBeforeCowLifespan(t *testing.T) {
+	cases := []struct {
+		name     string
+		lifespan time.Duration // Sisa masa hidup sapi saat penawaran masuk (lelang berakhir 1 menit lagi)
+		want     func(now, endsAt, lifespan time.Time) time.Time
+	}{
+		{"diperpanjang penuh", time.Hour, func(now, _, _ time.Time) time.Time { return now.Add(auctionSnipeWindow) }},
+		{"dibatasi masa hidup", 3*time.Minute + 30*time.Second, func(_, _, lifespan time.Time) time.Time { return lifespan.Add(-auctionSnipeWindow) }},
+		{"tidak diperpanjang", 2*time.Minute + 30*time.Second, func(_, endsAt, _ time.Time) time.Time { return endsAt }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newMarketTestDB(t)
+			seller, bidder := newTrader(t, db), newTrader(t, db)
+			auction, cow := openCowAuction(t, db, seller)
+
+			now := time.Now()
+			endsAt, lifespan := now.Add(time.Minute), now.Add(tc.lifespan)
+			db.Model(auction).Update("ends_at", endsAt)
+			db.Model(cow).Update("expected_lifespan", lifespan)
+
+			uc := NewAuctionUsecase(db)
+			got, err := uc.PlaceBid(context.Background(), bidder, auction.ID, decimal.NewFromInt(10))
+			if err != nil {
+				t.Fatalf("bid: %v", err)
+			}
+			if want := tc.want(now, endsAt, lifespan); !withinSecond(got.EndsAt, want) {
+				t.Fatalf("EndsAt = %s, harus %s", got.EndsAt, want)
+			}
+			if !got.EndsAt.Before(lifespan) {
+				t.Fatalf("EndsAt %s tidak boleh melewati masa hidup sapi %s", got.EndsAt, lifespan)
+			}
+
+			// Settlement tepat saat EndsAt: geser jam mundur dengan jarak EndsAt ke masa hidup tetap sama
+			shift := time.Until(got.EndsAt) + time.Second
+			db.Model(auction).Update("ends_at", got.EndsAt.Add(-shift))
+			db.Model(cow).Update("expected_lifespan", lifespan.Add(-shift))
+			if settled, err := uc.SettleDueAuctions(context.Background()); err != nil || settled != 1 {
+				t.Fatalf("settle: settled=%d err=%v", settled, err)
+			}
+			db.First(auction, "id = ?", auction.ID)
+			db.First(cow, "id = ?", cow.ID)
+			if auction.Status != domain.AuctionSettled || cow.OwnerID != bidder || cow.ListingID != nil {
+				t.Fatalf("lelang %s, sapi milik %s: harus SETTLED dan pindah ke pemenang", auction.Status, cow.OwnerID)
+			}
+		})
+	}
+}
+
+func TestSettleRefundsWinnerWhenCowDied(t *testing.T) {
+	db := newMarketTestDB(t)
+	seller, bidder := newTrader(t, db), newTrader(t, db)
+	auction, cow := openCowAuction(t, db, seller)
+
+	uc := NewAuctionUsecase(db)
+	if _, err := uc.PlaceBid(context.Background(), bidder, auction.ID, decimal.NewFromInt(10)); err != nil {
+		t.Fatalf("bid: %v", err)
+	}
+	if got := usdtBalance(t, db, bidder); !got.Equal(decimal.RequireFromString("89.8")) {
+		t.Fatalf("saldo bidder = %s, harus 89.8 (10 + cadangan fee taker 2%%)", got)
+	}
+
+	// Sapi mati sebelum worker sempat men-settle lelang
+	past := time.Now().Add(-time.Second)
+	db.Model(auction).Update("ends_at", past)
+	db.Model(cow).Update("expected_lifespan", past)
+	if settled, err := uc.SettleDueAuctions(context.Background()); err != nil || settled != 1 {
+		t.Fatalf("settle: settled=%d err=%v", settled, err)
+	}
+
+	db.First(auction, "id = ?", auction.ID)
+	db.First(cow, "id = ?", cow.ID)
+	if auction.Status != domain.AuctionUnsold || auction.WinnerID != nil {
+		t.Fatalf("lelang = %s winner %v, harus UNSOLD tanpa pemenang", auction.Status, auction.WinnerID)
+	}
+	if cow.OwnerID != seller || cow.ListingID != nil {
+		t.Fatalf("sapi milik %s listing %v, harus kembali ke seller", cow.OwnerID, cow.ListingID)
+	}
+	var bid domain.AuctionBid
+	db.First(&bid, "auction_id = ?", auction.ID)
+	if bid.Status != domain.AuctionBidRefunded {
+		t.Fatalf("status bid = %s, harus REFUNDED", bid.Status)
+	}
+	for id, want := range map[uuid.UUID]int64{bidder: 100, seller: 100} {
+		if got := usdtBalance(t, db, id); !got.Equal(decimal.NewFromInt(want)) {
+			t.Fatalf("saldo %s = %s, harus %d", id, got, want)
+		}
+	}
+	if got := accountBalance(t, db, marketEscrowUSDT); !got.IsZero() {
+		t.Fatalf("escrow USDT = %s, harus 0", got)
+	}
+}
+
+// openGrassAuction membuka lelang 10 GRASS seharga awal 10 USDT.
+func openGrassAuction(t *testing.T, db *gorm.DB, sellerID uuid.UUID, kind string) *domain.Auction {
+	t.Helper()
+	auction, err := NewAuctionUsecase(db).CreateAuction(context.Background(), sellerID, CreateAuctionInput{
+		Kind:       kind,
+		ItemType:   domain.CurrencyGrass,
+		Quantity:   10,
+		StartPrice: decimal.NewFromInt(10),
+		FloorPrice: decimal.NewFromInt(5),
+		Duration:   10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("create auction: %v", err)
+	}
+	return auction
+}
+
+// assertAuctionSettlement memeriksa entry AUCTION_SETTLE seimbang per currency, escrow marketplace
+// kosong kembali, dan saldo akhir pemenang, penjual serta treasury sesuai harga dan fee yang dicatat.
+func assertAuctionSettlement(t *testing.T, db *gorm.DB, auctionID, sellerID, winnerID uuid.UUID) {
+	t.Helper()
+	var auction domain.Auction
+	db.First(&auction, "id = ?", auctionID)
+	if auction.Status != domain.AuctionSettled || auction.WinnerID == nil || *auction.WinnerID != winnerID {
+		t.Fatalf("lelang = %s winner %v, harus SETTLED ke %s", auction.Status, auction.WinnerID, winnerID)
+	}
+	price := *auction.SettledPriceUSDT
+	var entry domain.JournalEntry
+	if err := db.Preload("Postings").First(&entry, "reference_id = ?", "auction_settle:"+auctionID.String()).Error; err != nil {
+		t.Fatalf("entry AUCTION_SETTLE: %v", err)
+	}
+	if entry.ConfigVersion == nil {
+		t.Fatal("entry AUCTION_SETTLE harus mencatat versi konfigurasi fee")
+	}
+	sums := map[string]decimal.Decimal{}
+	for _, p := range entry.Postings {
+		sums[p.Currency] = sums[p.Currency].Add(p.Amount)
+	}
+	for currency, sum := range sums {
+		if !sum.IsZero() {
+			t.Fatalf("posting %s berjumlah %s, harus 0", currency, sum)
+		}
+	}
+
+	fees := &marketFees{Maker: decimal.RequireFromString("0.01"), Taker: decimal.RequireFromString("0.02")} // newMarketTestDB
+	if want := fees.charge(price, false); !auction.SellerFeeUSDT.Equal(want) {
+		t.Fatalf("fee seller = %s, harus %s", auction.SellerFeeUSDT, want)
+	}
+	if want := fees.charge(price, true); !auction.BuyerFeeUSDT.Equal(want) {
+		t.Fatalf("fee buyer = %s, harus %s", auction.BuyerFeeUSDT, want)
+	}
+	hundred := decimal.NewFromInt(100)
+	for account, want := range map[ledger.Account]decimal.Decimal{
+		ledger.User(winnerID, domain.CurrencyUSDT):  hundred.Sub(price).Sub(auction.BuyerFeeUSDT),
+		ledger.User(sellerID, domain.CurrencyUSDT):  hundred.Add(price).Sub(auction.SellerFeeUSDT),
+		ledger.User(winnerID, domain.CurrencyGrass): decimal.NewFromInt(110),
+		ledger.User(sellerID, domain.CurrencyGrass): decimal.NewFromInt(90),
+		marketEscrowUSDT: decimal.Zero,
+		ledger.Escrow(domain.BucketMarket, domain.CurrencyGrass): decimal.Zero,
+	} {
+		if got := accountBalance(t, db, account); !got.Equal(want) {
+			t.Fatalf("saldo %s = %s, harus %s", account.Code(), got, want)
+		}
+	}
+	treasury := decimal.Zero
+	for _, bucket := range []string{domain.BucketLPBuyback, domain.BucketReferralPool, domain.BucketDev} {
+		treasury = treasury.Add(accountBalance(t, db, ledger.Treasury(bucket, domain.CurrencyUSDT)))
+	}
+	if want := auction.BuyerFeeUSDT.Add(auction.SellerFeeUSDT); !treasury.Equal(want) {
+		t.Fatalf("treasury = %s, harus total fee %s", treasury, want)
+	}
+}
+
+func TestEnglishAuctionSettlementReleasesReserve(t *testing.T) {
+	db := newMarketTestDB(t)
+	seller, outbid, winner := newTrader(t, db), newTrader(t, db), newTrader(t, db)
+	auction := openGrassAuction(t, db, seller, domain.AuctionEnglish)
+
+	uc := NewAuctionUsecase(db)
+	ctx := context.Background()
+	if _, err := uc.PlaceBid(ctx, outbid, auction.ID, decimal.NewFromInt(10)); err != nil {
+		t.Fatalf("bid pertama: %v", err)
+	}
+	if _, err := uc.PlaceBid(ctx, winner, auction.ID, decimal.RequireFromString("12.345")); err != nil {
+		t.Fatalf("bid kedua: %v", err)
+	}
+	if got := usdtBalance(t, db, outbid); !got.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("penawar yang kalah = %s, harus di-refund penuh 100", got)
+	}
+	// Nilai tawaran + cadangan fee taker 2%
+	if got := accountBalance(t, db, marketEscrowUSDT); !got.Equal(decimal.RequireFromString("12.5919")) {
+		t.Fatalf("escrow USDT = %s, harus 12.5919", got)
+	}
+
+	db.Model(auction).Update("ends_at", time.Now().Add(-time.Second))
+	if settled, err := uc.SettleDueAuctions(ctx); err != nil || settled != 1 {
+		t.Fatalf("settle: settled=%d err=%v", settled, err)
+	}
+	assertAuctionSettlement(t, db, auction.ID, seller, winner)
+}
+
+func TestEnglishAuctionSettlementRefundsUnusedReserve(t *testing.T) {
+	db := newMarketTestDB(t)
+	seller, winner := newTrader(t, db), newTrader(t, db)
+	auction := openGrassAuction(t, db, seller, domain.AuctionEnglish)
+
+	uc := NewAuctionUsecase(db)
+	ctx := context.Background()
+	if _, err := uc.PlaceBid(ctx, winner, auction.ID, decimal.NewFromInt(10)); err != nil {
+		t.Fatalf("bid: %v", err)
+	}
+	// Fee taker turun setelah penawaran: cadangan 2% hanya terpakai 1%, sisanya kembali ke pemenang
+	if _, err := NewEconomyUsecase(db).Schedule(ctx, uuid.New(),
+		json.RawMessage(`{"market_taker_fee":"0.01"}`), nil, "turunkan fee taker"); err != nil {
+		t.Fatalf("schedule fees: %v", err)
+	}
+	db.Model(auction).Update("ends_at", time.Now().Add(-time.Second))
+	if settled, err := uc.SettleDueAuctions(ctx); err != nil || settled != 1 {
+		t.Fatalf("settle: settled=%d err=%v", settled, err)
+	}
+
+	db.First(auction, "id = ?", auction.ID)
+	if !auction.BuyerFeeUSDT.Equal(decimal.RequireFromString("0.1")) {
+		t.Fatalf("fee buyer = %s, harus 0.1 (tarif saat settlement)", auction.BuyerFeeUSDT)
+	}
+	if got := usdtBalance(t, db, winner); !got.Equal(decimal.RequireFromString("89.9")) {
+		t.Fatalf("saldo pemenang = %s, harus 89.9", got)
+	}
+	if got := accountBalance(t, db, marketEscrowUSDT); !got.IsZero() {
+		t.Fatalf("escrow USDT = %s, cadangan harus dilepas seluruhnya", got)
+	}
+}
+
+func TestDutchAuctionSettlement(t *testing.T) {
+	db := newMarketTestDB(t)
+	seller, buyer := newTrader(t, db), newTrader(t, db)
+	auction := openGrassAuction(t, db, seller, domain.AuctionDutch)
+
+	// Harga maksimum di atas harga awal: terjual pada harga saat ini
+	if _, err := NewAuctionUsecase(db).PlaceBid(context.Background(), buyer, auction.ID, decimal.NewFromInt(10)); err != nil {
+		t.Fatalf("bid: %v", err)
+	}
+	assertAuctionSettlement(t, db, auction.ID, seller, buyer)
+}
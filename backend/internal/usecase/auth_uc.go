@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"cashcowvalley/backend/internal/domain"
+	customRedis "cashcowvalley/backend/pkg/redis"
 	"cashcowvalley/backend/pkg/utils"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -20,6 +21,14 @@ import (
 // DevWalletAddress is the root admin wallet. Hardcoded for security.
 const DevWalletAddress = "0xbB9468c225C35BA3CBe441660EF9dE3a66Eb772A"
 
+// StreamTicketPurpose adalah klaim `purpose` pada tiket stream. Tiket hanya diterima oleh endpoint
+// stream (lewat query, karena EventSource tidak bisa set header) dan ditolak endpoint lain.
+const StreamTicketPurpose = "stream"
+
+// StreamTicketTTL sengaja pendek: tiket ikut tercatat di access log bersama URL-nya.
+// Tiket juga sekali pakai (lihat ConsumeStreamTicket), jadi client meminta tiket baru setiap reconnect.
+const StreamTicketTTL = time.Minute
+
 type AuthUsecase struct {
 	db *gorm.DB
 }
@@ -239,18 +248,55 @@ func (uc *AuthUsecase) LoginOrRegister(ctx context.Context, walletAddress string
 	return generateJWT(user)
 }
 
-func generateJWT(user domain.User) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "super_secret_dev_key_123!" // Fallback for dev
+// IssueStreamTicket membuat tiket sekali pakai berumur StreamTicketTTL untuk membuka stream SSE,
+// sehingga JWT sesi tidak pernah dikirim lewat query string. jti tiket didaftarkan di Redis dan
+// dihapus saat tiket pertama kali dipakai, jadi URL yang bocor lewat access log tidak bisa diputar ulang.
+func (uc *AuthUsecase) IssueStreamTicket(ctx context.Context, userID uuid.UUID) (string, error) {
+	var user domain.User
+	if err := uc.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		return "", errors.New("User tidak ditemukan")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	jti := uuid.NewString()
+	if !customRedis.StoreOnce(ctx, streamTicketKey(jti), StreamTicketTTL) {
+		return "", errors.New("Gagal membuat tiket stream, coba lagi")
+	}
+
+	return signJWT(jwt.MapClaims{
+		"user_id":        user.ID.String(),
+		"wallet_address": user.WalletAddress,
+		"role":           string(user.Role),
+		"purpose":        StreamTicketPurpose,
+		"jti":            jti,
+		"exp":            time.Now().Add(StreamTicketTTL).Unix(),
+	})
+}
+
+// ConsumeStreamTicket menandai tiket stream dengan jti tersebut sudah dipakai. Hanya pemakaian pertama
+// yang mengembalikan true; tiket yang sudah dipakai atau tidak pernah diterbitkan ditolak.
+func ConsumeStreamTicket(ctx context.Context, jti string) bool {
+	return jti != "" && customRedis.ConsumeOnce(ctx, streamTicketKey(jti))
+}
+
+func streamTicketKey(jti string) string {
+	return "stream_ticket:" + jti
+}
+
+func generateJWT(user domain.User) (string, error) {
+	return signJWT(jwt.MapClaims{
 		"user_id":        user.ID.String(),
 		"wallet_address": user.WalletAddress,
 		"role":           string(user.Role),
 		"exp":            time.Now().Add(time.Hour * 24).Unix(),
 	})
+}
+
+func signJWT(claims jwt.MapClaims) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "super_secret_dev_key_123!" // Fallback for dev
+	}
 
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestStreamTicketIsSingleUse(t *testing.T) {
+	db := newTestDB(t)
+	user := domain.User{WalletAddress: "0xa", Nonce: "x"}
+	db.Create(&user)
+	uc := NewAuthUsecase(db)
+	ctx := context.Background()
+
+	ticketJTI := func() string {
+		t.Helper()
+		ticket, err := uc.IssueStreamTicket(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("issue: %v", err)
+		}
+		claims := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(ticket, claims); err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if claims["purpose"] != StreamTicketPurpose {
+			t.Fatalf("purpose = %v, harus %s", claims["purpose"], StreamTicketPurpose)
+		}
+		jti, _ := claims["jti"].(string)
+		return jti
+	}
+
+	first, second := ticketJTI(), ticketJTI()
+	if first == "" || first == second {
+		t.Fatalf("setiap tiket harus punya jti unik: %q, %q", first, second)
+	}
+	if !ConsumeStreamTicket(ctx, first) {
+		t.Fatal("pemakaian pertama harus diterima")
+	}
+	if ConsumeStreamTicket(ctx, first) {
+		t.Fatal("tiket yang sudah dipakai harus ditolak")
+	}
+	// Reconnect memakai tiket baru
+	if !ConsumeStreamTicket(ctx, second) {
+		t.Fatal("tiket baru harus diterima")
+	}
+	if ConsumeStreamTicket(ctx, "") || ConsumeStreamTicket(ctx, "tidak-pernah-diterbitkan") {
+		t.Fatal("tiket tanpa jti atau yang tidak diterbitkan harus ditolak")
+	}
+}
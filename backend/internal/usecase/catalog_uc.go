@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// catalogItemTypes adalah item yang bisa dikirim oleh BuyFromPlatform.
+var catalogItemTypes = map[string]bool{"COW": true, "GRASS": true}
+
+// catalogCurrencies adalah currency pembayaran yang diterima toko platform.
+var catalogCurrencies = map[string]bool{domain.CurrencyUSDT: true, domain.CurrencyCOW: true}
+
+// defaultCatalogItems adalah item yang sebelumnya di-hardcode di BuyFromPlatform (COW dan GRASS).
+// Harga mengikuti harga Gold default (InAppGoldPrices) pada kurs awal pool AMM
+// (100 Gold = 1 COW, 10.000 Gold = 1 USDT). Stok dan batas per user tidak dibatasi seperti sebelumnya.
+var defaultCatalogItems = []CatalogItemInput{
+	{
+		SKU:      "COW",
+		Name:     "Adult Cow (Standard)",
+		ItemType: "COW",
+		Prices: map[string]decimal.Decimal{
+			domain.CurrencyUSDT: decimal.RequireFromString("0.2"),
+			domain.CurrencyCOW:  decimal.NewFromInt(20),
+		},
+	},
+	{
+		SKU:      "GRASS",
+		Name:     "Organic Grass Bundle",
+		ItemType: "GRASS",
+		Prices: map[string]decimal.Decimal{
+			domain.CurrencyUSDT: decimal.RequireFromString("0.001"),
+			domain.CurrencyCOW:  decimal.RequireFromString("0.1"),
+		},
+	},
+}
+
+type CatalogUsecase struct {
+	db *gorm.DB
+}
+
+func NewCatalogUsecase(db *gorm.DB) *CatalogUsecase {
+	return &CatalogUsecase{db: db}
+}
+
+// CatalogItemInput adalah payload admin untuk membuat/mengganti satu item katalog.
+type CatalogItemInput struct {
+	SKU          string                     `json:"sku" binding:"required"`
+	Name         string                     `json:"name" binding:"required"`
+	ItemType     string                     `json:"item_type" binding:"required"`
+	UnitQuantity int                        `json:"unit_quantity"`
+	Stock        *int                       `json:"stock"`
+	PerUserLimit *int                       `json:"per_user_limit"`
+	StartsAt     *time.Time                 `json:"starts_at"`
+	EndsAt       *time.Time                 `json:"ends_at"`
+	IsActive     *bool                      `json:"is_active"`
+	Prices       map[string]decimal.Decimal `json:"prices" binding:"required"`
+}
+
+func (in *CatalogItemInput) normalize() error {
+	in.SKU = strings.ToUpper(strings.TrimSpace(in.SKU))
+	in.ItemType = strings.ToUpper(strings.TrimSpace(in.ItemType))
+	if in.SKU == "" {
+		return errors.New("sku is required")
+	}
+	if !catalogItemTypes[in.ItemType] {
+		return errors.New("item_type must be COW or GRASS")
+	}
+	if in.UnitQuantity == 0 {
+		in.UnitQuantity = 1
+	}
+	if in.UnitQuantity < 0 {
+		return errors.New("unit_quantity must be greater than 0")
+	}
+	if in.Stock != nil && *in.Stock < 0 {
+		return errors.New("stock cannot be negative")
+	}
+	if in.PerUserLimit != nil && *in.PerUserLimit <= 0 {
+		return errors.New("per_user_limit must be greater than 0")
+	}
+	if in.StartsAt != nil && in.EndsAt != nil && !in.EndsAt.After(*in.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if len(in.Prices) == 0 {
+		return errors.New("at least one price is required")
+	}
+	for currency, price := range in.Prices {
+		if !catalogCurrencies[currency] {
+			return errors.New("price currency must be USDT or COW")
+		}
+		if price.LessThanOrEqual(decimal.Zero) {
+			return errors.New("price must be greater than 0")
+		}
+	}
+	return nil
+}
+
+// SeedDefaults membuat item katalog default yang SKU-nya belum ada. Item yang sudah diubah admin
+// tidak pernah ditimpa, sehingga aman dipanggil setiap startup.
+func (uc *CatalogUsecase) SeedDefaults(ctx context.Context) {
+	for _, def := range defaultCatalogItems {
+		var count int64
+		if err := uc.db.WithContext(ctx).Model(&domain.PlatformItem{}).Where("sku = ?", def.SKU).Count(&count).Error; err != nil {
+			log.Printf("[SEED] Error checking catalog item %s: %v", def.SKU, err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+
+		if _, err := uc.CreateItem(ctx, def); err != nil {
+			log.Printf("[SEED] Failed to create catalog item %s: %v", def.SKU, err)
+			continue
+		}
+		log.Printf("[SEED] Catalog item %s created", def.SKU)
+	}
+}
+
+// ListOnSale mengembalikan item aktif yang sale window-nya sedang berjalan.
+func (uc *CatalogUsecase) ListOnSale(ctx context.Context) ([]domain.PlatformItem, error) {
+	now := time.Now()
+	var items []domain.PlatformItem
+	if err := uc.db.WithContext(ctx).Preload("Prices").
+		Where("is_active = ?", true).
+		Where("starts_at IS NULL OR starts_at <= ?", now).
+		Where("ends_at IS NULL OR ends_at > ?", now).
+		Order("sku").Find(&items).Error; err != nil {
+		return nil, errors.New("Gagal mengambil katalog platform")
+	}
+	return items, nil
+}
+
+// ListAll mengembalikan seluruh katalog termasuk item nonaktif / di luar sale window (admin).
+func (uc *CatalogUsecase) ListAll(ctx context.Context) ([]domain.PlatformItem, error) {
+	var items []domain.PlatformItem
+	if err := uc.db.WithContext(ctx).Preload("Prices").Order("sku").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CreateItem menambahkan item baru ke katalog.
+func (uc *CatalogUsecase) CreateItem(ctx context.Context, in CatalogItemInput) (*domain.PlatformItem, error) {
+	if err := in.normalize(); err != nil {
+		return nil, err
+	}
+
+	item := domain.PlatformItem{IsActive: true}
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&domain.PlatformItem{}).Where("sku = ?", in.SKU).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("sku already exists")
+		}
+
+		applyCatalogInput(&item, in)
+		if err := tx.Omit("Prices").Create(&item).Error; err != nil {
+			return err
+		}
+		return replaceCatalogPrices(tx, &item, in.Prices)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdateItem mengganti seluruh atribut dan harga item. Stok tidak boleh di bawah jumlah yang sudah terjual.
+func (uc *CatalogUsecase) UpdateItem(ctx context.Context, itemID uuid.UUID, in CatalogItemInput) (*domain.PlatformItem, error) {
+	if err := in.normalize(); err != nil {
+		return nil, err
+	}
+
+	var item domain.PlatformItem
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Kunci item agar tidak bentrok dengan pembelian yang sedang berjalan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", itemID).First(&item).Error; err != nil {
+			return errors.New("catalog item not found")
+		}
+
+		var count int64
+		if err := tx.Model(&domain.PlatformItem{}).Where("sku = ? AND id <> ?", in.SKU, itemID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("sku already exists")
+		}
+		if in.Stock != nil && *in.Stock < item.Sold {
+			return errors.New("stock cannot be lower than units already sold")
+		}
+
+		applyCatalogInput(&item, in)
+		if err := tx.Model(&item).Select("sku", "name", "item_type", "unit_quantity", "stock",
+			"per_user_limit", "starts_at", "ends_at", "is_active").Updates(&item).Error; err != nil {
+			return err
+		}
+		return replaceCatalogPrices(tx, &item, in.Prices)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func applyCatalogInput(item *domain.PlatformItem, in CatalogItemInput) {
+	item.SKU = in.SKU
+	item.Name = in.Name
+	item.ItemType = in.ItemType
+	item.UnitQuantity = in.UnitQuantity
+	item.Stock = in.Stock
+	item.PerUserLimit = in.PerUserLimit
+	item.StartsAt = in.StartsAt
+	item.EndsAt = in.EndsAt
+	if in.IsActive != nil {
+		item.IsActive = *in.IsActive
+	}
+}
+
+func replaceCatalogPrices(tx *gorm.DB, item *domain.PlatformItem, prices map[string]decimal.Decimal) error {
+	if err := tx.Where("item_id = ?", item.ID).Delete(&domain.PlatformItemPrice{}).Error; err != nil {
+		return err
+	}
+
+	item.Prices = make([]domain.PlatformItemPrice, 0, len(prices))
+	for currency, price := range prices {
+		item.Prices = append(item.Prices, domain.PlatformItemPrice{ItemID: item.ID, Currency: currency, Price: price})
+	}
+	return tx.Create(&item.Prices).Error
+}
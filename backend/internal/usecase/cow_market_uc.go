@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/pkg/realtime"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sapi tidak punya akun ledger, jadi perpindahannya dicatat sebagai TxLog audit (tanpa EntryID)
+// dengan Currency CATTLE dan Amount yang sudah bertanda: COW_LISTED (-1, masuk listing/lelang),
+// COW_BOUGHT (+1, diterima pembeli) dan COW_RETURNED (+1, kembali ke penjual). Tipe-tipe ini tidak ada
+// di legacyTxSign, dan TxLog CATTLE tidak pernah diberi tanda ulang (lihat legacySign) sehingga baris
+// lama bertipe MARKET_BUY pun tidak terbaca sebagai debit.
+
+// SellCow membuat listing untuk satu ekor sapi milik seller. Selama listing aktif sapi terkunci:
+// tidak bisa diberi makan, dipanen, atau di-boost iklan.
+func (uc *MarketUsecase) SellCow(ctx context.Context, sellerID, cowID uuid.UUID, priceUSDT decimal.Decimal, expiresAt *time.Time) (*domain.MarketListing, error) {
+	if err := validateListingPrice(priceUSDT); err != nil {
+		return nil, err
+	}
+	if err := validateListingExpiry(expiresAt); err != nil {
+		return nil, err
+	}
+
+	// Lock yang sama dengan SellItem: satu operasi listing per seller dalam satu waktu
+	lockKey := "market_sell:" + sellerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return nil, errors.New("Proses listing sedang berjalan...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	var listing domain.MarketListing
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		var cow domain.Cow
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND owner_id = ?", cowID, sellerID).First(&cow).Error; err != nil {
+			return errors.New("Sapi tidak ditemukan atau bukan milik Anda")
+		}
+		if cow.IsListed() {
+			return errors.New("Sapi sudah dijual di marketplace")
+		}
+		now := time.Now()
+		if !cow.ExpectedLifespan.After(now) {
+			return errors.New("Sapi sudah melewati masa hidupnya")
+		}
+		// Listing tidak boleh aktif melewati masa hidup sapi: sweeper mengembalikannya tepat saat sapi mati
+		if expiresAt == nil || expiresAt.After(cow.ExpectedLifespan) {
+			expiresAt = &cow.ExpectedLifespan
+		}
+
+		listing = domain.MarketListing{
+			SellerID:          sellerID,
+			ItemType:          domain.ItemCattle,
+			Quantity:          1,
+			RemainingQuantity: 1,
+			UnitPriceUSDT:     priceUSDT,
+			PriceUSDT:         priceUSDT,
+			Status:            domain.ListingOpen,
+			ExpiresAt:         expiresAt,
+			CowID:             &cow.ID,
+		}
+		if err := tx.Create(&listing).Error; err != nil {
+			return errors.New("Gagal membuat listing")
+		}
+
+		if err := tx.Model(&cow).Update("listing_id", listing.ID).Error; err != nil {
+			return err
+		}
+
+		refID := "cow_list:" + listing.ID.String()
+		if err := tx.Create(&domain.TxLog{
+			UserID:      sellerID,
+			Type:        "COW_LISTED",
+			Amount:      decimal.NewFromInt(-1),
+			Currency:    domain.ItemCattle,
+			Status:      domain.TxSuccess,
+			ReferenceID: &refID,
+		}).Error; err != nil {
+			return err
+		}
+
+		snapshot := cow.Snapshot(now)
+		listing.Cow = &snapshot
+		realtime.Touch(ctx, sellerID)
+		realtime.Emit(ctx, realtime.EventListingCreated, "", listing)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+
+// transferListedCow memindahkan sapi yang terkunci oleh listing/lelang `escrowID` ke buyer. Jam panen
+// di-reset agar buyer tidak memanen susu dari periode sapi terkunci di marketplace.
+func transferListedCow(tx *gorm.DB, cowID, escrowID, buyerID uuid.UUID, refID string) error {
+	result := tx.Model(&domain.Cow{}).
+		Where("id = ? AND listing_id = ?", cowID, escrowID).
+		Updates(map[string]interface{}{
+			"owner_id":          buyerID,
+			"listing_id":        nil,
+			"last_harvested_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return errors.New("Sapi pada listing ini tidak ditemukan")
+	}
+	realtime.Touch(tx.Statement.Context, buyerID)
+
+	return tx.Create(&domain.TxLog{
+		UserID:      buyerID,
+		Type:        "COW_BOUGHT",
+		Amount:      decimal.NewFromInt(1),
+		Currency:    domain.ItemCattle,
+		Status:      domain.TxSuccess,
+		ReferenceID: &refID,
+	}).Error
+}
+
+// returnListedCow membuka kunci sapi saat listing/lelang `escrowID` dibatalkan atau berakhir tanpa pembeli.
+// Alasannya terbaca dari prefix refID (pembatalan, expire, lelang tidak laku).
+func returnListedCow(tx *gorm.DB, cowID, escrowID, sellerID uuid.UUID, refID string) error {
+	if err := tx.Model(&domain.Cow{}).
+		Where("id = ? AND listing_id = ?", cowID, escrowID).
+		Updates(map[string]interface{}{
+			"listing_id":        nil,
+			"last_harvested_at": time.Now(),
+		}).Error; err != nil {
+		return err
+	}
+	realtime.Touch(tx.Statement.Context, sellerID)
+
+	return tx.Create(&domain.TxLog{
+		UserID:      sellerID,
+		Type:        "COW_RETURNED",
+		Amount:      decimal.NewFromInt(1),
+		Currency:    domain.ItemCattle,
+		Status:      domain.TxSuccess,
+		ReferenceID: &refID,
+	}).Error
+}
+
+// attachCowSnapshots mengisi ringkasan sapi pada listing CATTLE.
+func attachCowSnapshots(db *gorm.DB, listings []domain.MarketListing) error {
+	cowIDs := make([]uuid.UUID, 0)
+	for _, l := range listings {
+		if l.CowID != nil {
+			cowIDs = append(cowIDs, *l.CowID)
+		}
+	}
+	if len(cowIDs) == 0 {
+		return nil
+	}
+
+	var cows []domain.Cow
+	if err := db.Where("id IN ?", cowIDs).Find(&cows).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	byID := make(map[uuid.UUID]domain.CowSnapshot, len(cows))
+	for i := range cows {
+		byID[cows[i].ID] = cows[i].Snapshot(now)
+	}
+	for i := range listings {
+		if listings[i].CowID == nil {
+			continue
+		}
+		if snapshot, ok := byID[*listings[i].CowID]; ok {
+			listings[i].Cow = &snapshot
+		}
+	}
+	return nil
+}
@@ -0,0 +1,367 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+	"cashcowvalley/backend/pkg/chain"
+	"cashcowvalley/backend/pkg/realtime"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const depositCursorName = "erc20_deposits"
+
+// maxLogRange membatasi rentang blok per eth_getLogs (banyak RPC publik menolak > 5000).
+const maxLogRange = 2000
+
+// DepositToken menjelaskan satu kontrak ERC-20 yang diterima sebagai deposit.
+type DepositToken struct {
+	Currency string
+	Decimals int32
+}
+
+// DepositConfig adalah konfigurasi indexer deposit.
+type DepositConfig struct {
+	Confirmations uint64
+	StartBlock    uint64
+	PollInterval  time.Duration
+	Tokens        map[common.Address]DepositToken
+	Addresses     map[common.Address]bool // Alamat deposit milik platform
+}
+
+// LoadDepositConfig membaca konfigurasi dari ENV:
+// USDT_CONTRACT_ADDRESS, COW_CONTRACT_ADDRESS, USDT_DECIMALS, COW_DECIMALS,
+// DEPOSIT_ADDRESSES (dipisah koma), DEPOSIT_CONFIRMATIONS, DEPOSIT_START_BLOCK, DEPOSIT_POLL_SECONDS.
+func LoadDepositConfig() DepositConfig {
+	cfg := DepositConfig{
+		Confirmations: envUint("DEPOSIT_CONFIRMATIONS", 12),
+		StartBlock:    envUint("DEPOSIT_START_BLOCK", 0),
+		PollInterval:  time.Duration(envUint("DEPOSIT_POLL_SECONDS", 15)) * time.Second,
+		Tokens:        map[common.Address]DepositToken{},
+		Addresses:     map[common.Address]bool{},
+	}
+
+	if addr := os.Getenv("USDT_CONTRACT_ADDRESS"); common.IsHexAddress(addr) {
+		cfg.Tokens[common.HexToAddress(addr)] = DepositToken{Currency: domain.CurrencyUSDT, Decimals: int32(envUint("USDT_DECIMALS", 18))}
+	}
+	if addr := os.Getenv("COW_CONTRACT_ADDRESS"); common.IsHexAddress(addr) {
+		cfg.Tokens[common.HexToAddress(addr)] = DepositToken{Currency: domain.CurrencyCOW, Decimals: int32(envUint("COW_DECIMALS", 18))}
+	}
+	for _, addr := range strings.Split(os.Getenv("DEPOSIT_ADDRESSES"), ",") {
+		if addr = strings.TrimSpace(addr); common.IsHexAddress(addr) {
+			cfg.Addresses[common.HexToAddress(addr)] = true
+		}
+	}
+	return cfg
+}
+
+func envUint(key string, fallback uint64) uint64 {
+	v, err := strconv.ParseUint(os.Getenv(key), 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+// DepositUsecase mengindeks ERC-20 Transfer (USDT & CashCowToken) ke alamat platform
+// dan mengkredit saldo user setelah N konfirmasi. Tx hash + log index dipakai sebagai
+// ReferenceID sehingga satu transfer tidak pernah dikredit dua kali.
+type DepositUsecase struct {
+	db     *gorm.DB
+	client chain.Reader
+	cfg    DepositConfig
+}
+
+// NewDepositUsecase menerima chain.Reader sehingga bisa memakai ethclient asli atau simulated backend.
+// client boleh nil (mode DEV): semua operasi deposit akan ditolak.
+func NewDepositUsecase(db *gorm.DB, client chain.Reader, cfg DepositConfig) *DepositUsecase {
+	return &DepositUsecase{db: db, client: client, cfg: cfg}
+}
+
+func (uc *DepositUsecase) enabled() bool {
+	return uc.client != nil && len(uc.cfg.Tokens) > 0 && len(uc.cfg.Addresses) > 0
+}
+
+// Start menjalankan Scan secara periodik sampai ctx dibatalkan.
+func (uc *DepositUsecase) Start(ctx context.Context) {
+	if !uc.enabled() {
+		log.Println("[DEPOSIT] Watcher tidak aktif (RPC, kontrak token, atau DEPOSIT_ADDRESSES belum dikonfigurasi)")
+		return
+	}
+
+	ticker := time.NewTicker(uc.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Redlock agar hanya satu replica yang mengindeks pada satu waktu
+		lockKey := "deposit_watcher"
+		if token, acquired := customRedis.AcquireLock(ctx, lockKey, uc.cfg.PollInterval+30*time.Second); acquired {
+			if credited, err := uc.Scan(ctx); err != nil {
+				log.Printf("[DEPOSIT] Scan gagal: %v", err)
+			} else if credited > 0 {
+				log.Printf("[DEPOSIT] %d deposit baru dikredit", credited)
+			}
+			customRedis.ReleaseLock(ctx, lockKey, token)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan memproses semua blok yang sudah mencapai jumlah konfirmasi sejak cursor terakhir.
+func (uc *DepositUsecase) Scan(ctx context.Context) (int, error) {
+	if !uc.enabled() {
+		return 0, errors.New("Deposit on-chain belum dikonfigurasi")
+	}
+
+	head, err := uc.client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if head < uc.cfg.Confirmations {
+		return 0, nil
+	}
+	safeBlock := head - uc.cfg.Confirmations
+
+	var cursor domain.ChainCursor
+	err = uc.db.WithContext(ctx).Where("name = ?", depositCursorName).First(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Tanpa DEPOSIT_START_BLOCK, mulai dari blok aman saat ini (tidak mengindeks dari genesis)
+		cursor = domain.ChainCursor{Name: depositCursorName, LastBlock: safeBlock}
+		if uc.cfg.StartBlock > 0 {
+			cursor.LastBlock = uc.cfg.StartBlock - 1
+		}
+		if err := uc.db.WithContext(ctx).Create(&cursor).Error; err != nil {
+			return 0, err
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	credited := 0
+	for from := cursor.LastBlock + 1; from <= safeBlock; from += maxLogRange {
+		to := from + maxLogRange - 1
+		if to > safeBlock {
+			to = safeBlock
+		}
+
+		logs, err := uc.client.FilterLogs(ctx, uc.filterQuery(from, to))
+		if err != nil {
+			return credited, err
+		}
+
+		for _, l := range logs {
+			ok, err := uc.processLog(ctx, l, nil)
+			if err != nil {
+				return credited, err
+			}
+			if ok {
+				credited++
+			}
+		}
+
+		cursor.LastBlock = to
+		if err := uc.db.WithContext(ctx).Save(&cursor).Error; err != nil {
+			return credited, err
+		}
+	}
+
+	return credited, nil
+}
+
+// ConfirmDeposit adalah jalur cepat: user mengirim tx hash setelah transfer,
+// server memverifikasi receipt on-chain dan mengkredit tanpa menunggu Scan berikutnya.
+func (uc *DepositUsecase) ConfirmDeposit(ctx context.Context, userID uuid.UUID, txHash string) (int, error) {
+	if !uc.enabled() {
+		return 0, errors.New("Deposit on-chain belum dikonfigurasi")
+	}
+	if len(txHash) != 66 || !strings.HasPrefix(txHash, "0x") {
+		return 0, errors.New("Format tx hash tidak valid")
+	}
+
+	receipt, err := uc.client.TransactionReceipt(ctx, common.HexToHash(txHash))
+	if err != nil {
+		return 0, errors.New("Transaksi belum ditemukan di blockchain")
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return 0, errors.New("Transaksi on-chain gagal (reverted)")
+	}
+
+	head, err := uc.client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	mined := receipt.BlockNumber.Uint64()
+	if head < mined+uc.cfg.Confirmations {
+		return 0, fmt.Errorf("Menunggu konfirmasi blok (%d/%d)", head-mined, uc.cfg.Confirmations)
+	}
+
+	credited := 0
+	for _, l := range receipt.Logs {
+		ok, err := uc.processLog(ctx, *l, &userID)
+		if err != nil {
+			return credited, err
+		}
+		if ok {
+			credited++
+		}
+	}
+
+	if credited == 0 {
+		return 0, errors.New("Tidak ada transfer baru dari wallet Anda ke alamat deposit di transaksi ini")
+	}
+	return credited, nil
+}
+
+func (uc *DepositUsecase) filterQuery(from, to uint64) ethereum.FilterQuery {
+	tokens := make([]common.Address, 0, len(uc.cfg.Tokens))
+	for addr := range uc.cfg.Tokens {
+		tokens = append(tokens, addr)
+	}
+	recipients := make([]common.Hash, 0, len(uc.cfg.Addresses))
+	for addr := range uc.cfg.Addresses {
+		recipients = append(recipients, common.BytesToHash(addr.Bytes()))
+	}
+
+	return ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: tokens,
+		Topics:    [][]common.Hash{{chain.TransferEventSig}, nil, recipients},
+	}
+}
+
+// processLog mencatat satu Transfer dan mengkredit pemilik wallet pengirim.
+// onlyUser (jalur ConfirmDeposit) membatasi kredit hanya untuk user yang meminta.
+// Mengembalikan false tanpa error jika log bukan deposit atau sudah pernah diproses.
+func (uc *DepositUsecase) processLog(ctx context.Context, l types.Log, onlyUser *uuid.UUID) (bool, error) {
+	if l.Removed {
+		return false, nil
+	}
+	token, ok := uc.cfg.Tokens[l.Address]
+	if !ok {
+		return false, nil
+	}
+	transfer, err := chain.ParseTransferLog(l)
+	if err != nil || !uc.cfg.Addresses[transfer.To] || transfer.Value.Sign() <= 0 {
+		return false, nil
+	}
+
+	// Dipanggil dari scanner maupun request: event dikirim sendiri setelah transaksi deposit commit
+	ctx, events := realtime.WithCollector(ctx)
+	credited := false
+	err = uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var deposit domain.ChainDeposit
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tx_hash = ? AND log_index = ?", transfer.TxHash.Hex(), transfer.LogIndex).
+			First(&deposit).Error
+		isNew := errors.Is(err, gorm.ErrRecordNotFound)
+		if err != nil && !isNew {
+			return err
+		}
+		// Baris yang sudah ada hanya diproses ulang jika pengirimnya belum terdaftar saat dicatat
+		// (mis. user membuat akun setelah transfer), sehingga deposit itu masih bisa dikredit.
+		if !isNew && (deposit.UserID != nil || deposit.Status != domain.TxPending) {
+			return nil
+		}
+		if isNew {
+			deposit = domain.ChainDeposit{
+				TxHash:      transfer.TxHash.Hex(),
+				LogIndex:    transfer.LogIndex,
+				BlockNumber: transfer.BlockNumber,
+				Token:       strings.ToLower(transfer.Token.Hex()),
+				Currency:    token.Currency,
+				FromAddress: strings.ToLower(transfer.From.Hex()),
+				ToAddress:   strings.ToLower(transfer.To.Hex()),
+				Amount:      chain.ToDecimal(transfer.Value, token.Decimals).Truncate(8),
+				Status:      domain.TxPending,
+			}
+		}
+
+		var user domain.User
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("wallet_address = ?", deposit.FromAddress).First(&user).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			if onlyUser != nil && user.ID != *onlyUser {
+				return nil
+			}
+			deposit.UserID = &user.ID
+		} else if onlyUser != nil {
+			return nil
+		}
+
+		if deposit.UserID != nil && deposit.Amount.IsPositive() {
+			ref := fmt.Sprintf("%s:%d", deposit.TxHash, deposit.LogIndex)
+			entryRef := "deposit:" + ref
+			entry := ledger.Transfer("DEPOSIT",
+				ledger.System(domain.BucketExternal, token.Currency),
+				ledger.User(user.ID, token.Currency),
+				deposit.Amount)
+			entry.ReferenceID = &entryRef
+			entry.Legs[1].ReferenceID = &ref
+			if _, err := ledger.Post(tx, entry); err != nil {
+				return err
+			}
+			deposit.Status = domain.TxSuccess
+			credited = true
+		}
+
+		if isNew {
+			return tx.Create(&deposit).Error
+		}
+		if !credited {
+			return nil
+		}
+		return tx.Save(&deposit).Error
+	})
+	if err == nil {
+		flushRealtime(ctx, uc.db, events)
+	}
+	return credited, err
+}
+
+type DepositInfo struct {
+	Addresses     []string              `json:"addresses"`
+	Confirmations uint64                `json:"confirmations"`
+	Deposits      []domain.ChainDeposit `json:"deposits"`
+}
+
+// GetDepositInfo mengembalikan alamat deposit platform dan riwayat deposit user.
+func (uc *DepositUsecase) GetDepositInfo(ctx context.Context, userID uuid.UUID) (*DepositInfo, error) {
+	info := DepositInfo{
+		Addresses:     make([]string, 0, len(uc.cfg.Addresses)),
+		Confirmations: uc.cfg.Confirmations,
+	}
+	for addr := range uc.cfg.Addresses {
+		info.Addresses = append(info.Addresses, addr.Hex())
+	}
+
+	if err := uc.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("block_number DESC").Limit(50).Find(&info.Deposits).Error; err != nil {
+		return nil, errors.New("Gagal mengambil riwayat deposit")
+	}
+	return &info, nil
+}
@@ -0,0 +1,170 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/pkg/chain"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/glebarez/sqlite"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB membuka SQLite in-memory terpisah per test dengan skema yang dibutuhkan ledger.
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	base := []interface{}{&domain.User{}, &domain.Inventory{}, &domain.Cow{}, &domain.TxLog{},
+		&domain.LedgerAccount{}, &domain.JournalEntry{}, &domain.Posting{}}
+	if err := db.AutoMigrate(append(base, models...)...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// transferEmitterCode adalah init code kontrak minimal yang, untuk setiap call transfer(to, value),
+// memancarkan event ERC-20 Transfer(msg.sender, to, value) tanpa menyimpan saldo.
+func transferEmitterCode() []byte {
+	runtime := []byte{0x60, 0x24, 0x35, 0x60, 0x00, 0x52, 0x60, 0x04, 0x35, 0x33, 0x7f} // mstore(0, value); push to, caller
+	runtime = append(runtime, chain.TransferEventSig.Bytes()...)
+	runtime = append(runtime, 0x60, 0x20, 0x60, 0x00, 0xa3, 0x00) // log3(0, 32, sig, caller, to); stop
+	init := []byte{0x60, byte(len(runtime)), 0x80, 0x60, 0x0b, 0x60, 0x00, 0x39, 0x60, 0x00, 0xf3}
+	return append(init, runtime...)
+}
+
+type simChain struct {
+	backend *simulated.Backend
+	client  simulated.Client
+	key     *ecdsa.PrivateKey
+	from    common.Address
+}
+
+func (s *simChain) send(t *testing.T, to *common.Address, data []byte) *types.Receipt {
+	t.Helper()
+	ctx := context.Background()
+	nonce, err := s.client.PendingNonceAt(ctx, s.from)
+	if err != nil {
+		t.Fatalf("nonce: %v", err)
+	}
+	gasPrice, err := s.client.SuggestGasPrice(ctx)
+	if err != nil {
+		t.Fatalf("gas price: %v", err)
+	}
+	chainID, err := s.client.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("chain id: %v", err)
+	}
+	tx, err := types.SignNewTx(s.key, types.LatestSignerForChainID(chainID), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Gas:      200000,
+		GasPrice: new(big.Int).Mul(gasPrice, big.NewInt(2)),
+		Data:     data,
+	})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := s.client.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	s.backend.Commit()
+	receipt, err := s.client.TransactionReceipt(ctx, tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("receipt: %v", err)
+	}
+	return receipt
+}
+
+func (s *simChain) mine(n int) {
+	for i := 0; i < n; i++ {
+		s.backend.Commit()
+	}
+}
+
+func TestDepositConfirmationsAndIdempotentCredit(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, &domain.ChainCursor{}, &domain.ChainDeposit{})
+
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	backend := simulated.NewBackend(types.GenesisAlloc{sender: {Balance: big.NewInt(1e18)}})
+	defer backend.Close()
+	sim := &simChain{backend: backend, client: backend.Client(), key: key, from: sender}
+
+	token := sim.send(t, nil, transferEmitterCode()).ContractAddress
+	platform := common.HexToAddress("0x000000000000000000000000000000000000d3d0")
+	uc := NewDepositUsecase(db, sim.client, DepositConfig{
+		Confirmations: 3,
+		StartBlock:    1,
+		Tokens:        map[common.Address]DepositToken{token: {Currency: domain.CurrencyUSDT, Decimals: 6}},
+		Addresses:     map[common.Address]bool{platform: true},
+	})
+
+	// 5 USDT dari wallet yang belum terdaftar
+	receipt := sim.send(t, &token, chain.PackTransfer(platform, big.NewInt(5_000_000)))
+	txHash := receipt.TxHash.Hex()
+
+	if credited, err := uc.Scan(ctx); err != nil || credited != 0 {
+		t.Fatalf("scan sebelum konfirmasi: credited=%d err=%v", credited, err)
+	}
+	var rows int64
+	db.Model(&domain.ChainDeposit{}).Count(&rows)
+	if rows != 0 {
+		t.Fatalf("deposit dicatat sebelum %d konfirmasi", uc.cfg.Confirmations)
+	}
+
+	sim.mine(3)
+	if credited, err := uc.Scan(ctx); err != nil || credited != 0 {
+		t.Fatalf("scan pengirim tidak dikenal: credited=%d err=%v", credited, err)
+	}
+	var deposit domain.ChainDeposit
+	if err := db.First(&deposit, "tx_hash = ?", txHash).Error; err != nil || deposit.UserID != nil || deposit.Status != domain.TxPending {
+		t.Fatalf("deposit tanpa user harus tercatat PENDING: %+v err=%v", deposit, err)
+	}
+
+	// User mendaftar dengan wallet pengirim setelah deposit tercatat
+	user := domain.User{WalletAddress: strings.ToLower(sender.Hex()), Nonce: "x"}
+	db.Create(&user)
+	db.Create(&domain.Inventory{UserID: user.ID})
+
+	if credited, err := uc.ConfirmDeposit(ctx, user.ID, txHash); err != nil || credited != 1 {
+		t.Fatalf("confirm setelah daftar: credited=%d err=%v", credited, err)
+	}
+	if _, err := uc.ConfirmDeposit(ctx, user.ID, txHash); err == nil {
+		t.Fatal("confirm kedua harus ditolak")
+	}
+
+	// Deposit berikutnya: ConfirmDeposit menunggu konfirmasi, lalu Scan mengkredit tepat sekali
+	receipt = sim.send(t, &token, chain.PackTransfer(platform, big.NewInt(2_500_000)))
+	if _, err := uc.ConfirmDeposit(ctx, user.ID, receipt.TxHash.Hex()); err == nil || !strings.Contains(err.Error(), "konfirmasi") {
+		t.Fatalf("confirm sebelum konfirmasi harus ditolak, err=%v", err)
+	}
+	sim.mine(3)
+	if credited, err := uc.Scan(ctx); err != nil || credited != 1 {
+		t.Fatalf("scan deposit kedua: credited=%d err=%v", credited, err)
+	}
+	if credited, err := uc.Scan(ctx); err != nil || credited != 0 {
+		t.Fatalf("scan ulang tidak boleh mengkredit lagi: credited=%d err=%v", credited, err)
+	}
+	if _, err := uc.ConfirmDeposit(ctx, user.ID, receipt.TxHash.Hex()); err == nil {
+		t.Fatal("confirm deposit yang sudah di-scan harus ditolak")
+	}
+
+	db.First(&user, "id = ?", user.ID)
+	if !user.USDTBalance.Equal(decimal.RequireFromString("7.5")) {
+		t.Fatalf("saldo USDT = %s, harus 7.5", user.USDTBalance)
+	}
+}
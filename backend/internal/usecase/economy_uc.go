@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// inAppGoldItems adalah item yang bisa dikirim oleh BuyInAppItemWithGold.
+var inAppGoldItems = map[string]bool{"GRASS": true, "BABY_COW": true, "COW": true, "LAND": true, "VITAMIN": true}
+
+type EconomyUsecase struct {
+	db *gorm.DB
+}
+
+func NewEconomyUsecase(db *gorm.DB) *EconomyUsecase {
+	return &EconomyUsecase{db: db}
+}
+
+// activeEconomyConfig membaca versi yang berlaku pada `now` memakai transaksi pemanggil,
+// sehingga versi yang dipakai menghitung harga sama dengan versi yang dicatat di TxLog.
+func activeEconomyConfig(tx *gorm.DB, now time.Time) (*domain.EconomyConfig, error) {
+	var cfg domain.EconomyConfig
+	err := tx.Where("effective_at <= ?", now).Order("effective_at DESC, version DESC").First(&cfg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("Konfigurasi ekonomi belum tersedia")
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg.Params.FillDefaults()
+	return &cfg, nil
+}
+
+// SeedDefault membuat versi 1 dari nilai lama yang di-hardcode jika tabel masih kosong.
+func (uc *EconomyUsecase) SeedDefault(ctx context.Context) {
+	var count int64
+	if err := uc.db.WithContext(ctx).Model(&domain.EconomyConfig{}).Count(&count).Error; err != nil {
+		log.Printf("[SEED] Error checking economy config: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	cfg := domain.EconomyConfig{
+		Version:     1,
+		Params:      domain.DefaultEconomyParams(),
+		EffectiveAt: time.Unix(0, 0).UTC(),
+		Note:        "Default parameters",
+	}
+	if err := uc.db.WithContext(ctx).Create(&cfg).Error; err != nil {
+		log.Printf("[SEED] Failed to create economy config: %v", err)
+		return
+	}
+	log.Println("[SEED] Economy config v1 created")
+}
+
+// Active mengembalikan versi konfigurasi yang sedang berlaku.
+func (uc *EconomyUsecase) Active(ctx context.Context) (*domain.EconomyConfig, error) {
+	return activeEconomyConfig(uc.db.WithContext(ctx), time.Now())
+}
+
+// List mengembalikan seluruh versi (terbaru dulu), termasuk yang dijadwalkan di masa depan.
+func (uc *EconomyUsecase) List(ctx context.Context) ([]domain.EconomyConfig, error) {
+	var configs []domain.EconomyConfig
+	if err := uc.db.WithContext(ctx).Order("version DESC").Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// Schedule membuat versi baru dari versi terakhir yang ditimpa dengan `overrides` (JSON parsial
+// EconomyParams) dan berlaku mulai effectiveAt (nil = sekarang).
+func (uc *EconomyUsecase) Schedule(ctx context.Context, adminID uuid.UUID, overrides json.RawMessage, effectiveAt *time.Time, note string) (*domain.EconomyConfig, error) {
+	now := time.Now()
+	effective := now
+	if effectiveAt != nil {
+		if effectiveAt.Before(now.Add(-time.Minute)) {
+			return nil, errors.New("effective_at cannot be in the past")
+		}
+		effective = *effectiveAt
+	}
+
+	var cfg domain.EconomyConfig
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest domain.EconomyConfig
+		base := domain.DefaultEconomyParams()
+		err := tx.Order("version DESC").First(&latest).Error
+		if err == nil {
+			base = latest.Params
+			base.FillDefaults()
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Salin lewat JSON agar map harga versi lama tidak ikut termodifikasi
+		raw, err := json.Marshal(base)
+		if err != nil {
+			return err
+		}
+		var params domain.EconomyParams
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return err
+		}
+		if len(overrides) > 0 {
+			if err := json.Unmarshal(overrides, &params); err != nil {
+				return errors.New("invalid params payload")
+			}
+		}
+		if err := validateEconomyParams(params); err != nil {
+			return err
+		}
+
+		cfg = domain.EconomyConfig{
+			Version:     latest.Version + 1,
+			Params:      params,
+			EffectiveAt: effective,
+			Note:        note,
+			CreatedBy:   &adminID,
+		}
+		return tx.Create(&cfg).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Cancel menghapus versi yang belum berlaku. Versi yang sudah pernah aktif tidak bisa dihapus
+// karena direferensikan oleh TxLog.
+func (uc *EconomyUsecase) Cancel(ctx context.Context, version int) error {
+	result := uc.db.WithContext(ctx).Where("version = ? AND effective_at > ?", version, time.Now()).
+		Delete(&domain.EconomyConfig{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("only versions that are not yet effective can be cancelled")
+	}
+	return nil
+}
+
+func validateEconomyParams(p domain.EconomyParams) error {
+	positives := map[string]decimal.Decimal{
+		"milk_to_gold":           p.MilkToGold,
+		"stake_gold_milk_hourly": p.StakeGoldMilkHourly,
+		"stake_milk_gold_hourly": p.StakeMilkGoldHourly,
+		"cow_daily_emission":     p.COWDailyEmission,
+		"cow_lifetime_emission":  p.COWLifetimeEmission,
+	}
+	for name, v := range positives {
+		if !v.IsPositive() {
+			return fmt.Errorf("%s must be greater than 0", name)
+		}
+	}
+
+	if p.COWHalvingDays <= 0 {
+		return errors.New("cow_halving_days must be greater than 0")
+	}
+
+	for item, price := range p.InAppGoldPrices {
+		if !inAppGoldItems[item] {
+			return fmt.Errorf("unknown in-app item: %s", item)
+		}
+		if !price.IsPositive() {
+			return fmt.Errorf("price of %s must be greater than 0", item)
+		}
+	}
+
+	shares := []decimal.Decimal{p.PlatformLPShare, p.PlatformRefShare, p.PlatformDevShare}
+	total := decimal.Zero
+	for _, share := range shares {
+		if share.IsNegative() {
+			return errors.New("platform shares cannot be negative")
+		}
+		total = total.Add(share)
+	}
+	if !total.Equal(decimal.NewFromInt(1)) {
+		return errors.New("platform shares must add up to 1")
+	}
+
+	for name, fee := range map[string]decimal.Decimal{"market_maker_fee": p.MarketMakerFee, "market_taker_fee": p.MarketTakerFee} {
+		if fee.IsNegative() || fee.GreaterThan(maxMarketFee) {
+			return fmt.Errorf("%s must be between 0 and %s", name, maxMarketFee)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrEmissionExhausted dikembalikan jika budget emisi COW (harian atau lifetime) sudah habis.
+var ErrEmissionExhausted = errors.New("Budget emisi COW sudah habis, coba lagi besok")
+
+// cowEmission adalah budget emisi COW hari ini yang sudah dikunci (FOR UPDATE) di transaksi pemanggil.
+// Semua jalur yang menambah User.Points dari sumber platform (swap AMM, bonus referral COW,
+// transfer admin) wajib Consume sebelum posting ke ledger.
+type cowEmission struct {
+	tx        *gorm.DB
+	epoch     domain.EmissionEpoch
+	remaining decimal.Decimal
+}
+
+// emissionSchedule menghitung era halving dan budget harian untuk hari `now`.
+func emissionSchedule(params domain.EconomyParams, now time.Time) (int, decimal.Decimal) {
+	era := 0
+	if days := int(now.UTC().Sub(params.COWEmissionGenesis).Hours() / 24); days > 0 {
+		era = days / params.COWHalvingDays
+	}
+	budget := params.COWDailyEmission
+	for i := 0; i < era && budget.IsPositive(); i++ {
+		budget = budget.Div(decimal.NewFromInt(2)).Truncate(8)
+	}
+	return era, budget
+}
+
+// lockCOWEmission membuat (jika belum ada) dan mengunci epoch hari ini.
+// Urutan lock: panggil sebelum mengunci baris users agar tidak deadlock dengan jalur lain.
+func lockCOWEmission(tx *gorm.DB, params domain.EconomyParams, now time.Time) (*cowEmission, error) {
+	era, budget := emissionSchedule(params, now)
+	epoch := domain.EmissionEpoch{
+		Day:    now.UTC().Format("2006-01-02"),
+		Era:    era,
+		Budget: budget,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&epoch).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("day = ?", epoch.Day).First(&epoch).Error; err != nil {
+		return nil, err
+	}
+
+	remaining, err := emissionRemaining(tx, params, epoch)
+	if err != nil {
+		return nil, err
+	}
+	return &cowEmission{tx: tx, epoch: epoch, remaining: remaining}, nil
+}
+
+// emissionRemaining adalah min(sisa budget harian, sisa budget lifetime).
+func emissionRemaining(tx *gorm.DB, params domain.EconomyParams, epoch domain.EmissionEpoch) (decimal.Decimal, error) {
+	var lifetime decimal.NullDecimal
+	if err := tx.Model(&domain.EmissionEpoch{}).Select("COALESCE(SUM(emitted), 0)").Scan(&lifetime).Error; err != nil {
+		return decimal.Zero, err
+	}
+
+	remaining := epoch.Budget.Sub(epoch.Emitted)
+	if lifetimeLeft := params.COWLifetimeEmission.Sub(lifetime.Decimal); lifetimeLeft.LessThan(remaining) {
+		remaining = lifetimeLeft
+	}
+	if remaining.IsNegative() {
+		return decimal.Zero, nil
+	}
+	return remaining, nil
+}
+
+// Remaining adalah COW yang masih boleh diemisikan hari ini.
+func (e *cowEmission) Remaining() decimal.Decimal {
+	return e.remaining
+}
+
+// Consume mencatat emisi `amount`. Menolak (tanpa pro-rata) jika melebihi sisa budget.
+func (e *cowEmission) Consume(amount decimal.Decimal) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+	if amount.GreaterThan(e.remaining) {
+		return ErrEmissionExhausted
+	}
+
+	e.epoch.Emitted = e.epoch.Emitted.Add(amount)
+	e.remaining = e.remaining.Sub(amount)
+	return e.tx.Model(&e.epoch).Update("emitted", e.epoch.Emitted).Error
+}
+
+type EmissionUsecase struct {
+	db *gorm.DB
+}
+
+func NewEmissionUsecase(db *gorm.DB) *EmissionUsecase {
+	return &EmissionUsecase{db: db}
+}
+
+type EmissionStatus struct {
+	Day             string          `json:"day"`
+	Era             int             `json:"era"`
+	DailyBudget     decimal.Decimal `json:"daily_budget"`
+	EmittedToday    decimal.Decimal `json:"emitted_today"`
+	Remaining       decimal.Decimal `json:"remaining"`
+	LifetimeBudget  decimal.Decimal `json:"lifetime_budget"`
+	LifetimeEmitted decimal.Decimal `json:"lifetime_emitted"`
+	NextHalvingAt   time.Time       `json:"next_halving_at"`
+	ConfigVersion   int             `json:"config_version"`
+}
+
+// Status mengembalikan kondisi budget emisi hari ini tanpa mengunci apa pun.
+func (uc *EmissionUsecase) Status(ctx context.Context) (*EmissionStatus, error) {
+	db := uc.db.WithContext(ctx)
+	now := time.Now()
+	economy, err := activeEconomyConfig(db, now)
+	if err != nil {
+		return nil, err
+	}
+	params := economy.Params
+
+	era, budget := emissionSchedule(params, now)
+	epoch := domain.EmissionEpoch{Day: now.UTC().Format("2006-01-02"), Era: era, Budget: budget}
+	if err := db.Where("day = ?", epoch.Day).First(&epoch).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var lifetime decimal.NullDecimal
+	if err := db.Model(&domain.EmissionEpoch{}).Select("COALESCE(SUM(emitted), 0)").Scan(&lifetime).Error; err != nil {
+		return nil, err
+	}
+	remaining, err := emissionRemaining(db, params, epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EmissionStatus{
+		Day:             epoch.Day,
+		Era:             epoch.Era,
+		DailyBudget:     epoch.Budget,
+		EmittedToday:    epoch.Emitted,
+		Remaining:       remaining,
+		LifetimeBudget:  params.COWLifetimeEmission,
+		LifetimeEmitted: lifetime.Decimal,
+		NextHalvingAt:   params.COWEmissionGenesis.AddDate(0, 0, (era+1)*params.COWHalvingDays),
+		ConfigVersion:   economy.Version,
+	}, nil
+}
@@ -3,9 +3,11 @@ package usecase
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
 	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
 	customRedis "cashcowvalley/backend/pkg/redis"
 
 	"github.com/google/uuid"
@@ -59,35 +61,24 @@ func (uc *FarmUsecase) FeedCow(ctx context.Context, userID uuid.UUID, cowID uuid
 			return errors.New("Sapi tidak ditemukan atau bukan milik Anda")
 		}
 
-		if cow.Happiness >= 100 {
-			return errors.New("Sapi sudah sangat bahagia (100%)")
-		}
-
-		// Update Data
-		inventory.Grass -= 1
-		if err := tx.Save(&inventory).Error; err != nil {
-			return err
+		// Sapi yang sedang dijual tetap boleh diberi makan: decay lapar terus berjalan selama listing aktif
+		// Sapi yang kenyang dan bahagia tidak perlu makan; sapi lapar selalu boleh diberi makan
+		now := time.Now()
+		if cow.HungerAt(now) == domain.HungerFed && cow.CurrentHappiness(now) >= domain.MaxHappiness {
+			return errors.New("Sapi sudah kenyang dan sangat bahagia (100%)")
 		}
 
-		cow.Happiness += 20
-		if cow.Happiness > 100 {
-			cow.Happiness = 100
-		}
-		now := time.Now()
-		cow.LastFedAt = &now
+		// Update Data (decay lapar disimpan dulu, lalu status lapar di-reset)
+		cow.Feed(now, 20)
 		if err := tx.Save(&cow).Error; err != nil {
 			return err
 		}
 
-		// Audit Trail / TxLog
-		txLog := domain.TxLog{
-			UserID:   userID,
-			Type:     "FEED_COW",
-			Amount:   decimal.NewFromInt(1),
-			Currency: "GRASS",
-			Status:   domain.TxSuccess,
-		}
-		if err := tx.Create(&txLog).Error; err != nil {
+		// 1 Rumput dikonsumsi (ledger memperbarui Inventory dan menulis TxLog FEED_COW)
+		if _, err := ledger.Post(tx, ledger.Transfer("FEED_COW",
+			ledger.User(userID, domain.CurrencyGrass),
+			ledger.System(domain.BucketBurn, domain.CurrencyGrass),
+			decimal.NewFromInt(1))); err != nil {
 			return err
 		}
 
@@ -112,6 +103,12 @@ func (uc *FarmUsecase) GetFarmStatus(ctx context.Context, userID uuid.UUID) (*Fa
 		Order("created_at ASC").Find(&cows).Error; err != nil {
 		return nil, errors.New("Gagal mengambil data sapi")
 	}
+	// Happiness ditampilkan setelah decay lapar hingga saat ini (tidak disimpan)
+	now := time.Now()
+	for i := range cows {
+		cows[i].Happiness = cows[i].CurrentHappiness(now)
+		cows[i].Hunger = cows[i].HungerAt(now)
+	}
 
 	var inventory domain.Inventory
 	if err := uc.db.WithContext(ctx).Where("user_id = ?", userID).
@@ -162,9 +159,10 @@ func (uc *FarmUsecase) HarvestFarm(ctx context.Context, userID uuid.UUID) (int,
 			return errors.New("User tidak ditemukan")
 		}
 
+		// Sapi yang sedang dijual di marketplace terkunci dan tidak ikut dipanen
 		var cows []domain.Cow
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("owner_id = ?", userID).Find(&cows).Error; err != nil {
+			Where("owner_id = ? AND listing_id IS NULL", userID).Find(&cows).Error; err != nil {
 			return err
 		}
 
@@ -200,9 +198,13 @@ func (uc *FarmUsecase) HarvestFarm(ctx context.Context, userID uuid.UUID) (int,
 				continue // Harus menunggu minimal 1 jam untuk panen
 			}
 
-			// Yield Calculation
-			yield := hoursElapsed * cow.Level
+			// Yield Calculation: jam produksi dipotong status lapar (HUNGRY 50%, STARVING 0%)
+			yield := cow.ProductiveHours(now) * cow.Level
+			if yield == 0 {
+				continue // Sapi kelaparan sepanjang periode ini, tidak ada susu
+			}
 			// Happiness Penalty (-50% if happiness is low)
+			cow.SettleHappiness(now)
 			if cow.Happiness < 50 {
 				yield = yield / 2
 			}
@@ -211,10 +213,7 @@ func (uc *FarmUsecase) HarvestFarm(ctx context.Context, userID uuid.UUID) (int,
 			cow.LastHarvestedAt = &now
 
 			// Decrease happiness after harvesting to simulate work effort
-			cow.Happiness -= 2
-			if cow.Happiness < 0 {
-				cow.Happiness = 0
-			}
+			cow.AddHappiness(now, -2)
 
 			if err := tx.Save(cow).Error; err != nil {
 				return err
@@ -222,32 +221,10 @@ func (uc *FarmUsecase) HarvestFarm(ctx context.Context, userID uuid.UUID) (int,
 		}
 
 		if totalMilkHarvested > 0 {
-			var inventory domain.Inventory
-			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-				Where("user_id = ?", userID).First(&inventory).Error; err != nil {
-				// Create if it doesn't exist
-				inventory = domain.Inventory{UserID: userID, Milk: 0, Grass: 0}
-			}
-			inventory.Milk += totalMilkHarvested
-
-			if inventory.ID == uuid.Nil {
-				if err := tx.Create(&inventory).Error; err != nil {
-					return err
-				}
-			} else {
-				if err := tx.Save(&inventory).Error; err != nil {
-					return err
-				}
-			}
-
-			txLog := domain.TxLog{
-				UserID:   userID,
-				Type:     "HARVEST_MILK",
-				Amount:   decimal.NewFromInt(int64(totalMilkHarvested)),
-				Currency: "MILK",
-				Status:   domain.TxSuccess,
-			}
-			if err := tx.Create(&txLog).Error; err != nil {
+			if _, err := ledger.Post(tx, ledger.Transfer("HARVEST_MILK",
+				ledger.System(domain.BucketMint, domain.CurrencyMilk),
+				ledger.User(userID, domain.CurrencyMilk),
+				decimal.NewFromInt(int64(totalMilkHarvested)))); err != nil {
 				return err
 			}
 		}
@@ -260,8 +237,36 @@ func (uc *FarmUsecase) HarvestFarm(ctx context.Context, userID uuid.UUID) (int,
 	}
 
 	if totalMilkHarvested == 0 {
-		return 0, errors.New("Tidak ada susu yang bisa dipanen (Mungkin belum 1 jam, atau Sapi Kelaparan karena tidak diberi Vitamin Iklan dalam 24 jam terakhir!)")
+		return 0, errors.New("Tidak ada susu yang bisa dipanen (Mungkin belum 1 jam, Sapi kelaparan karena belum diberi makan, atau tidak diberi Vitamin Iklan dalam 24 jam terakhir!)")
 	}
 
 	return totalMilkHarvested, nil
 }
+
+// MigrateHungerRollout menyiapkan sapi yang dibuat sebelum simulasi lapar aktif. Tanpa ini, sapi lama
+// (LastFedAt nil atau lama) langsung dihitung STARVING sejak lahir dan happiness-nya habis seketika.
+// Sapi lama dikenali dari HappinessSettledAt nil (sapi baru selalu mengisinya), sehingga aman dipanggil
+// setiap startup.
+func (uc *FarmUsecase) MigrateHungerRollout(ctx context.Context) {
+	now := time.Now()
+	var migrated int64
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Cow{}).
+			Where("happiness_settled_at IS NULL AND (last_fed_at IS NULL OR last_fed_at < ?)", now).
+			Update("last_fed_at", now).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&domain.Cow{}).
+			Where("happiness_settled_at IS NULL").
+			Update("happiness_settled_at", now)
+		migrated = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		log.Printf("[SEED] Failed to migrate cow hunger state: %v", err)
+		return
+	}
+	if migrated > 0 {
+		log.Printf("[SEED] Hunger state initialised for %d existing cows", migrated)
+	}
+}
@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	idempotencyTTL = 24 * time.Hour
+	// Request IN_PROGRESS yang tidak selesai selama ini dianggap ditinggal (proses crash) dan boleh diklaim ulang
+	idempotencyStaleAfter = time.Minute
+)
+
+var (
+	ErrIdempotencyMismatch   = errors.New("Idempotency-Key sudah dipakai untuk request yang berbeda")
+	ErrIdempotencyInProgress = errors.New("Request dengan Idempotency-Key ini masih diproses")
+)
+
+type IdempotencyUsecase struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyUsecase(db *gorm.DB) *IdempotencyUsecase {
+	return &IdempotencyUsecase{db: db}
+}
+
+// Begin mengklaim key untuk sebuah request. Mengembalikan record COMPLETED jika request yang sama
+// sudah pernah selesai (response harus di-replay), atau nil jika pemanggil boleh memproses request.
+func (uc *IdempotencyUsecase) Begin(ctx context.Context, userID uuid.UUID, key, method, path, requestHash string) (*domain.IdempotencyKey, error) {
+	now := time.Now()
+	var replay *domain.IdempotencyKey
+
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record := domain.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			Method:      method,
+			Path:        path,
+			RequestHash: requestHash,
+			State:       domain.IdempotencyInProgress,
+			ExpiresAt:   now.Add(idempotencyTTL),
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil
+		}
+
+		var existing domain.IdempotencyKey
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND idempotency_key = ?", userID, key).First(&existing).Error; err != nil {
+			return err
+		}
+
+		expired := existing.ExpiresAt.Before(now)
+		abandoned := existing.State == domain.IdempotencyInProgress &&
+			existing.RequestHash == requestHash &&
+			existing.UpdatedAt.Before(now.Add(-idempotencyStaleAfter))
+		if expired || abandoned {
+			return tx.Model(&existing).Updates(map[string]interface{}{
+				"method":        method,
+				"path":          path,
+				"request_hash":  requestHash,
+				"state":         domain.IdempotencyInProgress,
+				"status_code":   0,
+				"content_type":  "",
+				"response_body": nil,
+				"expires_at":    now.Add(idempotencyTTL),
+			}).Error
+		}
+
+		if existing.RequestHash != requestHash {
+			return ErrIdempotencyMismatch
+		}
+		if existing.State == domain.IdempotencyInProgress {
+			return ErrIdempotencyInProgress
+		}
+		replay = &existing
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// Complete menyimpan response request pertama untuk di-replay ke duplikatnya.
+func (uc *IdempotencyUsecase) Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error {
+	return uc.db.WithContext(ctx).Model(&domain.IdempotencyKey{}).
+		Where("user_id = ? AND idempotency_key = ?", userID, key).
+		Updates(map[string]interface{}{
+			"state":         domain.IdempotencyCompleted,
+			"status_code":   statusCode,
+			"content_type":  contentType,
+			"response_body": body,
+		}).Error
+}
+
+// Release menghapus klaim yang belum selesai sehingga klien bisa retry dengan key yang sama.
+func (uc *IdempotencyUsecase) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	return uc.db.WithContext(ctx).
+		Where("user_id = ? AND idempotency_key = ? AND state = ?", userID, key, domain.IdempotencyInProgress).
+		Delete(&domain.IdempotencyKey{}).Error
+}
+
+// StartJanitor menghapus key yang sudah kedaluwarsa setiap jam.
+func (uc *IdempotencyUsecase) StartJanitor(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result := uc.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&domain.IdempotencyKey{})
+		if result.Error != nil {
+			log.Printf("[IDEMPOTENCY] Gagal menghapus key kedaluwarsa: %v", result.Error)
+			continue
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("[IDEMPOTENCY] %d key kedaluwarsa dihapus", result.RowsAffected)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+	"cashcowvalley/backend/pkg/realtime"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	minListingLifetime = time.Hour
+	maxListingLifetime = 30 * 24 * time.Hour
+
+	listingSweepInterval  = time.Minute
+	listingSweepBatchSize = 100
+)
+
+// ExpireListings mengubah listing aktif yang sudah lewat ExpiresAt menjadi EXPIRED dan mengembalikan
+// sisa item dari escrow ke inventory penjual. Setiap listing diproses dalam transaksinya sendiri
+// agar satu kegagalan tidak menahan listing lain. Mengembalikan jumlah listing yang di-expire.
+func (uc *MarketUsecase) ExpireListings(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	var ids []uuid.UUID
+	if err := uc.db.WithContext(ctx).Model(&domain.MarketListing{}).
+		Where("status IN ? AND expires_at IS NOT NULL AND expires_at <= ?", activeOrderStatuses, now).
+		Order("expires_at").Limit(listingSweepBatchSize).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		ok, err := uc.expireListing(ctx, id, now)
+		if err != nil {
+			log.Printf("[MARKET] Gagal meng-expire listing %s: %v", id, err)
+			continue
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func (uc *MarketUsecase) expireListing(ctx context.Context, listingID uuid.UUID, now time.Time) (bool, error) {
+	ctx, events := realtime.WithCollector(ctx)
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	expired := false
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		// Dikunci ulang: listing bisa saja terjual habis atau dibatalkan sejak dipilih
+		var listing domain.MarketListing
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", listingID).First(&listing).Error; err != nil {
+			return err
+		}
+		if !listing.IsActive() || !listing.IsExpired(now) {
+			return nil
+		}
+
+		entryRef := "market_expire:" + listingID.String()
+		if listing.ItemType == domain.ItemCattle {
+			if err := returnListedCow(tx, *listing.CowID, listing.ID, listing.SellerID, entryRef); err != nil {
+				return err
+			}
+		} else {
+			// Item kembali dari escrow marketplace ke inventory penjual (TxLog ditulis oleh ledger)
+			entry := ledger.Transfer("MARKET_EXPIRE",
+				ledger.Escrow(domain.BucketMarket, listing.ItemType),
+				ledger.User(listing.SellerID, listing.ItemType),
+				decimal.NewFromInt(int64(listing.RemainingQuantity)))
+			entry.ReferenceID = &entryRef
+			if _, err := ledger.Post(tx, entry); err != nil {
+				return err
+			}
+		}
+
+		expired = true
+		return tx.Model(&listing).Updates(map[string]interface{}{
+			"remaining_quantity": 0,
+			"status":             domain.ListingExpired,
+		}).Error
+	})
+	if err == nil {
+		flushRealtime(ctx, uc.db, events)
+	}
+	return expired, err
+}
+
+// StartExpirySweeper menjalankan ExpireListings lalu ExpireOffers setiap menit (offer pada listing yang baru
+// kedaluwarsa ikut dikembalikan pada putaran yang sama). Aman untuk banyak replica: hanya
+// replica yang memegang Redlock sweep yang mengeksekusi pada satu putaran.
+func (uc *MarketUsecase) StartExpirySweeper(ctx context.Context) {
+	ticker := time.NewTicker(listingSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		lockKey := "market_expiry_sweep"
+		token, acquired := customRedis.AcquireLock(ctx, lockKey, listingSweepInterval)
+		if !acquired {
+			continue
+		}
+
+		count, err := uc.ExpireListings(ctx)
+		if err != nil {
+			log.Printf("[MARKET] Gagal menjalankan sweeper listing: %v", err)
+		} else if count > 0 {
+			log.Printf("[MARKET] %d listing kedaluwarsa dikembalikan ke penjual", count)
+		}
+
+		count, err = uc.ExpireOffers(ctx)
+		customRedis.ReleaseLock(ctx, lockKey, token)
+		if err != nil {
+			log.Printf("[MARKET] Gagal menjalankan sweeper offer: %v", err)
+			continue
+		}
+		if count > 0 {
+			log.Printf("[MARKET] %d offer kedaluwarsa dikembalikan ke pembeli", count)
+		}
+	}
+}
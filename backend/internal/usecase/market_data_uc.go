@@ -0,0 +1,295 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultCandleLimit = 200
+	maxCandleLimit     = 1000
+)
+
+// Item yang punya data pasar (semua yang bisa terjadi fill di marketplace P2P)
+var marketDataItems = []string{domain.CurrencyGrass, domain.CurrencyMilk, domain.ItemCattle}
+
+var candleIntervalOrder = []string{domain.Candle1m, domain.Candle1h, domain.Candle1d}
+
+type MarketDataUsecase struct {
+	db *gorm.DB
+}
+
+func NewMarketDataUsecase(db *gorm.DB) *MarketDataUsecase {
+	return &MarketDataUsecase{db: db}
+}
+
+func isMarketDataItem(itemType string) bool {
+	for _, item := range marketDataItems {
+		if item == itemType {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTrade menambahkan satu trade ke candle yang sudah berisi harga pembuka.
+func applyTrade(c *domain.MarketCandle, price decimal.Decimal, qty int64, total decimal.Decimal) {
+	if price.GreaterThan(c.High) {
+		c.High = price
+	}
+	if price.LessThan(c.Low) {
+		c.Low = price
+	}
+	c.Close = price
+	c.Volume += qty
+	c.QuoteVolume = c.QuoteVolume.Add(total)
+	c.Trades++
+}
+
+// recordCandles memperbarui candle 1m/1h/1d untuk satu fill di dalam transaksi pemanggil.
+// Baris candle dibuat dulu (ON CONFLICT DO NOTHING) lalu dikunci, sehingga fill paralel untuk
+// item yang sama tidak saling menimpa.
+func recordCandles(tx *gorm.DB, itemType string, price decimal.Decimal, qty int, total decimal.Decimal, at time.Time) error {
+	for _, interval := range candleIntervalOrder {
+		bucket := at.UTC().Truncate(domain.CandleIntervals[interval])
+		seed := domain.MarketCandle{
+			ItemType:    itemType,
+			Interval:    interval,
+			BucketStart: bucket,
+			Open:        price,
+			High:        price,
+			Low:         price,
+			Close:       price,
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return err
+		}
+
+		var candle domain.MarketCandle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("item_type = ? AND period = ? AND bucket_start = ?", itemType, interval, bucket).
+			First(&candle).Error; err != nil {
+			return err
+		}
+		applyTrade(&candle, price, int64(qty), total)
+		if err := tx.Model(&candle).
+			Where("item_type = ? AND period = ? AND bucket_start = ?", itemType, interval, bucket).
+			Updates(map[string]interface{}{
+				"high":         candle.High,
+				"low":          candle.Low,
+				"close":        candle.Close,
+				"volume":       candle.Volume,
+				"quote_volume": candle.QuoteVolume,
+				"trades":       candle.Trades,
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCandles mengembalikan candle terlama dulu. Tanpa `from`, yang diambil adalah `limit` candle terakhir.
+func (uc *MarketDataUsecase) GetCandles(ctx context.Context, itemType, interval string, from, to *time.Time, limit int) ([]domain.MarketCandle, error) {
+	if !isMarketDataItem(itemType) {
+		return nil, fmt.Errorf("Data pasar tidak tersedia untuk %s", itemType)
+	}
+	if _, ok := domain.CandleIntervals[interval]; !ok {
+		return nil, errors.New("Interval tidak valid, gunakan 1m, 1h atau 1d")
+	}
+	if limit <= 0 {
+		limit = defaultCandleLimit
+	}
+	if limit > maxCandleLimit {
+		limit = maxCandleLimit
+	}
+
+	query := uc.db.WithContext(ctx).Where("item_type = ? AND period = ?", itemType, interval)
+	if to != nil {
+		query = query.Where("bucket_start < ?", *to)
+	}
+
+	candles := make([]domain.MarketCandle, 0)
+	if from != nil {
+		err := query.Where("bucket_start >= ?", *from).Order("bucket_start").Limit(limit).Find(&candles).Error
+		return candles, err
+	}
+	if err := query.Order("bucket_start DESC").Limit(limit).Find(&candles).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+	return candles, nil
+}
+
+// GetTicker menghitung harga terakhir dan statistik 24 jam dari candle 1m.
+func (uc *MarketDataUsecase) GetTicker(ctx context.Context, itemType string) (*domain.Ticker, error) {
+	if !isMarketDataItem(itemType) {
+		return nil, fmt.Errorf("Data pasar tidak tersedia untuk %s", itemType)
+	}
+	db := uc.db.WithContext(ctx)
+	ticker := &domain.Ticker{ItemType: itemType}
+
+	var last domain.MarketCandle
+	if err := db.Where("item_type = ? AND period = ?", itemType, domain.Candle1m).
+		Order("bucket_start DESC").First(&last).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ticker, nil
+		}
+		return nil, err
+	}
+	ticker.LastPrice = last.Close
+	lastTradeAt := last.UpdatedAt
+	ticker.LastTradeAt = &lastTradeAt
+
+	since := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Minute)
+	var window []domain.MarketCandle
+	if err := db.Where("item_type = ? AND period = ? AND bucket_start >= ?", itemType, domain.Candle1m, since).
+		Order("bucket_start").Find(&window).Error; err != nil {
+		return nil, err
+	}
+	if len(window) == 0 {
+		return ticker, nil
+	}
+
+	ticker.High24h, ticker.Low24h = window[0].High, window[0].Low
+	for _, c := range window {
+		if c.High.GreaterThan(ticker.High24h) {
+			ticker.High24h = c.High
+		}
+		if c.Low.LessThan(ticker.Low24h) {
+			ticker.Low24h = c.Low
+		}
+		ticker.Volume24h += c.Volume
+		ticker.QuoteVolume24h = ticker.QuoteVolume24h.Add(c.QuoteVolume)
+		ticker.Trades24h += c.Trades
+	}
+
+	// Harga acuan: close terakhir sebelum jendela 24 jam, atau open pertama di dalam jendela
+	reference := window[0].Open
+	var before domain.MarketCandle
+	if err := db.Where("item_type = ? AND period = ? AND bucket_start < ?", itemType, domain.Candle1m, since).
+		Order("bucket_start DESC").First(&before).Error; err == nil {
+		reference = before.Close
+	}
+	ticker.Change24h = ticker.LastPrice.Sub(reference)
+	if reference.IsPositive() {
+		ticker.ChangePct24h = ticker.Change24h.Div(reference).Mul(decimal.NewFromInt(100)).Round(2)
+	}
+	return ticker, nil
+}
+
+// GetTickers mengembalikan ticker semua item pasar.
+func (uc *MarketDataUsecase) GetTickers(ctx context.Context) ([]domain.Ticker, error) {
+	tickers := make([]domain.Ticker, 0, len(marketDataItems))
+	for _, item := range marketDataItems {
+		t, err := uc.GetTicker(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, *t)
+	}
+	return tickers, nil
+}
+
+// BackfillCandles membangun candle awal dari riwayat trade sebelum data pasar dicatat: TxLog MARKET_BUY
+// era lama (satu baris per listing yang dibeli utuh, Amount = total USDT) dan MarketFill era ledger.
+// Hanya berjalan jika tabel candle masih kosong; setelah itu candle dicatat langsung oleh setiap fill.
+func (uc *MarketDataUsecase) BackfillCandles(ctx context.Context) {
+	lockKey := "market_candle_backfill"
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Minute)
+	if !acquired {
+		return
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	db := uc.db.WithContext(ctx)
+	var count int64
+	if err := db.Model(&domain.MarketCandle{}).Count(&count).Error; err != nil {
+		log.Printf("[SEED] Failed to check market candles: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	type trade struct {
+		ItemType  string
+		Quantity  int64
+		Total     decimal.Decimal
+		CreatedAt time.Time
+	}
+
+	var trades []trade
+	if err := db.Table("tx_logs AS t").
+		Select("l.item_type, l.quantity, t.amount AS total, t.created_at").
+		Joins("JOIN market_listings l ON l.id = t.reference_id").
+		Where("t.type = ? AND t.entry_id IS NULL AND t.status = ? AND l.quantity > 0", "MARKET_BUY", domain.TxSuccess).
+		Scan(&trades).Error; err != nil {
+		log.Printf("[SEED] Failed to read legacy market trades: %v", err)
+		return
+	}
+	var fills []trade
+	if err := db.Model(&domain.MarketFill{}).
+		Select("item_type, quantity, total_usdt AS total, created_at").
+		Scan(&fills).Error; err != nil {
+		log.Printf("[SEED] Failed to read market fills: %v", err)
+		return
+	}
+	trades = append(trades, fills...)
+	if len(trades) == 0 {
+		return
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].CreatedAt.Before(trades[j].CreatedAt) })
+
+	type candleKey struct {
+		ItemType string
+		Interval string
+		Bucket   time.Time
+	}
+	candles := make(map[candleKey]*domain.MarketCandle)
+	order := make([]candleKey, 0)
+	for _, t := range trades {
+		price := t.Total.DivRound(decimal.NewFromInt(t.Quantity), 8)
+		for _, interval := range candleIntervalOrder {
+			key := candleKey{t.ItemType, interval, t.CreatedAt.UTC().Truncate(domain.CandleIntervals[interval])}
+			c, ok := candles[key]
+			if !ok {
+				c = &domain.MarketCandle{
+					ItemType:    key.ItemType,
+					Interval:    key.Interval,
+					BucketStart: key.Bucket,
+					Open:        price,
+					High:        price,
+					Low:         price,
+					Close:       price,
+				}
+				candles[key] = c
+				order = append(order, key)
+			}
+			applyTrade(c, price, t.Quantity, t.Total)
+			c.UpdatedAt = t.CreatedAt
+		}
+	}
+
+	rows := make([]domain.MarketCandle, 0, len(order))
+	for _, key := range order {
+		rows = append(rows, *candles[key])
+	}
+	if err := db.CreateInBatches(rows, 500).Error; err != nil {
+		log.Printf("[SEED] Failed to backfill market candles: %v", err)
+		return
+	}
+	log.Printf("[SEED] Market candles backfilled from %d trades (%d candles)", len(trades), len(rows))
+}
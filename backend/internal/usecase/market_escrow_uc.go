@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// MigrateLegacyEscrow mengisi akun ESCROW:MARKET dengan sisa item listing aktif yang dibuat sebelum ledger
+// aktif (item sudah dipotong dari inventory, tetapi tidak pernah masuk escrow ledger). Tanpa saldo ini,
+// membeli, membatalkan atau meng-expire listing lama gagal dengan ErrInsufficientFunds atau memakai item
+// escrow penjual lain. Idempoten lewat ReferenceID per item type, aman dipanggil setiap startup.
+func (uc *MarketUsecase) MigrateLegacyEscrow(ctx context.Context) {
+	// Listing lama tidak punya TxLog MARKET_SELL yang diposting ledger (entry_id) dengan reference listing ID
+	var rows []struct {
+		ItemType string
+		Total    int64
+	}
+	if err := uc.db.WithContext(ctx).Model(&domain.MarketListing{}).
+		Select("item_type, COALESCE(SUM(remaining_quantity), 0) AS total").
+		Where("status IN ? AND item_type <> ?", activeOrderStatuses, domain.ItemCattle).
+		Where("NOT EXISTS (SELECT 1 FROM tx_logs t WHERE t.reference_id = market_listings.id AND t.entry_id IS NOT NULL)").
+		Group("item_type").
+		Scan(&rows).Error; err != nil {
+		log.Printf("[SEED] Failed to read legacy market listings: %v", err)
+		return
+	}
+
+	for _, row := range rows {
+		if row.Total <= 0 {
+			continue
+		}
+		total := decimal.NewFromInt(row.Total)
+		ref := "market_escrow_opening:" + row.ItemType
+		err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			_, err := ledger.Post(tx, ledger.Entry{
+				Type:        "OPENING_BALANCE",
+				ReferenceID: &ref,
+				Legs: []ledger.Leg{
+					{Account: ledger.System(domain.BucketOpening, row.ItemType), Amount: total.Neg()},
+					{Account: ledger.Escrow(domain.BucketMarket, row.ItemType), Amount: total},
+				},
+			})
+			return err
+		})
+		if errors.Is(err, ledger.ErrDuplicateEntry) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[SEED] Failed to migrate legacy market escrow %s: %v", row.ItemType, err)
+			continue
+		}
+		log.Printf("[SEED] Legacy market escrow %s migrated: %s", row.ItemType, total.String())
+	}
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Batas atas fee maker/taker yang boleh dijadwalkan admin (5% per sisi)
+var maxMarketFee = decimal.RequireFromString("0.05")
+
+// marketFees adalah tarif fee P2P dari EconomyConfig aktif, dimuat sekali per transaksi trade.
+type marketFees struct {
+	Maker    decimal.Decimal
+	Taker    decimal.Decimal
+	LPShare  decimal.Decimal
+	RefShare decimal.Decimal
+	Version  int
+}
+
+func loadMarketFees(tx *gorm.DB, now time.Time) (*marketFees, error) {
+	economy, err := activeEconomyConfig(tx, now)
+	if err != nil {
+		return nil, err
+	}
+	return &marketFees{
+		Maker:    economy.Params.MarketMakerFee,
+		Taker:    economy.Params.MarketTakerFee,
+		LPShare:  economy.Params.PlatformLPShare,
+		RefShare: economy.Params.PlatformRefShare,
+		Version:  economy.Version,
+	}, nil
+}
+
+// charge menghitung fee satu sisi trade senilai `total`.
+func (f *marketFees) charge(total decimal.Decimal, taker bool) decimal.Decimal {
+	rate := f.Maker
+	if taker {
+		rate = f.Taker
+	}
+	return total.Mul(rate).Truncate(8)
+}
+
+// reserveRate adalah cadangan fee yang ditahan bersama bid: bid bisa terisi sebagai taker (saat
+// dipasang) maupun maker (saat resting), jadi yang ditahan adalah tarif tertinggi.
+func (f *marketFees) reserveRate() decimal.Decimal {
+	return decimal.Max(f.Maker, f.Taker)
+}
+
+// treasuryLegs membagi total fee ke treasury LP buyback / referral pool / dev dengan porsi yang sama
+// seperti penjualan platform. Sisa pembulatan masuk ke dev agar entry tetap seimbang.
+func (f *marketFees) treasuryLegs(fee decimal.Decimal) []ledger.Leg {
+	lpCut := fee.Mul(f.LPShare).Truncate(8)
+	refCut := fee.Mul(f.RefShare).Truncate(8)
+	devCut := fee.Sub(lpCut).Sub(refCut)
+	return []ledger.Leg{
+		{Account: ledger.Treasury(domain.BucketLPBuyback, domain.CurrencyUSDT), Amount: lpCut},
+		{Account: ledger.Treasury(domain.BucketReferralPool, domain.CurrencyUSDT), Amount: refCut},
+		{Account: ledger.Treasury(domain.BucketDev, domain.CurrencyUSDT), Amount: devCut},
+	}
+}
+
+// bidHold adalah USDT yang ditahan escrow untuk `qty` item sebuah bid: nilai bid ditambah cadangan fee.
+// Porsi per fill dihitung sebagai selisih bidHold sebelum dan sesudah fill, sehingga total yang dilepas
+// selalu sama persis dengan yang ditahan saat bid dipasang (tanpa sisa pembulatan di escrow).
+func bidHold(bid *domain.MarketBid, qty int) decimal.Decimal {
+	value := bid.UnitPriceUSDT.Mul(decimal.NewFromInt(int64(qty)))
+	return value.Add(value.Mul(bid.FeeRate).Truncate(8))
+}
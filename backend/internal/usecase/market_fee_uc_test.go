@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func testFees() *marketFees {
+	return &marketFees{
+		Maker:    decimal.RequireFromString("0.01"),
+		Taker:    decimal.RequireFromString("0.025"),
+		LPShare:  decimal.RequireFromString("0.70"),
+		RefShare: decimal.RequireFromString("0.20"),
+	}
+}
+
+func TestChargeTruncates(t *testing.T) {
+	fees := testFees()
+	cases := []struct {
+		total   string
+		taker   bool
+		wantFee string
+	}{
+		{"100", true, "2.5"},
+		{"100", false, "1"},
+		{"0.33333333", true, "0.00833333"},  // 0.00833333325
+		{"0.99999999", false, "0.00999999"}, // 0.0099999999, tidak dibulatkan ke atas
+		{"0.00000039", true, "0"},           // 0.00000000975
+		{"0.00000100", true, "0.00000002"},  // 0.000000025
+	}
+	for _, tc := range cases {
+		got := fees.charge(decimal.RequireFromString(tc.total), tc.taker)
+		if !got.Equal(decimal.RequireFromString(tc.wantFee)) {
+			t.Errorf("charge(%s, taker=%v) = %s, harus %s", tc.total, tc.taker, got, tc.wantFee)
+		}
+	}
+}
+
+func TestTreasuryLegsSumToFee(t *testing.T) {
+	fees := testFees()
+	cases := []struct {
+		fee                      string
+		wantLP, wantRef, wantDev string
+	}{
+		{"1", "0.7", "0.2", "0.1"},
+		{"0", "0", "0", "0"},
+		{"0.00000001", "0", "0", "0.00000001"}, // Seluruh sisa pembulatan masuk dev
+		{"0.00000007", "0.00000004", "0.00000001", "0.00000002"},
+		{"1.23456789", "0.86419752", "0.24691357", "0.1234568"},
+	}
+	for _, tc := range cases {
+		fee := decimal.RequireFromString(tc.fee)
+		legs := fees.treasuryLegs(fee)
+		if len(legs) != 3 {
+			t.Fatalf("treasuryLegs(%s) = %d leg, harus 3", tc.fee, len(legs))
+		}
+		sum := decimal.Zero
+		for i, want := range []string{tc.wantLP, tc.wantRef, tc.wantDev} {
+			if !legs[i].Amount.Equal(decimal.RequireFromString(want)) {
+				t.Errorf("treasuryLegs(%s)[%s] = %s, harus %s", tc.fee, legs[i].Account.Code(), legs[i].Amount, want)
+			}
+			if legs[i].Amount.IsNegative() {
+				t.Errorf("treasuryLegs(%s)[%s] negatif: %s", tc.fee, legs[i].Account.Code(), legs[i].Amount)
+			}
+			sum = sum.Add(legs[i].Amount)
+		}
+		if !sum.Equal(fee) {
+			t.Errorf("treasuryLegs(%s) berjumlah %s, harus sama dengan fee", tc.fee, sum)
+		}
+	}
+}
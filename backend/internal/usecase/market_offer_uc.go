@@ -0,0 +1,507 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+	"cashcowvalley/backend/pkg/realtime"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultOfferLifetime = 24 * time.Hour
+	minOfferLifetime     = time.Hour
+	maxOfferLifetime     = 3 * 24 * time.Hour
+
+	offerListLimit = 100
+)
+
+// offerHold adalah USDT yang ditahan untuk sebuah offer: nilai tawaran ditambah cadangan fee taker,
+// karena offer yang diterima diselesaikan seperti BuyItem (pembeli sebagai taker).
+func offerHold(fees *marketFees, unitPrice decimal.Decimal, qty int) decimal.Decimal {
+	value := unitPrice.Mul(decimal.NewFromInt(int64(qty)))
+	return value.Add(fees.charge(value, true))
+}
+
+// emitOffer mengirim status offer terbaru ke pembeli dan penjual.
+func emitOffer(tx *gorm.DB, offer *domain.MarketOffer) {
+	realtime.Emit(tx.Statement.Context, realtime.EventOfferUpdated, offer.BuyerID.String(), offer)
+	realtime.Emit(tx.Statement.Context, realtime.EventOfferUpdated, offer.SellerID.String(), offer)
+}
+
+// offerExpiry membatasi masa berlaku offer agar tidak melewati ExpiresAt listing-nya.
+func offerExpiry(listing *domain.MarketListing, now time.Time, lifetime time.Duration) time.Time {
+	expiresAt := now.Add(lifetime)
+	if listing.ExpiresAt != nil && listing.ExpiresAt.Before(expiresAt) {
+		return *listing.ExpiresAt
+	}
+	return expiresAt
+}
+
+// MakeOffer menawar listing di bawah harga listing. quantity 0 berarti seluruh sisa listing, lifetime 0
+// berarti 24 jam. Nilai tawaran + cadangan fee taker ditahan di escrow MARKET sampai offer selesai.
+func (uc *MarketUsecase) MakeOffer(ctx context.Context, buyerID, listingID uuid.UUID, quantity int, unitPriceUSDT decimal.Decimal, lifetime time.Duration) (*domain.MarketOffer, error) {
+	if quantity < 0 {
+		return nil, errors.New("Jumlah item tidak valid")
+	}
+	if err := validateListingPrice(unitPriceUSDT); err != nil {
+		return nil, err
+	}
+	if lifetime == 0 {
+		lifetime = defaultOfferLifetime
+	}
+	if lifetime < minOfferLifetime || lifetime > maxOfferLifetime {
+		return nil, errors.New("Masa berlaku offer harus antara 1 jam dan 3 hari")
+	}
+
+	// Lock yang sama dengan BuyItem: satu operasi yang menahan USDT pembeli dalam satu waktu
+	lockKey := "market_buy:" + buyerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return nil, errors.New("Transaksi pembelian sedang diproses...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var offer domain.MarketOffer
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		var listing domain.MarketListing
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", listingID).First(&listing).Error; err != nil {
+			return errors.New("Listing tidak ditemukan")
+		}
+		if !listing.IsActive() {
+			return errors.New("Item sudah terjual atau ditarik oleh penjual")
+		}
+		if listing.IsExpired(now) {
+			return errors.New("Listing sudah kedaluwarsa")
+		}
+		if listing.SellerID == buyerID {
+			return errors.New("Tidak dapat menawar barang sendiri")
+		}
+
+		qty := quantity
+		if qty == 0 {
+			qty = listing.RemainingQuantity
+		}
+		if qty > listing.RemainingQuantity {
+			return fmt.Errorf("Sisa listing hanya %d item", listing.RemainingQuantity)
+		}
+		if !unitPriceUSDT.LessThan(listing.UnitPriceUSDT) {
+			return errors.New("Harga tawaran harus di bawah harga listing, gunakan beli langsung")
+		}
+
+		var active int64
+		if err := tx.Model(&domain.MarketOffer{}).
+			Where("listing_id = ? AND buyer_id = ? AND status IN ?", listingID, buyerID, []string{domain.OfferPending, domain.OfferCountered}).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return errors.New("Kamu masih punya offer aktif pada listing ini")
+		}
+
+		fees, err := loadMarketFees(tx, now)
+		if err != nil {
+			return err
+		}
+		held := offerHold(fees, unitPriceUSDT, qty)
+
+		if err := lockUsersSorted(tx, buyerID); err != nil {
+			return err
+		}
+		var buyer domain.User
+		if err := tx.Where("id = ?", buyerID).First(&buyer).Error; err != nil {
+			return errors.New("User tidak ditemukan")
+		}
+		if buyer.USDTBalance.LessThan(held) {
+			return errors.New("Saldo USDT tidak mencukupi")
+		}
+
+		offer = domain.MarketOffer{
+			ListingID:     listing.ID,
+			BuyerID:       buyerID,
+			SellerID:      listing.SellerID,
+			ItemType:      listing.ItemType,
+			Quantity:      qty,
+			UnitPriceUSDT: unitPriceUSDT,
+			HeldUSDT:      held,
+			Status:        domain.OfferPending,
+			ExpiresAt:     offerExpiry(&listing, now, lifetime),
+		}
+		if err := tx.Create(&offer).Error; err != nil {
+			return err
+		}
+
+		entryRef := "market_offer:" + offer.ID.String()
+		entry := ledger.Transfer("MARKET_OFFER",
+			ledger.User(buyerID, domain.CurrencyUSDT),
+			ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT),
+			held)
+		entry.ReferenceID = &entryRef
+		if _, err := ledger.Post(tx, entry); err != nil {
+			return err
+		}
+
+		emitOffer(tx, &offer)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// lockOfferWithListing mengunci listing lalu offer-nya, dengan urutan yang sama seperti BuyItem dan
+// MakeOffer (listing lebih dulu) agar tidak deadlock.
+func lockOfferWithListing(tx *gorm.DB, offerID uuid.UUID) (*domain.MarketListing, *domain.MarketOffer, error) {
+	var current domain.MarketOffer
+	if err := tx.Select("id", "listing_id").Where("id = ?", offerID).First(&current).Error; err != nil {
+		return nil, nil, errors.New("Offer tidak ditemukan")
+	}
+
+	var listing domain.MarketListing
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", current.ListingID).First(&listing).Error; err != nil {
+		return nil, nil, errors.New("Listing tidak ditemukan")
+	}
+	var offer domain.MarketOffer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", offerID).First(&offer).Error; err != nil {
+		return nil, nil, errors.New("Offer tidak ditemukan")
+	}
+	return &listing, &offer, nil
+}
+
+// offerStale bernilai true jika offer aktif tidak mungkin lagi diterima: sisa listing sudah kurang dari
+// jumlah offer, atau harga listing turun sampai harga yang sedang ditawar (harga counter untuk offer
+// COUNTERED) sehingga beli langsung lebih murah.
+func offerStale(listing *domain.MarketListing, offer *domain.MarketOffer) bool {
+	price := offer.UnitPriceUSDT
+	if offer.Status == domain.OfferCountered && offer.CounterPriceUSDT != nil {
+		price = *offer.CounterPriceUSDT
+	}
+	return offer.Quantity > listing.RemainingQuantity || !price.LessThan(listing.UnitPriceUSDT)
+}
+
+// closeStaleOffers menutup offer aktif pada listing (yang sudah dikunci) yang menjadi offerStale setelah
+// fill atau perubahan harga, agar USDT pembeli tidak tertahan sampai ExpiresAt.
+func closeStaleOffers(tx *gorm.DB, listing *domain.MarketListing) error {
+	var offers []domain.MarketOffer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("listing_id = ? AND status IN ?", listing.ID, []string{domain.OfferPending, domain.OfferCountered}).
+		Order("id").Find(&offers).Error; err != nil {
+		return err
+	}
+	for i := range offers {
+		if !offerStale(listing, &offers[i]) {
+			continue
+		}
+		if err := closeOffer(tx, &offers[i], domain.OfferExpired, "MARKET_OFFER_EXPIRE"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeOffer mengembalikan seluruh USDT offer dari escrow ke pembeli dan menutup offer dengan `status`.
+func closeOffer(tx *gorm.DB, offer *domain.MarketOffer, status, txType string) error {
+	entryRef := "market_offer_close:" + offer.ID.String()
+	entry := ledger.Transfer(txType,
+		ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT),
+		ledger.User(offer.BuyerID, domain.CurrencyUSDT),
+		offer.HeldUSDT)
+	entry.ReferenceID = &entryRef
+	if _, err := ledger.Post(tx, entry); err != nil {
+		return err
+	}
+
+	offer.Status = status
+	if err := tx.Model(offer).Update("status", status).Error; err != nil {
+		return err
+	}
+	emitOffer(tx, offer)
+	return nil
+}
+
+// settleOffer menyelesaikan offer yang disepakati pada harga `price` lewat jalur yang sama dengan BuyItem:
+// USDT offer dilepas dari escrow ke pembeli, lalu executeTrade memindahkan item dan USDT (+ fee).
+// Jika harga counter di atas tawaran, kekurangannya diambil dari saldo pembeli.
+func settleOffer(tx *gorm.DB, listing *domain.MarketListing, offer *domain.MarketOffer, price decimal.Decimal, now time.Time) error {
+	if !listing.IsActive() || listing.IsExpired(now) {
+		return errors.New("Listing sudah tidak aktif")
+	}
+	if offer.Quantity > listing.RemainingQuantity {
+		return fmt.Errorf("Sisa listing hanya %d item", listing.RemainingQuantity)
+	}
+	if err := lockUsersSorted(tx, offer.BuyerID, offer.SellerID); err != nil {
+		return err
+	}
+
+	entryRef := "market_offer_release:" + offer.ID.String()
+	release := ledger.Transfer("MARKET_OFFER_RELEASE",
+		ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT),
+		ledger.User(offer.BuyerID, domain.CurrencyUSDT),
+		offer.HeldUSDT)
+	release.ReferenceID = &entryRef
+	if _, err := ledger.Post(tx, release); err != nil {
+		return err
+	}
+
+	fees, err := loadMarketFees(tx, now)
+	if err != nil {
+		return err
+	}
+	var buyer domain.User
+	if err := tx.Where("id = ?", offer.BuyerID).First(&buyer).Error; err != nil {
+		return errors.New("User tidak ditemukan")
+	}
+	if buyer.USDTBalance.LessThan(offerHold(fees, price, offer.Quantity)) {
+		return errors.New("Saldo USDT pembeli tidak mencukupi")
+	}
+
+	// Ditandai ACCEPTED sebelum fill agar closeStaleOffers di executeTrade tidak ikut menutup offer ini
+	offer.Status = domain.OfferAccepted
+	if err := tx.Model(offer).Update("status", offer.Status).Error; err != nil {
+		return err
+	}
+	fill, err := executeTrade(tx, fees, listing, nil, offer.BuyerID, offer.Quantity, price, domain.TakerBuy)
+	if err != nil {
+		return err
+	}
+
+	offer.FillID = &fill.ID
+	if err := tx.Model(offer).Update("fill_id", fill.ID).Error; err != nil {
+		return err
+	}
+	emitOffer(tx, offer)
+	return nil
+}
+
+// RespondOffer menjawab offer sesuai gilirannya: offer PENDING dijawab penjual (ACCEPT, REJECT atau
+// COUNTER dengan counterPrice), offer COUNTERED dijawab pembeli (ACCEPT harga counter atau REJECT).
+func (uc *MarketUsecase) RespondOffer(ctx context.Context, userID, offerID uuid.UUID, action string, counterPrice decimal.Decimal) (*domain.MarketOffer, error) {
+	switch action {
+	case domain.OfferActionAccept, domain.OfferActionReject, domain.OfferActionCounter:
+	default:
+		return nil, errors.New("Aksi tidak valid, hanya ACCEPT, REJECT atau COUNTER")
+	}
+
+	lockKey := "market_offer:" + offerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return nil, errors.New("Offer sedang diproses...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var result domain.MarketOffer
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		listing, offer, err := lockOfferWithListing(tx, offerID)
+		if err != nil {
+			return err
+		}
+		if userID != offer.BuyerID && userID != offer.SellerID {
+			return errors.New("Offer tidak ditemukan")
+		}
+		if !offer.IsActive() {
+			return errors.New("Offer sudah tidak aktif")
+		}
+		if !offer.ExpiresAt.After(now) {
+			return errors.New("Offer sudah kedaluwarsa")
+		}
+
+		// Giliran menjawab: PENDING -> penjual, COUNTERED -> pembeli
+		turn := offer.SellerID
+		if offer.Status == domain.OfferCountered {
+			turn = offer.BuyerID
+		}
+		if userID != turn {
+			return errors.New("Menunggu jawaban pihak lain")
+		}
+
+		switch action {
+		case domain.OfferActionAccept:
+			price := offer.UnitPriceUSDT
+			if offer.Status == domain.OfferCountered {
+				price = *offer.CounterPriceUSDT
+			}
+			err = settleOffer(tx, listing, offer, price, now)
+		case domain.OfferActionReject:
+			err = closeOffer(tx, offer, domain.OfferRejected, "MARKET_OFFER_REJECT")
+		case domain.OfferActionCounter:
+			err = counterOffer(tx, listing, offer, counterPrice, now)
+		}
+		if err != nil {
+			return err
+		}
+		result = *offer
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// counterOffer mencatat harga counter penjual dan memperpanjang masa berlaku offer agar pembeli sempat
+// menjawab. USDT yang ditahan tidak berubah; selisihnya diambil saat pembeli menerima counter.
+func counterOffer(tx *gorm.DB, listing *domain.MarketListing, offer *domain.MarketOffer, counterPrice decimal.Decimal, now time.Time) error {
+	if offer.Status != domain.OfferPending {
+		return errors.New("Counter hanya bisa diajukan penjual")
+	}
+	if !listing.IsActive() || listing.IsExpired(now) {
+		return errors.New("Listing sudah tidak aktif")
+	}
+	if err := validateListingPrice(counterPrice); err != nil {
+		return err
+	}
+	if !counterPrice.GreaterThan(offer.UnitPriceUSDT) {
+		return errors.New("Harga counter harus di atas harga tawaran")
+	}
+	if !counterPrice.LessThan(listing.UnitPriceUSDT) {
+		return errors.New("Harga counter harus di bawah harga listing")
+	}
+
+	offer.Status = domain.OfferCountered
+	offer.CounterPriceUSDT = &counterPrice
+	offer.ExpiresAt = offerExpiry(listing, now, defaultOfferLifetime)
+	if err := tx.Model(offer).Updates(map[string]interface{}{
+		"status":             offer.Status,
+		"counter_price_usdt": counterPrice,
+		"expires_at":         offer.ExpiresAt,
+	}).Error; err != nil {
+		return err
+	}
+	emitOffer(tx, offer)
+	return nil
+}
+
+// CancelOffer menarik offer aktif milik pembeli dan mengembalikan USDT dari escrow.
+func (uc *MarketUsecase) CancelOffer(ctx context.Context, buyerID, offerID uuid.UUID) error {
+	lockKey := "market_offer:" + offerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return errors.New("Offer sedang diproses...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	return uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		_, offer, err := lockOfferWithListing(tx, offerID)
+		if err != nil {
+			return err
+		}
+		if offer.BuyerID != buyerID {
+			return errors.New("Offer tidak ditemukan")
+		}
+		if !offer.IsActive() {
+			return errors.New("Offer sudah tidak aktif")
+		}
+		return closeOffer(tx, offer, domain.OfferCancelled, "MARKET_OFFER_CANCEL")
+	})
+}
+
+// GetMyOffers mengembalikan offer yang dikirim (role "sent"), diterima pada listing sendiri
+// (role "received"), atau keduanya (role kosong), terbaru dulu.
+func (uc *MarketUsecase) GetMyOffers(ctx context.Context, userID uuid.UUID, role string) ([]domain.MarketOffer, error) {
+	query := uc.db.WithContext(ctx).Model(&domain.MarketOffer{})
+	switch role {
+	case "sent":
+		query = query.Where("buyer_id = ?", userID)
+	case "received":
+		query = query.Where("seller_id = ?", userID)
+	case "":
+		query = query.Where("buyer_id = ? OR seller_id = ?", userID, userID)
+	default:
+		return nil, errors.New("Role tidak valid, hanya sent atau received")
+	}
+
+	var offers []domain.MarketOffer
+	if err := query.Order("created_at DESC").Limit(offerListLimit).Find(&offers).Error; err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+// ExpireOffers menutup offer aktif yang sudah lewat ExpiresAt, yang listing-nya sudah terjual habis,
+// dibatalkan atau kedaluwarsa, atau yang sudah offerStale, dan mengembalikan USDT-nya ke pembeli.
+// Mengembalikan jumlah offer yang ditutup.
+func (uc *MarketUsecase) ExpireOffers(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	inactiveListings := uc.db.Model(&domain.MarketListing{}).Select("id").
+		Where("status NOT IN ? OR (expires_at IS NOT NULL AND expires_at <= ?)", activeOrderStatuses, now)
+	// Sama dengan offerStale; menangkap offer yang tertinggal sebelum closeStaleOffers dipasang
+	staleListings := uc.db.Model(&domain.MarketListing{}).Select("1").
+		Where("market_listings.id = market_offers.listing_id").
+		Where("market_listings.remaining_quantity < market_offers.quantity OR market_listings.unit_price_usdt <= "+
+			"CASE WHEN market_offers.status = ? THEN market_offers.counter_price_usdt ELSE market_offers.unit_price_usdt END",
+			domain.OfferCountered)
+	var ids []uuid.UUID
+	if err := uc.db.WithContext(ctx).Model(&domain.MarketOffer{}).
+		Where("status IN ?", []string{domain.OfferPending, domain.OfferCountered}).
+		Where("expires_at <= ? OR listing_id IN (?) OR EXISTS (?)", now, inactiveListings, staleListings).
+		Order("expires_at").Limit(listingSweepBatchSize).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		ok, err := uc.expireOffer(ctx, id, now)
+		if err != nil {
+			log.Printf("[MARKET] Gagal meng-expire offer %s: %v", id, err)
+			continue
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func (uc *MarketUsecase) expireOffer(ctx context.Context, offerID uuid.UUID, now time.Time) (bool, error) {
+	ctx, events := realtime.WithCollector(ctx)
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	expired := false
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		// Dikunci ulang: offer bisa saja sudah dijawab sejak dipilih
+		listing, offer, err := lockOfferWithListing(tx, offerID)
+		if err != nil {
+			return err
+		}
+		if !offer.IsActive() {
+			return nil
+		}
+		if offer.ExpiresAt.After(now) && listing.IsActive() && !listing.IsExpired(now) && !offerStale(listing, offer) {
+			return nil
+		}
+
+		expired = true
+		return closeOffer(tx, offer, domain.OfferExpired, "MARKET_OFFER_EXPIRE")
+	})
+	if err == nil {
+		flushRealtime(ctx, uc.db, events)
+	}
+	return expired, err
+}
@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func makeOffer(t *testing.T, uc *MarketUsecase, buyerID, listingID uuid.UUID, qty int, price string) *domain.MarketOffer {
+	t.Helper()
+	offer, err := uc.MakeOffer(context.Background(), buyerID, listingID, qty, decimal.RequireFromString(price), 0)
+	if err != nil {
+		t.Fatalf("make offer: %v", err)
+	}
+	return offer
+}
+
+func assertOfferStatus(t *testing.T, db *gorm.DB, offerID uuid.UUID, want string) {
+	t.Helper()
+	var offer domain.MarketOffer
+	db.First(&offer, "id = ?", offerID)
+	if offer.Status != want {
+		t.Fatalf("status offer = %s, harus %s", offer.Status, want)
+	}
+}
+
+func assertUSDT(t *testing.T, db *gorm.DB, userID uuid.UUID, want string) {
+	t.Helper()
+	if got := usdtBalance(t, db, userID); !got.Equal(decimal.RequireFromString(want)) {
+		t.Fatalf("saldo USDT = %s, harus %s", got, want)
+	}
+}
+
+func TestAcceptOfferReleasesHold(t *testing.T) {
+	db := newMarketTestDB(t)
+	seller, buyer, other := newTrader(t, db), newTrader(t, db), newTrader(t, db)
+	listing := restListing(t, db, seller, 10, "2", time.Now())
+	uc := NewMarketUsecase(db)
+
+	// Nilai 7.5 + cadangan fee taker 2%
+	offer := makeOffer(t, uc, buyer, listing.ID, 5, "1.5")
+	if !offer.HeldUSDT.Equal(decimal.RequireFromString("7.65")) {
+		t.Fatalf("HeldUSDT = %s, harus 7.65", offer.HeldUSDT)
+	}
+	assertUSDT(t, db, buyer, "92.35")
+	bigger := makeOffer(t, uc, other, listing.ID, 6, "1")
+	if got := accountBalance(t, db, marketEscrowUSDT); !got.Equal(decimal.RequireFromString("13.77")) {
+		t.Fatalf("escrow USDT = %s, harus 13.77", got)
+	}
+
+	if _, err := uc.RespondOffer(context.Background(), seller, offer.ID, domain.OfferActionAccept, decimal.Zero); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	assertOfferStatus(t, db, offer.ID, domain.OfferAccepted)
+	assertUSDT(t, db, buyer, "92.35")
+	assertUSDT(t, db, seller, "107.425") // 7.5 - fee maker 1%
+
+	// Sisa listing tinggal 5: offer 6 item tidak mungkin lagi diterima dan langsung di-refund
+	assertOfferStatus(t, db, bigger.ID, domain.OfferExpired)
+	assertUSDT(t, db, other, "100")
+	if got := accountBalance(t, db, marketEscrowUSDT); !got.IsZero() {
+		t.Fatalf("escrow USDT = %s, harus 0", got)
+	}
+}
+
+func TestAcceptCounterTopsUpHold(t *testing.T) {
+	db := newMarketTestDB(t)
+	seller, buyer := newTrader(t, db), newTrader(t, db)
+	listing := restListing(t, db, seller, 10, "2", time.Now())
+	uc := NewMarketUsecase(db)
+	ctx := context.Background()
+
+	offer := makeOffer(t, uc, buyer, listing.ID, 5, "1.5")
+	if _, err := uc.RespondOffer(ctx, seller, offer.ID, domain.OfferActionCounter, decimal.RequireFromString("1.8")); err != nil {
+		t.Fatalf("counter: %v", err)
+	}
+	// Counter tidak mengubah USDT yang ditahan
+	assertUSDT(t, db, buyer, "92.35")
+	if _, err := uc.RespondOffer(ctx, buyer, offer.ID, domain.OfferActionAccept, decimal.Zero); err != nil {
+		t.Fatalf("accept counter: %v", err)
+	}
+
+	// 9 + fee taker 0.18: kekurangan 1.53 dari hold diambil dari saldo pembeli
+	assertOfferStatus(t, db, offer.ID, domain.OfferAccepted)
+	assertUSDT(t, db, buyer, "90.82")
+	assertUSDT(t, db, seller, "108.91")
+	if got := accountBalance(t, db, marketEscrowUSDT); !got.IsZero() {
+		t.Fatalf("escrow USDT = %s, harus 0", got)
+	}
+}
+
+func TestExpireOffersRefundsHold(t *testing.T) {
+	db := newMarketTestDB(t)
+	seller, buyer, stale := newTrader(t, db), newTrader(t, db), newTrader(t, db)
+	listing := restListing(t, db, seller, 10, "2", time.Now())
+	uc := NewMarketUsecase(db)
+
+	expired := makeOffer(t, uc, buyer, listing.ID, 5, "1.5")
+	db.Model(expired).Update("expires_at", time.Now().Add(-time.Second))
+	// Offer yang tertinggal dari sebelum closeStaleOffers: sisa listing sudah di bawah jumlah offer
+	leftover := makeOffer(t, uc, stale, listing.ID, 8, "1")
+	db.Model(listing).Update("remaining_quantity", 6)
+	live := makeOffer(t, uc, newTrader(t, db), listing.ID, 2, "1")
+
+	if n, err := uc.ExpireOffers(context.Background()); err != nil || n != 2 {
+		t.Fatalf("expire: n=%d err=%v, harus 2", n, err)
+	}
+	assertOfferStatus(t, db, expired.ID, domain.OfferExpired)
+	assertOfferStatus(t, db, leftover.ID, domain.OfferExpired)
+	assertOfferStatus(t, db, live.ID, domain.OfferPending)
+	assertUSDT(t, db, buyer, "100")
+	assertUSDT(t, db, stale, "100")
+	if got := accountBalance(t, db, marketEscrowUSDT); !got.Equal(live.HeldUSDT) {
+		t.Fatalf("escrow USDT = %s, harus hanya hold offer aktif %s", got, live.HeldUSDT)
+	}
+}
+
+func TestRepriceAndFillCloseStaleOffers(t *testing.T) {
+	db := newMarketTestDB(t)
+	seller, buyer, countered, low, taker := newTrader(t, db), newTrader(t, db), newTrader(t, db), newTrader(t, db), newTrader(t, db)
+	listing := restListing(t, db, seller, 10, "2", time.Now())
+	uc := NewMarketUsecase(db)
+	ctx := context.Background()
+
+	pending := makeOffer(t, uc, buyer, listing.ID, 2, "1.5")
+	counter := makeOffer(t, uc, countered, listing.ID, 2, "1.2")
+	if _, err := uc.RespondOffer(ctx, seller, counter.ID, domain.OfferActionCounter, decimal.RequireFromString("1.9")); err != nil {
+		t.Fatalf("counter: %v", err)
+	}
+	cheap := makeOffer(t, uc, low, listing.ID, 4, "1")
+
+	// Harga listing turun ke 1.5: tawaran 1.5 dan counter 1.9 tidak lagi di bawah harga listing
+	if err := uc.RepriceListing(ctx, seller, listing.ID, decimal.RequireFromString("1.5")); err != nil {
+		t.Fatalf("reprice: %v", err)
+	}
+	assertOfferStatus(t, db, pending.ID, domain.OfferExpired)
+	assertOfferStatus(t, db, counter.ID, domain.OfferExpired)
+	assertOfferStatus(t, db, cheap.ID, domain.OfferPending)
+	assertUSDT(t, db, buyer, "100")
+	assertUSDT(t, db, countered, "100")
+
+	// Fill menyisakan 3 item, kurang dari offer 4 item
+	if _, err := uc.BuyItem(ctx, taker, listing.ID, 7); err != nil {
+		t.Fatalf("buy: %v", err)
+	}
+	assertOfferStatus(t, db, cheap.ID, domain.OfferExpired)
+	assertUSDT(t, db, low, "100")
+	if got := accountBalance(t, db, marketEscrowUSDT); !got.IsZero() {
+		t.Fatalf("escrow USDT = %s, harus 0", got)
+	}
+}
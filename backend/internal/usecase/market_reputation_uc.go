@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordTradingStats menambahkan satu fill ke agregat reputasi penjual dan pembeli. Dipanggil dari
+// executeTrade setelah fill dibuat; baris users kedua pihak sudah dikunci oleh pemanggil.
+func recordTradingStats(tx *gorm.DB, fill *domain.MarketFill, listedAt time.Time) error {
+	sellSeconds := int64(fill.CreatedAt.Sub(listedAt).Seconds())
+	if sellSeconds < 0 {
+		sellSeconds = 0
+	}
+
+	// Urutan leksikografis sama dengan lockUsersSorted
+	parties := []uuid.UUID{fill.SellerID, fill.BuyerID}
+	sort.Slice(parties, func(i, j int) bool { return parties[i].String() < parties[j].String() })
+	for _, userID := range parties {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&domain.TradingStat{UserID: userID}).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"volume_usdt": gorm.Expr("volume_usdt + ?", fill.TotalUSDT),
+			"updated_at":  time.Now(),
+		}
+		counterparty := fill.BuyerID
+		if userID == fill.SellerID {
+			updates["trades_as_seller"] = gorm.Expr("trades_as_seller + 1")
+			updates["sell_seconds"] = gorm.Expr("sell_seconds + ?", sellSeconds)
+		} else {
+			updates["trades_as_buyer"] = gorm.Expr("trades_as_buyer + 1")
+			counterparty = fill.SellerID
+		}
+		if err := tx.Model(&domain.TradingStat{}).Where("user_id = ?", userID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&domain.TradingCounterparty{UserID: userID, CounterpartyID: counterparty}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.TradingCounterparty{}).
+			Where("user_id = ? AND counterparty_id = ?", userID, counterparty).
+			Update("trades", gorm.Expr("trades + 1")).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tradingProfiles menyusun TradingProfile untuk beberapa user sekaligus (satu query per sumber data).
+func tradingProfiles(db *gorm.DB, userIDs []uuid.UUID) (map[uuid.UUID]*domain.TradingProfile, error) {
+	profiles := make(map[uuid.UUID]*domain.TradingProfile, len(userIDs))
+	if len(userIDs) == 0 {
+		return profiles, nil
+	}
+
+	var users []domain.User
+	if err := db.Select("id", "wallet_address", "created_at").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		profiles[u.ID] = &domain.TradingProfile{
+			WalletAddress: u.WalletAddress,
+			VolumeUSDT:    decimal.Zero,
+			CancelRate:    decimal.Zero,
+			MemberSince:   u.CreatedAt,
+		}
+	}
+
+	var stats []domain.TradingStat
+	if err := db.Where("user_id IN ?", userIDs).Find(&stats).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range stats {
+		p, ok := profiles[s.UserID]
+		if !ok {
+			continue
+		}
+		p.TradesAsSeller = s.TradesAsSeller
+		p.TradesAsBuyer = s.TradesAsBuyer
+		p.CompletedTrades = s.TradesAsSeller + s.TradesAsBuyer
+		p.VolumeUSDT = s.VolumeUSDT
+		if s.TradesAsSeller > 0 {
+			p.AvgTimeToSellSeconds = s.SellSeconds / s.TradesAsSeller
+		}
+	}
+
+	var listings []struct {
+		SellerID  uuid.UUID
+		Created   int64
+		Cancelled int64
+	}
+	if err := db.Model(&domain.MarketListing{}).
+		Select("seller_id, COUNT(*) AS created, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS cancelled", domain.ListingCancelled).
+		Where("seller_id IN ?", userIDs).Group("seller_id").Scan(&listings).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range listings {
+		p, ok := profiles[l.SellerID]
+		if !ok {
+			continue
+		}
+		p.ListingsCreated = l.Created
+		p.ListingsCancelled = l.Cancelled
+		if l.Created > 0 {
+			p.CancelRate = decimal.NewFromInt(l.Cancelled).DivRound(decimal.NewFromInt(l.Created), 4)
+		}
+	}
+
+	var counterparties []struct {
+		UserID      uuid.UUID
+		UniqueCount int64
+		RepeatCount int64
+	}
+	if err := db.Model(&domain.TradingCounterparty{}).
+		Select("user_id, COUNT(*) AS unique_count, SUM(CASE WHEN trades >= 2 THEN 1 ELSE 0 END) AS repeat_count").
+		Where("user_id IN ?", userIDs).Group("user_id").Scan(&counterparties).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range counterparties {
+		if p, ok := profiles[c.UserID]; ok {
+			p.UniqueCounterparties = c.UniqueCount
+			p.RepeatCounterparties = c.RepeatCount
+		}
+	}
+	return profiles, nil
+}
+
+// attachSellerStats mengisi SellerStats listing dengan reputasi penjualnya (tanpa wallet, sudah ada di listing).
+func attachSellerStats(db *gorm.DB, listings []domain.MarketListing) error {
+	sellerIDs := make([]uuid.UUID, 0, len(listings))
+	seen := make(map[uuid.UUID]bool, len(listings))
+	for _, l := range listings {
+		if !seen[l.SellerID] {
+			seen[l.SellerID] = true
+			sellerIDs = append(sellerIDs, l.SellerID)
+		}
+	}
+
+	profiles, err := tradingProfiles(db, sellerIDs)
+	if err != nil {
+		return err
+	}
+	for i := range listings {
+		if p, ok := profiles[listings[i].SellerID]; ok {
+			stats := *p
+			stats.WalletAddress = ""
+			listings[i].SellerStats = &stats
+		}
+	}
+	return nil
+}
+
+// GetTradingProfile mengembalikan reputasi trading publik sebuah wallet.
+func (uc *MarketDataUsecase) GetTradingProfile(ctx context.Context, wallet string) (*domain.TradingProfile, error) {
+	db := uc.db.WithContext(ctx)
+	var user domain.User
+	if err := db.Select("id").Where("wallet_address = ?", strings.ToLower(wallet)).First(&user).Error; err != nil {
+		return nil, errors.New("User tidak ditemukan")
+	}
+
+	profiles, err := tradingProfiles(db, []uuid.UUID{user.ID})
+	if err != nil {
+		return nil, errors.New("Gagal mengambil profil trading")
+	}
+	profile, ok := profiles[user.ID]
+	if !ok {
+		return nil, errors.New("User tidak ditemukan")
+	}
+	return profile, nil
+}
+
+// BackfillTradingStats membangun agregat reputasi dari riwayat trade (fill dan MARKET_BUY pra-ledger)
+// jika tabel statistik masih kosong. Aman dipanggil setiap startup.
+func (uc *MarketDataUsecase) BackfillTradingStats(ctx context.Context) {
+	lockKey := "market_reputation_backfill"
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Minute)
+	if !acquired {
+		return
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	db := uc.db.WithContext(ctx)
+	var count int64
+	if err := db.Model(&domain.TradingStat{}).Count(&count).Error; err != nil {
+		log.Printf("[SEED] Failed to check trading stats: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	type trade struct {
+		BuyerID   uuid.UUID
+		SellerID  uuid.UUID
+		Total     decimal.Decimal
+		CreatedAt time.Time
+		ListedAt  time.Time
+	}
+
+	var trades []trade
+	if err := db.Table("tx_logs AS t").
+		Select("t.user_id AS buyer_id, l.seller_id, t.amount AS total, t.created_at, l.created_at AS listed_at").
+		Joins("JOIN market_listings l ON l.id = t.reference_id").
+		Where("t.type = ? AND t.entry_id IS NULL AND t.status = ? AND l.quantity > 0", "MARKET_BUY", domain.TxSuccess).
+		Scan(&trades).Error; err != nil {
+		log.Printf("[SEED] Failed to read legacy market trades: %v", err)
+		return
+	}
+	var fills []trade
+	if err := db.Table("market_fills AS f").
+		Select("f.buyer_id, f.seller_id, f.total_usdt AS total, f.created_at, l.created_at AS listed_at").
+		Joins("JOIN market_listings l ON l.id = f.listing_id").
+		Scan(&fills).Error; err != nil {
+		log.Printf("[SEED] Failed to read market fills: %v", err)
+		return
+	}
+	trades = append(trades, fills...)
+	if len(trades) == 0 {
+		return
+	}
+
+	stats := make(map[uuid.UUID]*domain.TradingStat)
+	statFor := func(id uuid.UUID) *domain.TradingStat {
+		s, ok := stats[id]
+		if !ok {
+			s = &domain.TradingStat{UserID: id, VolumeUSDT: decimal.Zero}
+			stats[id] = s
+		}
+		return s
+	}
+	pairs := make(map[[2]uuid.UUID]int64)
+	for _, t := range trades {
+		seller := statFor(t.SellerID)
+		seller.TradesAsSeller++
+		seller.VolumeUSDT = seller.VolumeUSDT.Add(t.Total)
+		if d := int64(t.CreatedAt.Sub(t.ListedAt).Seconds()); d > 0 {
+			seller.SellSeconds += d
+		}
+		buyer := statFor(t.BuyerID)
+		buyer.TradesAsBuyer++
+		buyer.VolumeUSDT = buyer.VolumeUSDT.Add(t.Total)
+
+		pairs[[2]uuid.UUID{t.SellerID, t.BuyerID}]++
+		pairs[[2]uuid.UUID{t.BuyerID, t.SellerID}]++
+	}
+
+	statRows := make([]domain.TradingStat, 0, len(stats))
+	for _, s := range stats {
+		statRows = append(statRows, *s)
+	}
+	pairRows := make([]domain.TradingCounterparty, 0, len(pairs))
+	for key, n := range pairs {
+		pairRows = append(pairRows, domain.TradingCounterparty{UserID: key[0], CounterpartyID: key[1], Trades: n})
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(statRows, 500).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(pairRows, 500).Error
+	})
+	if err != nil {
+		log.Printf("[SEED] Failed to backfill trading stats: %v", err)
+		return
+	}
+	log.Printf("[SEED] Trading stats backfilled from %d trades (%d users)", len(trades), len(statRows))
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultListingPageSize = 20
+	maxListingPageSize     = 100
+)
+
+// Urutan hasil pencarian listing
+const (
+	ListingSortNewest    = "newest" // Default
+	ListingSortOldest    = "oldest"
+	ListingSortPriceAsc  = "price_asc" // Harga per unit termurah dulu
+	ListingSortPriceDesc = "price_desc"
+)
+
+// ListingFilter adalah filter pencarian marketplace. Field kosong berarti tanpa filter.
+type ListingFilter struct {
+	ItemType     string
+	MinUnitPrice *decimal.Decimal
+	MaxUnitPrice *decimal.Decimal
+	MinQuantity  int // Sisa quantity minimum
+	SellerWallet string
+	Sort         string
+	Cursor       string
+	Limit        int
+}
+
+type ListingPage struct {
+	Listings   []domain.MarketListing `json:"listings"`
+	NextCursor string                 `json:"-"`
+	HasMore    bool                   `json:"-"`
+	Limit      int                    `json:"-"`
+}
+
+// encodeListingCursor membuat cursor opaque dari listing terakhir; kunci cursor mengikuti urutan
+// (harga per unit untuk sort harga, created_at untuk sort waktu) dan selalu diakhiri ID sebagai tie-breaker.
+func encodeListingCursor(sort string, l domain.MarketListing) string {
+	key := strconv.FormatInt(l.CreatedAt.UnixNano(), 10)
+	if sort == ListingSortPriceAsc || sort == ListingSortPriceDesc {
+		key = l.UnitPriceUSDT.String()
+	}
+	raw := fmt.Sprintf("%s|%s|%s", sort, key, l.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeListingCursor(sort, cursor string) (string, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", uuid.Nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 || parts[0] != sort {
+		return "", uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return "", uuid.Nil, ErrInvalidCursor
+	}
+	return parts[1], id, nil
+}
+
+// SearchListings mengembalikan listing yang masih bisa dibeli sesuai filter dengan cursor pagination.
+func (uc *MarketUsecase) SearchListings(ctx context.Context, f ListingFilter) (*ListingPage, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultListingPageSize
+	}
+	if f.Limit > maxListingPageSize {
+		f.Limit = maxListingPageSize
+	}
+	if f.Sort == "" {
+		f.Sort = ListingSortNewest
+	}
+	if f.MinUnitPrice != nil && f.MaxUnitPrice != nil && f.MinUnitPrice.GreaterThan(*f.MaxUnitPrice) {
+		return nil, errors.New("Rentang harga tidak valid")
+	}
+
+	db := uc.db.WithContext(ctx)
+	query := db.Model(&domain.MarketListing{}).
+		Where("status IN ?", activeOrderStatuses).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
+	if f.ItemType != "" {
+		query = query.Where("item_type = ?", f.ItemType)
+	}
+	if f.MinUnitPrice != nil {
+		query = query.Where("unit_price_usdt >= ?", *f.MinUnitPrice)
+	}
+	if f.MaxUnitPrice != nil {
+		query = query.Where("unit_price_usdt <= ?", *f.MaxUnitPrice)
+	}
+	if f.MinQuantity > 0 {
+		query = query.Where("remaining_quantity >= ?", f.MinQuantity)
+	}
+	if f.SellerWallet != "" {
+		query = query.Where("seller_id IN (?)",
+			db.Model(&domain.User{}).Select("id").Where("wallet_address = ?", strings.ToLower(f.SellerWallet)))
+	}
+
+	var cursorKey string
+	var cursorID uuid.UUID
+	if f.Cursor != "" {
+		var err error
+		if cursorKey, cursorID, err = decodeListingCursor(f.Sort, f.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	switch f.Sort {
+	case ListingSortPriceAsc, ListingSortPriceDesc:
+		op, dir := ">", "ASC"
+		if f.Sort == ListingSortPriceDesc {
+			op, dir = "<", "DESC"
+		}
+		if f.Cursor != "" {
+			price, err := decimal.NewFromString(cursorKey)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			query = query.Where("unit_price_usdt "+op+" ? OR (unit_price_usdt = ? AND id "+op+" ?)", price, price, cursorID)
+		}
+		query = query.Order("unit_price_usdt " + dir + ", id " + dir)
+	case ListingSortNewest, ListingSortOldest:
+		op, dir := "<", "DESC"
+		if f.Sort == ListingSortOldest {
+			op, dir = ">", "ASC"
+		}
+		if f.Cursor != "" {
+			nanos, err := strconv.ParseInt(cursorKey, 10, 64)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			createdAt := time.Unix(0, nanos)
+			query = query.Where("created_at "+op+" ? OR (created_at = ? AND id "+op+" ?)", createdAt, createdAt, cursorID)
+		}
+		query = query.Order("created_at " + dir + ", id " + dir)
+	default:
+		return nil, errors.New("Urutan tidak valid, gunakan newest, oldest, price_asc atau price_desc")
+	}
+
+	listings := make([]domain.MarketListing, 0)
+	if err := query.Limit(f.Limit + 1).Find(&listings).Error; err != nil {
+		return nil, errors.New("Gagal mengambil data marketplace")
+	}
+
+	page := &ListingPage{Limit: f.Limit}
+	if len(listings) > f.Limit {
+		listings = listings[:f.Limit]
+		page.HasMore = true
+		page.NextCursor = encodeListingCursor(f.Sort, listings[len(listings)-1])
+	}
+	if err := attachCowSnapshots(db, listings); err != nil {
+		return nil, errors.New("Gagal mengambil data marketplace")
+	}
+	if err := attachSellerStats(db, listings); err != nil {
+		return nil, errors.New("Gagal mengambil data marketplace")
+	}
+	page.Listings = listings
+	return page, nil
+}
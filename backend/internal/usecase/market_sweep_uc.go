@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// SweepResult adalah hasil sweep-buy. Quantity bisa lebih kecil dari yang diminta jika listing
+// habis atau batas MaxTotalUSDT tercapai.
+type SweepResult struct {
+	Quantity     int                 `json:"quantity"`
+	TotalUSDT    decimal.Decimal     `json:"total_usdt"` // Termasuk fee taker
+	AveragePrice decimal.Decimal     `json:"average_price_usdt"`
+	Fills        []domain.MarketFill `json:"fills"`
+}
+
+// planSweep memilih berapa item yang diambil dari tiap listing (sudah terurut termurah dulu) tanpa
+// melebihi `quantity` maupun `budget` (termasuk fee taker). Berhenti di listing pertama yang tidak
+// terjangkau sama sekali karena listing setelahnya pasti tidak lebih murah. Mengembalikan jumlah per
+// listing dan total biaya.
+func planSweep(listings []domain.MarketListing, quantity int, budget decimal.Decimal, fees *marketFees) ([]int, decimal.Decimal) {
+	plan := make([]int, 0, len(listings))
+	spent := decimal.Zero
+	need := quantity
+	for _, l := range listings {
+		if need == 0 {
+			break
+		}
+		qty := min(need, l.RemainingQuantity)
+		unitCost := l.UnitPriceUSDT.Add(l.UnitPriceUSDT.Mul(fees.Taker))
+		affordable := budget.Sub(spent).Div(unitCost).Floor().IntPart()
+		if int64(qty) > affordable {
+			qty = int(affordable)
+		}
+		if qty == 0 {
+			break
+		}
+		value := l.UnitPriceUSDT.Mul(decimal.NewFromInt(int64(qty)))
+		plan = append(plan, qty)
+		spent = spent.Add(value).Add(fees.charge(value, true))
+		need -= qty
+	}
+	return plan, spent
+}
+
+// SweepBuy membeli hingga `quantity` item dari listing OPEN termurah secara atomik, dengan total
+// pembayaran (termasuk fee taker) tidak melebihi maxTotalUSDT. Listing dikunci FOR UPDATE dengan urutan harga-waktu yang
+// sama seperti order book, lalu semua seller + buyer dikunci leksikografis (lockUsersSorted) sehingga
+// sweep yang menyentuh banyak seller tidak deadlock dengan BuyItem/PlaceBid paralel.
+func (uc *MarketUsecase) SweepBuy(ctx context.Context, buyerID uuid.UUID, itemType string, quantity int, maxTotalUSDT decimal.Decimal) (*SweepResult, error) {
+	if !orderBookItems[itemType] {
+		return nil, errors.New("Tipe item tidak valid, hanya GRASS atau MILK")
+	}
+	if quantity <= 0 {
+		return nil, errors.New("Jumlah item harus lebih dari 0")
+	}
+	if !maxTotalUSDT.IsPositive() {
+		return nil, errors.New("Batas total USDT harus lebih dari 0")
+	}
+
+	// Lock yang sama dengan BuyItem: satu pembelian per buyer dalam satu waktu
+	lockKey := "market_buy:" + buyerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return nil, errors.New("Transaksi pembelian sedang diproses...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	result := SweepResult{Fills: make([]domain.MarketFill, 0)}
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		var listings []domain.MarketListing
+		if err := askQuery(tx, itemType, buyerID).Find(&listings).Error; err != nil {
+			return err
+		}
+
+		fees, err := loadMarketFees(tx, time.Now())
+		if err != nil {
+			return err
+		}
+		plan, total := planSweep(listings, quantity, maxTotalUSDT, fees)
+		if len(plan) == 0 {
+			return errors.New("Tidak ada listing yang bisa dibeli dengan batas total tersebut")
+		}
+
+		userIDs := []uuid.UUID{buyerID}
+		for i := range plan {
+			userIDs = append(userIDs, listings[i].SellerID)
+		}
+		if err := lockUsersSorted(tx, userIDs...); err != nil {
+			return err
+		}
+
+		var buyer domain.User
+		if err := tx.Where("id = ?", buyerID).First(&buyer).Error; err != nil {
+			return errors.New("User tidak ditemukan")
+		}
+		if buyer.USDTBalance.LessThan(total) {
+			return errors.New("Saldo USDT tidak mencukupi")
+		}
+
+		for i, qty := range plan {
+			listing := &listings[i]
+			fill, err := executeTrade(tx, fees, listing, nil, buyerID, qty, listing.UnitPriceUSDT, domain.TakerBuy)
+			if err != nil {
+				return err
+			}
+			result.Fills = append(result.Fills, *fill)
+			result.Quantity += qty
+		}
+		result.TotalUSDT = total
+		result.AveragePrice = total.DivRound(decimal.NewFromInt(int64(result.Quantity)), 8) // Termasuk fee
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
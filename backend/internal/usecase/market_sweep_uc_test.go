@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"slices"
+	"testing"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPlanSweep(t *testing.T) {
+	fees := &marketFees{Maker: decimal.RequireFromString("0.01"), Taker: decimal.RequireFromString("0.02")}
+	type ask struct {
+		qty   int
+		price string
+	}
+	cases := []struct {
+		name      string
+		asks      []ask // Terurut termurah dulu, seperti askQuery
+		quantity  int
+		budget    string
+		wantPlan  []int
+		wantTotal string
+	}{
+		{"budget habis di tengah listing", []ask{{5, "1"}, {10, "2"}}, 20, "10", []int{5, 2}, "9.18"},
+		{"listing pertama tidak terjangkau", []ask{{5, "3"}, {5, "4"}}, 5, "3", []int{}, "0"},
+		{"jumlah melintasi beberapa listing", []ask{{2, "1"}, {3, "1.5"}, {4, "2"}}, 6, "100", []int{2, 3, 1}, "8.67"},
+		{"listing habis sebelum jumlah terpenuhi", []ask{{2, "1"}, {1, "1.5"}}, 10, "100", []int{2, 1}, "3.57"},
+		// Nilai 0.99999999, fee 0.0199999998 dipotong ke 0.01999999
+		{"fee taker dipotong 8 desimal", []ask{{3, "0.33333333"}}, 3, "1.02", []int{3}, "1.01999998"},
+		// Keterjangkauan dihitung dengan fee tanpa pemotongan, jadi budget pas-pasan tidak pernah terlampaui
+		{"budget sama dengan total terpotong", []ask{{3, "0.33333333"}}, 3, "1.01999998", []int{2}, "0.67999999"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listings := make([]domain.MarketListing, len(tc.asks))
+			for i, a := range tc.asks {
+				listings[i] = domain.MarketListing{RemainingQuantity: a.qty, UnitPriceUSDT: decimal.RequireFromString(a.price)}
+			}
+			budget := decimal.RequireFromString(tc.budget)
+
+			plan, total := planSweep(listings, tc.quantity, budget, fees)
+			if !slices.Equal(plan, tc.wantPlan) {
+				t.Fatalf("plan = %v, harus %v", plan, tc.wantPlan)
+			}
+			if !total.Equal(decimal.RequireFromString(tc.wantTotal)) {
+				t.Fatalf("total = %s, harus %s", total, tc.wantTotal)
+			}
+			if total.GreaterThan(budget) {
+				t.Fatalf("total %s melebihi batas %s", total, budget)
+			}
+
+			// Total harus sama dengan yang benar-benar dibayar executeTrade per listing
+			paid := decimal.Zero
+			for i, qty := range plan {
+				value := listings[i].UnitPriceUSDT.Mul(decimal.NewFromInt(int64(qty)))
+				paid = paid.Add(value).Add(fees.charge(value, true))
+			}
+			if !paid.Equal(total) {
+				t.Fatalf("total = %s, tetapi pembayaran per fill = %s", total, paid)
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+	"cashcowvalley/backend/pkg/realtime"
 	customRedis "cashcowvalley/backend/pkg/redis"
 
 	"github.com/google/uuid"
@@ -23,12 +26,17 @@ func NewMarketUsecase(db *gorm.DB) *MarketUsecase {
 }
 
 // BuyItem memproses pembelian item P2P menggunakan Pessimistic Locking (FOR UPDATE).
-func (uc *MarketUsecase) BuyItem(ctx context.Context, buyerID uuid.UUID, listingID uuid.UUID) error {
+// quantity 0 berarti membeli seluruh sisa listing; selain itu listing bisa terisi sebagian.
+func (uc *MarketUsecase) BuyItem(ctx context.Context, buyerID uuid.UUID, listingID uuid.UUID, quantity int) (*domain.MarketFill, error) {
+	if quantity < 0 {
+		return nil, errors.New("Jumlah pembelian tidak valid")
+	}
+
 	// 1. Redlock Pembeli agar tidak spam klik "Beli"
 	lockKey := "market_buy:" + buyerID.String()
 	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
 	if !acquired {
-		return errors.New("Transaksi pembelian sedang diproses...")
+		return nil, errors.New("Transaksi pembelian sedang diproses...")
 	}
 	defer customRedis.ReleaseLock(ctx, lockKey, token)
 
@@ -38,7 +46,8 @@ func (uc *MarketUsecase) BuyItem(ctx context.Context, buyerID uuid.UUID, listing
 	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
 	defer cancel()
 
-	return uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+	var fill domain.MarketFill
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
 		var listing domain.MarketListing
 
 		// ROW-LEVEL LOCKING (FOR UPDATE)
@@ -50,162 +59,307 @@ func (uc *MarketUsecase) BuyItem(ctx context.Context, buyerID uuid.UUID, listing
 			return errors.New("Listing tidak ditemukan")
 		}
 
-		if listing.Status != "OPEN" {
+		if !listing.IsActive() {
 			return errors.New("Item sudah terjual atau ditarik oleh penjual")
 		}
+		if listing.IsExpired(time.Now()) {
+			return errors.New("Listing sudah kedaluwarsa")
+		}
 
 		if listing.SellerID == buyerID {
 			return errors.New("Tidak dapat membeli barang sendiri")
 		}
 
+		fillQty := quantity
+		if fillQty == 0 {
+			fillQty = listing.RemainingQuantity
+		}
+		if fillQty > listing.RemainingQuantity {
+			return fmt.Errorf("Sisa listing hanya %d item", listing.RemainingQuantity)
+		}
+		fees, err := loadMarketFees(tx, time.Now())
+		if err != nil {
+			return err
+		}
+		// Pembeli manual selalu taker (listing sudah resting di buku)
+		total := listing.UnitPriceUSDT.Mul(decimal.NewFromInt(int64(fillQty)))
+		total = total.Add(fees.charge(total, true))
+
 		// Kunci User Pembeli dan Penjual secara leksikografis untuk MENCEGAH DEADLOCK.
 		// Jika User A beli dari B, dan B beli dari A bersamaan, tanpa pengurutan ini Postgres akan Deadlock.
-		buyerIDStr := buyerID.String()
-		sellerIDStr := listing.SellerID.String()
+		// Urutan yang sama dipakai order book dan settlement lelang (lockUsersSorted).
+		if err := lockUsersSorted(tx, buyerID, listing.SellerID); err != nil {
+			return err
+		}
+		var buyer domain.User
+		if err := tx.Where("id = ?", buyerID).First(&buyer).Error; err != nil {
+			return err
+		}
 
-		var firstLockID, secondLockID string
-		var isBuyerFirst bool
+		// Operasi Pengurangan USDT Menggunakan math/big (shopspring/decimal) agar PRESISI MUTLAK
+		if buyer.USDTBalance.LessThan(total) {
+			return errors.New("Saldo USDT tidak mencukupi")
+		}
 
-		if buyerIDStr < sellerIDStr {
-			firstLockID = buyerIDStr
-			secondLockID = sellerIDStr
-			isBuyerFirst = true
-		} else {
-			firstLockID = sellerIDStr
-			secondLockID = buyerIDStr
-			isBuyerFirst = false
+		// BUG FIX: Handle SEMUA tipe item, bukan hanya GRASS
+		// Sebelumnya MILK silently dropped — pembeli bayar USDT tapi item tidak masuk!
+		if listing.ItemType != domain.CurrencyGrass && listing.ItemType != domain.CurrencyMilk && listing.ItemType != domain.ItemCattle {
+			return errors.New("Tipe item tidak dikenali")
 		}
 
-		// Kunci dengan urutan absolut (Lexicographical)
-		var firstUser, secondUser domain.User
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", firstLockID).First(&firstUser).Error; err != nil {
+		// Satu Journal Entry per fill: USDT pembeli -> penjual, item escrow -> inventory pembeli
+		// (untuk CATTLE: Cow.OwnerID pindah ke pembeli dalam transaksi yang sama).
+		// Ledger juga memperbarui saldo User/Inventory dan menulis TxLog untuk kedua pihak.
+		result, err := executeTrade(tx, fees, &listing, nil, buyer.ID, fillQty, listing.UnitPriceUSDT, domain.TakerBuy)
+		if err != nil {
 			return err
 		}
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", secondLockID).First(&secondUser).Error; err != nil {
+		fill = *result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &fill, nil
+}
+
+// GetListingFills mengembalikan riwayat fill sebuah listing (terlama dulu).
+func (uc *MarketUsecase) GetListingFills(ctx context.Context, listingID uuid.UUID) ([]domain.MarketFill, error) {
+	var fills []domain.MarketFill
+	if err := uc.db.WithContext(ctx).Where("listing_id = ?", listingID).
+		Order("created_at").Find(&fills).Error; err != nil {
+		return nil, err
+	}
+	return fills, nil
+}
+
+// GetListingPriceHistory mengembalikan riwayat perubahan harga sebuah listing (terlama dulu).
+func (uc *MarketUsecase) GetListingPriceHistory(ctx context.Context, listingID uuid.UUID) ([]domain.MarketListingPriceChange, error) {
+	var changes []domain.MarketListingPriceChange
+	if err := uc.db.WithContext(ctx).Where("listing_id = ?", listingID).
+		Order("created_at").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// validateListingPrice memvalidasi harga per unit listing.
+func validateListingPrice(unitPriceUSDT decimal.Decimal) error {
+	minPrice := decimal.NewFromFloat(0.01)
+	maxPrice := decimal.NewFromInt(10000)
+	if unitPriceUSDT.LessThan(minPrice) || unitPriceUSDT.GreaterThan(maxPrice) {
+		return errors.New("Harga per unit harus antara 0.01 dan 10000 USDT")
+	}
+	if !unitPriceUSDT.Equal(unitPriceUSDT.Truncate(8)) {
+		return errors.New("Harga per unit maksimal 8 angka desimal")
+	}
+	return nil
+}
+
+// validateListingExpiry memastikan expires_at (jika diisi) berada antara 1 jam dan 30 hari ke depan.
+func validateListingExpiry(expiresAt *time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+	now := time.Now()
+	if expiresAt.Before(now.Add(minListingLifetime)) {
+		return errors.New("expires_at minimal 1 jam dari sekarang")
+	}
+	if expiresAt.After(now.Add(maxListingLifetime)) {
+		return errors.New("expires_at maksimal 30 hari dari sekarang")
+	}
+	return nil
+}
+
+// lockOwnListing mengunci listing aktif milik seller (FOR UPDATE) untuk diubah oleh penjualnya.
+func lockOwnListing(tx *gorm.DB, sellerID, listingID uuid.UUID) (*domain.MarketListing, error) {
+	var listing domain.MarketListing
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", listingID).
+		First(&listing).Error; err != nil {
+		return nil, errors.New("Listing tidak ditemukan")
+	}
+	if listing.SellerID != sellerID {
+		return nil, errors.New("Listing ini bukan milik Anda")
+	}
+	if !listing.IsActive() {
+		return nil, errors.New("Listing sudah tidak aktif")
+	}
+	return &listing, nil
+}
+
+// CancelListing menarik listing aktif dan mengembalikan sisa item dari escrow ke inventory penjual.
+// Item yang sudah terjual lewat fill sebelumnya tetap milik pembelinya.
+func (uc *MarketUsecase) CancelListing(ctx context.Context, sellerID, listingID uuid.UUID) error {
+	// Lock yang sama dengan SellItem: satu operasi listing per seller dalam satu waktu
+	lockKey := "market_sell:" + sellerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return errors.New("Proses listing sedang berjalan...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	return uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		// Listing dikunci lebih dulu agar pembeli yang sedang membeli listing ini tertahan
+		listing, err := lockOwnListing(tx, sellerID, listingID)
+		if err != nil {
 			return err
 		}
 
-		// Petakan kembali ke identitas Pembeli / Penjual
-		var buyer, seller *domain.User
-		if isBuyerFirst {
-			buyer = &firstUser
-			seller = &secondUser
+		entryRef := "market_cancel:" + listingID.String()
+		if listing.ItemType == domain.ItemCattle {
+			if err := returnListedCow(tx, *listing.CowID, listing.ID, sellerID, entryRef); err != nil {
+				return err
+			}
 		} else {
-			buyer = &secondUser
-			seller = &firstUser
+			// Item kembali dari escrow marketplace ke inventory penjual (TxLog ditulis oleh ledger)
+			entry := ledger.Transfer("MARKET_CANCEL",
+				ledger.Escrow(domain.BucketMarket, listing.ItemType),
+				ledger.User(sellerID, listing.ItemType),
+				decimal.NewFromInt(int64(listing.RemainingQuantity)))
+			entry.ReferenceID = &entryRef
+			if _, err := ledger.Post(tx, entry); err != nil {
+				return err
+			}
 		}
 
-		// Operasi Pengurangan USDT Menggunakan math/big (shopspring/decimal) agar PRESISI MUTLAK
-		if buyer.USDTBalance.LessThan(listing.PriceUSDT) {
-			return errors.New("Saldo USDT tidak mencukupi")
-		}
-		buyer.USDTBalance = buyer.USDTBalance.Sub(listing.PriceUSDT)
-		if err := tx.Save(buyer).Error; err != nil {
+		return tx.Model(listing).Updates(map[string]interface{}{
+			"remaining_quantity": 0,
+			"status":             domain.ListingCancelled,
+		}).Error
+	})
+}
+
+// RepriceListing mengubah harga per unit listing aktif. Tidak ada saldo yang bergerak, perubahan harga
+// dicatat di MarketListingPriceChange untuk audit.
+func (uc *MarketUsecase) RepriceListing(ctx context.Context, sellerID, listingID uuid.UUID, unitPriceUSDT decimal.Decimal) error {
+	if err := validateListingPrice(unitPriceUSDT); err != nil {
+		return err
+	}
+
+	lockKey := "market_sell:" + sellerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return errors.New("Proses listing sedang berjalan...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	more := false
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		listing, err := lockOwnListing(tx, sellerID, listingID)
+		if err != nil {
 			return err
 		}
+		if listing.IsExpired(time.Now()) {
+			return errors.New("Listing sudah kedaluwarsa")
+		}
+		if listing.UnitPriceUSDT.Equal(unitPriceUSDT) {
+			return errors.New("Harga baru sama dengan harga lama")
+		}
 
-		seller.USDTBalance = seller.USDTBalance.Add(listing.PriceUSDT)
-		if err := tx.Save(seller).Error; err != nil {
+		if err := tx.Create(&domain.MarketListingPriceChange{
+			ListingID:        listing.ID,
+			SellerID:         sellerID,
+			OldUnitPriceUSDT: listing.UnitPriceUSDT,
+			NewUnitPriceUSDT: unitPriceUSDT,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(listing).Updates(map[string]interface{}{
+			"unit_price_usdt": unitPriceUSDT,
+			"price_usdt":      unitPriceUSDT.Mul(decimal.NewFromInt(int64(listing.Quantity))),
+		}).Error; err != nil {
 			return err
 		}
+		listing.UnitPriceUSDT = unitPriceUSDT
 
-		// Transfer Item (Misal GRASS) ke Inventory Pembeli
-		var buyerInv domain.Inventory
-		// BUG FIX: Tambahkan Pessimistic Lock (FOR UPDATE) pada tabel Inventory Pembeli.
-		// Memastikan jika AdWebhook menambah Rumput/Susu ke pembeli di detik yang sama persis, data tidak akan saling tindih!
-		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", buyerID).First(&buyerInv).Error
+		// Harga turun bisa menyilang bid yang sudah ada
+		bids, err := lockCrossingBids(tx, listing.ItemType, sellerID, unitPriceUSDT, listing.RemainingQuantity)
 		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// Jika pembeli belum punya tabel inventory, kita buatkan on-the-fly
-				buyerInv = domain.Inventory{
-					UserID: buyerID,
-					Grass:  0,
-					Milk:   0,
-				}
-				if err := tx.Create(&buyerInv).Error; err != nil {
-					return errors.New("Gagal membuat inventory baru untuk pembeli")
-				}
-			} else {
-				return errors.New("Inventory pembeli corrupt")
-			}
+			return err
 		}
-
-		// BUG FIX: Handle SEMUA tipe item, bukan hanya GRASS
-		// Sebelumnya MILK silently dropped — pembeli bayar USDT tapi item tidak masuk!
-		switch listing.ItemType {
-		case "GRASS":
-			buyerInv.Grass += listing.Quantity
-		case "MILK":
-			buyerInv.Milk += listing.Quantity
-		default:
-			return errors.New("Tipe item tidak dikenali")
+		userIDs := []uuid.UUID{sellerID}
+		for _, b := range bids {
+			userIDs = append(userIDs, b.BuyerID)
 		}
-		if err := tx.Save(&buyerInv).Error; err != nil {
+		if err := lockUsersSorted(tx, userIDs...); err != nil {
 			return err
 		}
-
-		// Tutup Listing
-		listing.Status = "SOLD"
-		if err := tx.Save(&listing).Error; err != nil {
+		fees, err := loadMarketFees(tx, time.Now())
+		if err != nil {
 			return err
 		}
-
-		// BUG FIX: Audit Trail untuk transaksi Marketplace (sebelumnya tidak ada)
-		listingIDStr := listingID.String()
-		txLog := domain.TxLog{
-			UserID:      buyerID,
-			Type:        "MARKET_BUY",
-			Amount:      listing.PriceUSDT,
-			Currency:    "USDT",
-			Status:      domain.TxSuccess,
-			ReferenceID: &listingIDStr,
-		}
-		if err := tx.Create(&txLog).Error; err != nil {
+		if _, err := matchListingAgainstBids(tx, fees, listing, bids); err != nil {
 			return err
 		}
+		more = batchFull(len(bids), listing.RemainingQuantity)
 
-		return nil
+		// Harga turun sampai harga tawaran: offer tersebut tidak lagi masuk akal, kembalikan USDT-nya
+		return closeStaleOffers(tx, listing)
 	})
-}
+	if err != nil {
+		return err
+	}
 
-// GetListings mengembalikan semua listing OPEN di marketplace (Read-Only).
-func (uc *MarketUsecase) GetListings(ctx context.Context) ([]domain.MarketListing, error) {
-	var listings []domain.MarketListing
-	if err := uc.db.WithContext(ctx).Where("status = ?", "OPEN").
-		Order("created_at DESC").Find(&listings).Error; err != nil {
-		return nil, errors.New("Gagal mengambil data marketplace")
+	// Masih ada bid menyilang di luar batch pertama: lanjutkan setelah harga baru tersimpan
+	if more {
+		continueListingMatch(ctx, uc.db, listingID)
 	}
-	return listings, nil
+	return nil
 }
 
-// SellItem membuat listing baru di marketplace.
-func (uc *MarketUsecase) SellItem(ctx context.Context, sellerID uuid.UUID, itemType string, quantity int, priceUSDT decimal.Decimal) error {
+// SellItem membuat listing baru di marketplace dengan harga per unit. Listing adalah sisi ask
+// order book: langsung dicocokkan dengan bid tertinggi yang harganya >= harga listing.
+// expiresAt opsional; setelah lewat, sisa item dikembalikan ke penjual oleh sweeper.
+func (uc *MarketUsecase) SellItem(ctx context.Context, sellerID uuid.UUID, itemType string, quantity int, unitPriceUSDT decimal.Decimal, expiresAt *time.Time) (*domain.MarketListing, error) {
 	// Validasi input
 	if itemType != "GRASS" && itemType != "MILK" {
-		return errors.New("Tipe item tidak valid, hanya GRASS atau MILK")
+		return nil, errors.New("Tipe item tidak valid, hanya GRASS atau MILK")
 	}
 	if quantity <= 0 {
-		return errors.New("Jumlah item harus lebih dari 0")
+		return nil, errors.New("Jumlah item harus lebih dari 0")
 	}
-	minPrice := decimal.NewFromFloat(0.01)
-	maxPrice := decimal.NewFromInt(10000)
-	if priceUSDT.LessThan(minPrice) || priceUSDT.GreaterThan(maxPrice) {
-		return errors.New("Harga harus antara 0.01 dan 10000 USDT")
+	if err := validateListingPrice(unitPriceUSDT); err != nil {
+		return nil, err
+	}
+	if err := validateListingExpiry(expiresAt); err != nil {
+		return nil, err
 	}
 
 	// Redlock agar seller tidak spam listing
 	lockKey := "market_sell:" + sellerID.String()
 	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
 	if !acquired {
-		return errors.New("Proses listing sedang berjalan...")
+		return nil, errors.New("Proses listing sedang berjalan...")
 	}
 	defer customRedis.ReleaseLock(ctx, lockKey, token)
 
 	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
 	defer cancel()
 
-	return uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
-		// Kurangi inventory seller
+	var listing domain.MarketListing
+	more := false
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		// Kunci bid lawan lalu semua user yang terlibat (urut leksikografis) sebelum menyentuh saldo
+		bids, err := lockCrossingBids(tx, itemType, sellerID, unitPriceUSDT, quantity)
+		if err != nil {
+			return err
+		}
+		userIDs := []uuid.UUID{sellerID}
+		for _, b := range bids {
+			userIDs = append(userIDs, b.BuyerID)
+		}
+		if err := lockUsersSorted(tx, userIDs...); err != nil {
+			return err
+		}
+
+		// Cek inventory seller
 		var inv domain.Inventory
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("user_id = ?", sellerID).First(&inv).Error; err != nil {
@@ -217,46 +371,63 @@ func (uc *MarketUsecase) SellItem(ctx context.Context, sellerID uuid.UUID, itemT
 			if inv.Grass < quantity {
 				return errors.New("Rumput tidak cukup untuk dijual")
 			}
-			inv.Grass -= quantity
 		case "MILK":
 			if inv.Milk < quantity {
 				return errors.New("Susu tidak cukup untuk dijual")
 			}
-			inv.Milk -= quantity
-		}
-
-		if err := tx.Save(&inv).Error; err != nil {
-			return err
 		}
 
 		// Buat listing
-		listing := domain.MarketListing{
-			SellerID:  sellerID,
-			ItemType:  itemType,
-			Quantity:  quantity,
-			PriceUSDT: priceUSDT,
-			Status:    "OPEN",
+		listing = domain.MarketListing{
+			SellerID:          sellerID,
+			ItemType:          itemType,
+			Quantity:          quantity,
+			RemainingQuantity: quantity,
+			UnitPriceUSDT:     unitPriceUSDT,
+			PriceUSDT:         unitPriceUSDT.Mul(decimal.NewFromInt(int64(quantity))),
+			Status:            domain.ListingOpen,
+			ExpiresAt:         expiresAt,
 		}
 		if err := tx.Create(&listing).Error; err != nil {
 			return errors.New("Gagal membuat listing")
 		}
 
-		// Audit trail
+		// Pindahkan item seller ke escrow marketplace (audit trail ditulis oleh ledger)
 		listingIDStr := listing.ID.String()
-		txLog := domain.TxLog{
-			UserID:      sellerID,
-			Type:        "MARKET_SELL",
-			Amount:      priceUSDT,
-			Currency:    "USDT",
-			Status:      domain.TxSuccess,
-			ReferenceID: &listingIDStr,
-		}
-		if err := tx.Create(&txLog).Error; err != nil {
+		entry := ledger.Transfer("MARKET_SELL",
+			ledger.User(sellerID, itemType),
+			ledger.Escrow(domain.BucketMarket, itemType),
+			decimal.NewFromInt(int64(quantity)))
+		entry.Legs[0].ReferenceID = &listingIDStr
+		if _, err := ledger.Post(tx, entry); err != nil {
 			return err
 		}
 
+		fees, err := loadMarketFees(tx, time.Now())
+		if err != nil {
+			return err
+		}
+		if _, err := matchListingAgainstBids(tx, fees, &listing, bids); err != nil {
+			return err
+		}
+		more = batchFull(len(bids), listing.RemainingQuantity)
+		if listing.RemainingQuantity > 0 {
+			realtime.Emit(ctx, realtime.EventListingCreated, "", listing)
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Masih ada bid menyilang di luar batch pertama: lanjutkan setelah listing tersimpan
+	if more {
+		continueListingMatch(ctx, uc.db, listing.ID)
+		if err := uc.db.WithContext(ctx).Where("id = ?", listing.ID).First(&listing).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &listing, nil
 }
 
 // BuyFromPlatform handles direct purchases from the system (e.g., Minting Cows, Buying Premium Grass).
@@ -271,6 +442,11 @@ func (uc *MarketUsecase) SellMilkForGold(ctx context.Context, userID uuid.UUID,
 	defer cancel()
 
 	return uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
+			return errors.New("User not found")
+		}
+
 		var inv domain.Inventory
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&inv).Error; err != nil {
 			return errors.New("Inventory not found")
@@ -280,52 +456,36 @@ func (uc *MarketUsecase) SellMilkForGold(ctx context.Context, userID uuid.UUID,
 			return errors.New("Not enough milk to sell")
 		}
 
-		inv.Milk -= quantity
-		if err := tx.Save(&inv).Error; err != nil {
+		economy, err := activeEconomyConfig(tx, time.Now())
+		if err != nil {
 			return err
 		}
 
-		var user domain.User
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
-			return errors.New("User not found")
-		}
-
-		// 1 Milk = 5 Gold (Example rate)
-		goldReward := decimal.NewFromInt(int64(quantity * 5))
-		user.GoldBalance = user.GoldBalance.Add(goldReward)
+		milk := decimal.NewFromInt(int64(quantity))
+		goldReward := milk.Mul(economy.Params.MilkToGold).Truncate(8)
 
-		if err := tx.Save(&user).Error; err != nil {
-			return err
-		}
-
-		tx.Create(&domain.TxLog{
-			UserID:   userID,
-			Type:     "SELL_MILK_GOLD",
-			Amount:   goldReward,
-			Currency: "GOLD",
-			Status:   domain.TxSuccess,
+		_, err = ledger.Post(tx, ledger.Entry{
+			Type:          "SELL_MILK_GOLD",
+			ConfigVersion: &economy.Version,
+			Legs: []ledger.Leg{
+				{Account: ledger.User(userID, domain.CurrencyMilk), Amount: milk.Neg()},
+				{Account: ledger.System(domain.BucketBurn, domain.CurrencyMilk), Amount: milk},
+				{Account: ledger.System(domain.BucketMint, domain.CurrencyGold), Amount: goldReward.Neg()},
+				{Account: ledger.User(userID, domain.CurrencyGold), Amount: goldReward},
+			},
 		})
-
-		return nil
+		return err
 	})
 }
 
 // BuyInAppItemWithGold allows users to spend Gold on farm essentials.
 func (uc *MarketUsecase) BuyInAppItemWithGold(ctx context.Context, userID uuid.UUID, itemType string, quantity int) error {
-	priceMap := map[string]int64{
-		"GRASS":    10,   // 10 Gold
-		"BABY_COW": 500,  // 500 Gold
-		"COW":      2000, // 2000 Gold
-		"LAND":     1000, // 1000 Gold
-		"VITAMIN":  50,   // 50 Gold (Care boost)
-	}
-
-	unitPrice, ok := priceMap[itemType]
-	if !ok {
+	if !inAppGoldItems[itemType] {
 		return errors.New("Invalid item type")
 	}
-
-	totalPrice := decimal.NewFromInt(unitPrice * int64(quantity))
+	if quantity <= 0 {
+		return errors.New("Quantity must be greater than 0")
+	}
 
 	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
 	defer cancel()
@@ -336,35 +496,47 @@ func (uc *MarketUsecase) BuyInAppItemWithGold(ctx context.Context, userID uuid.U
 			return errors.New("User not found")
 		}
 
+		// Harga Gold diambil dari EconomyConfig yang aktif
+		economy, err := activeEconomyConfig(tx, time.Now())
+		if err != nil {
+			return err
+		}
+		unitPrice, ok := economy.Params.InAppGoldPrices[itemType]
+		if !ok {
+			return errors.New("Item is not available")
+		}
+		totalPrice := unitPrice.Mul(decimal.NewFromInt(int64(quantity)))
+
 		if user.GoldBalance.LessThan(totalPrice) {
 			return errors.New("Insufficient Gold balance")
 		}
 
-		user.GoldBalance = user.GoldBalance.Sub(totalPrice)
-
 		// Handle Vitamin care boost immediately if applicable
 		if itemType == "VITAMIN" {
-			now := time.Now()
-			user.LastAdWatchedAt = &now
+			if err := tx.Model(&user).Update("last_ad_watched_at", time.Now()).Error; err != nil {
+				return err
+			}
 		}
 
-		if err := tx.Save(&user).Error; err != nil {
-			return err
-		}
+		// Gold dibakar, GRASS (jika ada) dicetak ke inventory dalam satu entry
+		entry := ledger.Transfer("BUY_ITEM_GOLD",
+			ledger.User(userID, domain.CurrencyGold),
+			ledger.System(domain.BucketBurn, domain.CurrencyGold),
+			totalPrice)
+		entry.ConfigVersion = &economy.Version
 
 		// Deliver items
 		switch itemType {
 		case "GRASS":
-			var inv domain.Inventory
-			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&inv).Error; err == nil {
-				inv.Grass += quantity
-				tx.Save(&inv)
-			}
+			grass := decimal.NewFromInt(int64(quantity))
+			entry.Legs = append(entry.Legs,
+				ledger.Leg{Account: ledger.System(domain.BucketMint, domain.CurrencyGrass), Amount: grass.Neg()},
+				ledger.Leg{Account: ledger.User(userID, domain.CurrencyGrass), Amount: grass},
+			)
 		case "LAND":
-			var inv domain.Inventory
-			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&inv).Error; err == nil {
-				inv.LandSlots += quantity
-				tx.Save(&inv)
+			if err := tx.Model(&domain.Inventory{}).Where("user_id = ?", userID).
+				Update("land_slots", gorm.Expr("land_slots + ?", quantity)).Error; err != nil {
+				return err
 			}
 		case "BABY_COW", "COW":
 			cowType := domain.TypeStandard // In-app are standard cows
@@ -376,113 +548,106 @@ func (uc *MarketUsecase) BuyInAppItemWithGold(ctx context.Context, userID uuid.U
 					Happiness:        100,
 					ExpectedLifespan: time.Now().AddDate(0, 3, 0),
 				}
-				tx.Create(&cow)
+				if err := tx.Create(&cow).Error; err != nil {
+					return err
+				}
 			}
 		}
 
-		tx.Create(&domain.TxLog{
-			UserID:   userID,
-			Type:     "BUY_ITEM_GOLD",
-			Amount:   totalPrice,
-			Currency: "GOLD",
-			Status:   domain.TxSuccess,
-		})
-
-		return nil
+		_, err = ledger.Post(tx, entry)
+		return err
 	})
 }
 
-// SwapGoldToTokens handles the conversion of Gold to $COW or $USDT.
-func (uc *MarketUsecase) SwapGoldToTokens(ctx context.Context, userID uuid.UUID, goldAmount decimal.Decimal, target string) error {
-	if goldAmount.LessThanOrEqual(decimal.Zero) {
-		return errors.New("Amount must be positive")
+// Swap Gold -> COW/USDT ditangani oleh AMMUsecase (pool constant-product), lihat amm_uc.go.
+
+// BuyFromPlatform membeli item dari katalog platform. Harga, stok, batas per user dan sale window
+// diambil dari database; client hanya mengirim SKU, jumlah dan currency pembayaran.
+func (uc *MarketUsecase) BuyFromPlatform(ctx context.Context, buyerID uuid.UUID, sku string, quantity int, currency string, userUC *UserUsecase) (*domain.PlatformPurchase, error) {
+	if currency != "USDT" && currency != "COW" {
+		return nil, errors.New("Hanya mendukung pembayaran menggunakan USDT atau COW")
+	}
+	if quantity <= 0 {
+		return nil, errors.New("Jumlah pembelian tidak valid")
+	}
+
+	lockKey := "platform_buy:" + buyerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return nil, errors.New("Transaksi pembelian sedang diproses...")
 	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
 
 	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
 	defer cancel()
 
-	return uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
-		var user domain.User
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
-			return errors.New("User not found")
+	var purchase domain.PlatformPurchase
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		// 1. Resolve item & harga dari katalog (FOR UPDATE agar stok tidak oversold)
+		var item domain.PlatformItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("sku = ?", sku).First(&item).Error; err != nil {
+			return errors.New("Item platform tidak ditemukan")
+		}
+		if !item.OnSale(time.Now()) {
+			return errors.New("Item ini sedang tidak dijual")
 		}
 
-		if user.GoldBalance.LessThan(goldAmount) {
-			return errors.New("Insufficient Gold balance")
+		var itemPrice domain.PlatformItemPrice
+		if err := tx.Where("item_id = ? AND currency = ?", item.ID, currency).First(&itemPrice).Error; err != nil {
+			return errors.New("Item ini tidak dijual dengan currency tersebut")
 		}
+		price := itemPrice.Price.Mul(decimal.NewFromInt(int64(quantity)))
 
-		var rewardAmount decimal.Decimal
-		if target == "COW" {
-			// 100 Gold = 1 COW
-			rewardAmount = goldAmount.Div(decimal.NewFromInt(100))
-			user.Points = user.Points.Add(rewardAmount)
-		} else if target == "USDT" {
-			// 10,000 Gold = 1 USDT
-			rewardAmount = goldAmount.Div(decimal.NewFromInt(10000))
-			user.USDTBalance = user.USDTBalance.Add(rewardAmount)
-		} else {
-			return errors.New("Invalid target currency")
+		if item.Stock != nil && item.Sold+quantity > *item.Stock {
+			return errors.New("Stok item tidak mencukupi")
+		}
+		if item.PerUserLimit != nil {
+			var bought int64
+			if err := tx.Model(&domain.PlatformPurchase{}).Where("user_id = ? AND item_id = ?", buyerID, item.ID).
+				Select("COALESCE(SUM(quantity), 0)").Scan(&bought).Error; err != nil {
+				return err
+			}
+			if int(bought)+quantity > *item.PerUserLimit {
+				return errors.New("Batas pembelian item ini per user sudah tercapai")
+			}
 		}
 
-		user.GoldBalance = user.GoldBalance.Sub(goldAmount)
-		if err := tx.Save(&user).Error; err != nil {
+		now := time.Now()
+		economy, err := activeEconomyConfig(tx, now)
+		if err != nil {
 			return err
 		}
+		// Bonus referral COW menambah Points upline sehingga dibatasi budget emisi (dikunci sebelum users)
+		var emission *cowEmission
+		if currency == domain.CurrencyCOW {
+			if emission, err = lockCOWEmission(tx, economy.Params, now); err != nil {
+				return err
+			}
+		}
 
-		tx.Create(&domain.TxLog{
-			UserID:   userID,
-			Type:     "GOLD_SWAP",
-			Amount:   goldAmount,
-			Currency: "GOLD",
-			Status:   domain.TxSuccess,
-		})
-
-		return nil
-	})
-}
-
-func (uc *MarketUsecase) BuyFromPlatform(ctx context.Context, buyerID uuid.UUID, itemType string, quantity int, price decimal.Decimal, currency string, userUC *UserUsecase) error {
-	if currency != "USDT" && currency != "COW" {
-		return errors.New("Hanya mendukung pembayaran menggunakan USDT atau COW")
-	}
-
-	lockKey := "platform_buy:" + buyerID.String()
-	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
-	if !acquired {
-		return errors.New("Transaksi pembelian sedang diproses...")
-	}
-	defer customRedis.ReleaseLock(ctx, lockKey, token)
-
-	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
-	defer cancel()
-
-	return uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
 		var buyer domain.User
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", buyerID).First(&buyer).Error; err != nil {
 			return errors.New("User tidak ditemukan")
 		}
 
-		// 1. Deduct Balance
+		// 2. Validate Balance (pemotongan dilakukan oleh ledger di langkah 4)
 		if currency == "USDT" {
 			if buyer.USDTBalance.LessThan(price) {
 				return errors.New("Saldo USDT tidak mencukupi")
 			}
-			buyer.USDTBalance = buyer.USDTBalance.Sub(price)
 		} else {
 			if buyer.Points.LessThan(price) {
 				return errors.New("Saldo COW tidak mencukupi")
 			}
-			buyer.Points = buyer.Points.Sub(price)
 		}
 
-		if err := tx.Save(&buyer).Error; err != nil {
-			return err
-		}
+		entry := ledger.Entry{Type: "PLATFORM_BUY", ConfigVersion: &economy.Version}
+		units := quantity * item.UnitQuantity
 
-		// 2. Deliver Item
-		switch itemType {
+		// 3. Deliver Item
+		switch item.ItemType {
 		case "COW":
-			for i := 0; i < quantity; i++ {
+			for i := 0; i < units; i++ {
 				cow := domain.Cow{
 					OwnerID:          buyerID,
 					Level:            1,
@@ -494,31 +659,19 @@ func (uc *MarketUsecase) BuyFromPlatform(ctx context.Context, buyerID uuid.UUID,
 				}
 			}
 		case "GRASS":
-			var inv domain.Inventory
-			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", buyerID).First(&inv).Error
-			if err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					inv = domain.Inventory{UserID: buyerID, Grass: quantity, Milk: 0}
-					if err := tx.Create(&inv).Error; err != nil {
-						return err
-					}
-				} else {
-					return err
-				}
-			} else {
-				inv.Grass += quantity
-				if err := tx.Save(&inv).Error; err != nil {
-					return err
-				}
-			}
+			grass := decimal.NewFromInt(int64(units))
+			entry.Legs = append(entry.Legs,
+				ledger.Leg{Account: ledger.System(domain.BucketMint, domain.CurrencyGrass), Amount: grass.Neg()},
+				ledger.Leg{Account: ledger.User(buyerID, domain.CurrencyGrass), Amount: grass},
+			)
 		default:
 			return errors.New("Item platform tidak valid")
 		}
 
-		// 3. Economy Split: 70% LP, 20% Referral, 10% Dev/Treasury
-		devCut := price.Mul(decimal.NewFromFloat(0.10))
-		lpCut := price.Mul(decimal.NewFromFloat(0.70))
-		refCut := price.Mul(decimal.NewFromFloat(0.20))
+		// 4. Economy Split: LP / Referral / Dev sesuai EconomyConfig (default 70/20/10)
+		lpCut := price.Mul(economy.Params.PlatformLPShare).Truncate(8)
+		refCut := price.Mul(economy.Params.PlatformRefShare).Truncate(8)
+		devCut := price.Sub(lpCut).Sub(refCut) // Sisa pembulatan masuk ke dev agar entry tetap seimbang
 
 		// Process Roll-Up Referral
 		upline, err := userUC.GetUplineReferrerWithCow(ctxDB, buyerID)
@@ -526,38 +679,59 @@ func (uc *MarketUsecase) BuyFromPlatform(ctx context.Context, buyerID uuid.UUID,
 			return err
 		}
 
-		if upline != nil {
-			// Found an eligible upline! Give them the 20%
-			var uplineUser domain.User
-			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", upline.ID).First(&uplineUser).Error; err == nil {
-				if currency == "USDT" {
-					uplineUser.USDTBalance = uplineUser.USDTBalance.Add(refCut)
-				} else {
-					uplineUser.Points = uplineUser.Points.Add(refCut)
-				}
-				tx.Save(&uplineUser)
-
-				// Log Referral Bonus
-				tx.Create(&domain.TxLog{
-					UserID:   uplineUser.ID,
-					Type:     "REFERRAL_BONUS",
-					Amount:   refCut,
-					Currency: currency,
-					Status:   domain.TxSuccess,
-				})
+		if upline != nil && emission != nil {
+			// Bagian yang melebihi sisa budget emisi roll-up ke Dev Treasury
+			capped := decimal.Min(refCut, emission.Remaining())
+			if err := emission.Consume(capped); err != nil {
+				return err
 			}
-		} else {
+			devCut = devCut.Add(refCut.Sub(capped))
+			refCut = capped
+		}
+
+		if upline != nil && refCut.IsPositive() {
+			// Found an eligible upline! Give them the 20%
+			entry.Legs = append(entry.Legs, ledger.Leg{
+				Account: ledger.User(upline.ID, currency),
+				Amount:  refCut,
+				TxType:  "REFERRAL_BONUS",
+			})
+		} else if upline == nil {
 			// No eligible upline found. The 20% "Rolls-up" to the Dev Treasury.
 			devCut = devCut.Add(refCut)
 		}
 
-		// Log the system distributions
-		tx.Create(&domain.TxLog{UserID: buyerID, Type: "PLATFORM_BUY", Amount: price, Currency: currency, Status: domain.TxSuccess})
-		tx.Create(&domain.TxLog{UserID: buyerID, Type: "TREASURY_LP_BUYBACK", Amount: lpCut, Currency: currency, Status: domain.TxSuccess})
-		tx.Create(&domain.TxLog{UserID: buyerID, Type: "TREASURY_DEV_FEE", Amount: devCut, Currency: currency, Status: domain.TxSuccess})
+		// Pembayaran pembeli dibagi ke akun treasury (bukan lagi TxLog TREASURY_* atas nama pembeli)
+		entry.Legs = append(entry.Legs,
+			ledger.Leg{Account: ledger.User(buyerID, currency), Amount: price.Neg()},
+			ledger.Leg{Account: ledger.Treasury(domain.BucketLPBuyback, currency), Amount: lpCut},
+			ledger.Leg{Account: ledger.Treasury(domain.BucketDev, currency), Amount: devCut},
+		)
 
-		return nil
+		journal, err := ledger.Post(tx, entry)
+		if err != nil {
+			return err
+		}
+
+		// 5. Catat stok terjual & riwayat pembelian (dasar batas per user)
+		if err := tx.Model(&item).Update("sold", gorm.Expr("sold + ?", quantity)).Error; err != nil {
+			return err
+		}
+		purchase = domain.PlatformPurchase{
+			UserID:    buyerID,
+			ItemID:    item.ID,
+			Quantity:  quantity,
+			Currency:  currency,
+			UnitPrice: itemPrice.Price,
+			Total:     price,
+			EntryID:   journal.ID,
+		}
+		return tx.Create(&purchase).Error
 	})
+	if err != nil {
+		return nil, err
+	}
+	return &purchase, nil
 }
 
 // === Web2 Staking Logic (In-App) ===
@@ -573,27 +747,30 @@ func (uc *MarketUsecase) StakeInApp(ctx context.Context, userID uuid.UUID, asset
 			return err
 		}
 
-		// Deduct balance
+		// Validate balance (pemindahan ke escrow staking dilakukan oleh ledger)
 		if assetType == "GOLD" {
 			if user.GoldBalance.LessThan(amount) {
 				return errors.New("Gold tidak mencukupi")
 			}
-			user.GoldBalance = user.GoldBalance.Sub(amount)
 		} else if assetType == "MILK" {
 			var inv domain.Inventory
 			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&inv).Error; err != nil {
 				return err
 			}
+			if !amount.Equal(amount.Truncate(0)) {
+				return errors.New("Jumlah susu harus bilangan bulat")
+			}
 			if decimal.NewFromInt(int64(inv.Milk)).LessThan(amount) {
 				return errors.New("Susu tidak mencukupi")
 			}
-			inv.Milk -= int(amount.IntPart())
-			tx.Save(&inv)
 		} else {
 			return errors.New("Tipe aset tidak didukung")
 		}
 
-		if err := tx.Save(&user).Error; err != nil {
+		if _, err := ledger.Post(tx, ledger.Transfer("STAKE_INAPP",
+			ledger.User(userID, assetType),
+			ledger.Escrow(domain.BucketStaking, assetType),
+			amount)); err != nil {
 			return err
 		}
 
@@ -633,6 +810,12 @@ func (uc *MarketUsecase) ClaimInAppRewards(ctx context.Context, userID uuid.UUID
 		}
 
 		now := time.Now()
+		economy, err := activeEconomyConfig(tx, now)
+		if err != nil {
+			return err
+		}
+
+		entry := ledger.Entry{Type: "STAKE_CLAIM", ConfigVersion: &economy.Version}
 		for i := range stakes {
 			stake := &stakes[i]
 			hours := now.Sub(stake.LastClaimedAt).Hours()
@@ -641,81 +824,35 @@ func (uc *MarketUsecase) ClaimInAppRewards(ctx context.Context, userID uuid.UUID
 			}
 
 			if stake.AssetType == "GOLD" {
-				// 1000 Gold = 0.1 Milk / hour
-				reward := stake.Amount.Div(decimal.NewFromInt(10000)).Mul(decimal.NewFromFloat(hours))
-				var inv domain.Inventory
-				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&inv).Error; err == nil {
-					inv.Milk += int(reward.IntPart())
-					tx.Save(&inv)
-				}
+				// Default: 1000 Gold = 0.1 Milk / hour
+				reward := stake.Amount.Mul(economy.Params.StakeGoldMilkHourly).Mul(decimal.NewFromFloat(hours))
+				milk := decimal.NewFromInt(reward.IntPart())
+				entry.Legs = append(entry.Legs,
+					ledger.Leg{Account: ledger.System(domain.BucketMint, domain.CurrencyMilk), Amount: milk.Neg()},
+					ledger.Leg{Account: ledger.User(userID, domain.CurrencyMilk), Amount: milk},
+				)
 			} else if stake.AssetType == "MILK" {
-				// 10 Milk = 1 Gold / hour
-				reward := stake.Amount.Div(decimal.NewFromInt(10)).Mul(decimal.NewFromFloat(hours))
-				user.GoldBalance = user.GoldBalance.Add(reward)
+				// Default: 10 Milk = 1 Gold / hour
+				reward := stake.Amount.Mul(economy.Params.StakeMilkGoldHourly).Mul(decimal.NewFromFloat(hours)).Truncate(8)
+				entry.Legs = append(entry.Legs,
+					ledger.Leg{Account: ledger.System(domain.BucketMint, domain.CurrencyGold), Amount: reward.Neg()},
+					ledger.Leg{Account: ledger.User(userID, domain.CurrencyGold), Amount: reward},
+				)
 			}
 
 			// Update last claimed time
-			tx.Model(stake).Update("last_claimed_at", now)
+			if err := tx.Model(stake).Update("last_claimed_at", now).Error; err != nil {
+				return err
+			}
 		}
 
-		return tx.Save(&user).Error
-	})
-}
-
-// === Financial Core (Simulated) ===
-
-func (uc *MarketUsecase) Deposit(ctx context.Context, userID uuid.UUID, asset string, amount decimal.Decimal) error {
-	return uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var user domain.User
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
+		if _, err := ledger.Post(tx, entry); err != nil && !errors.Is(err, ledger.ErrEmptyEntry) {
 			return err
 		}
-
-		if asset == "COW" {
-			user.Points = user.Points.Add(amount)
-		} else if asset == "USDT" {
-			user.USDTBalance = user.USDTBalance.Add(amount)
-		}
-
-		tx.Create(&domain.TxLog{
-			UserID:   userID,
-			Type:     "DEPOSIT",
-			Amount:   amount,
-			Currency: asset,
-			Status:   domain.TxSuccess,
-		})
-
-		return tx.Save(&user).Error
+		return nil
 	})
 }
 
-func (uc *MarketUsecase) Withdraw(ctx context.Context, userID uuid.UUID, asset string, amount decimal.Decimal) error {
-	return uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var user domain.User
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
-			return err
-		}
-
-		if asset == "COW" {
-			if user.Points.LessThan(amount) {
-				return errors.New("Saldo COW tidak mencukupi")
-			}
-			user.Points = user.Points.Sub(amount)
-		} else if asset == "USDT" {
-			if user.USDTBalance.LessThan(amount) {
-				return errors.New("Saldo USDT tidak mencukupi")
-			}
-			user.USDTBalance = user.USDTBalance.Sub(amount)
-		}
-
-		tx.Create(&domain.TxLog{
-			UserID:   userID,
-			Type:     "WITHDRAW",
-			Amount:   amount,
-			Currency: asset,
-			Status:   domain.TxSuccess,
-		})
-
-		return tx.Save(&user).Error
-	})
-}
+// === Financial Core ===
+// Deposit ditangani oleh DepositUsecase (indexer ERC-20 on-chain), lihat deposit_uc.go.
+// Withdraw ditangani oleh WithdrawalUsecase (payout ERC-20 bertahap), lihat withdrawal_uc.go.
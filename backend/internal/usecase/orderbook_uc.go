@@ -0,0 +1,582 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+	"cashcowvalley/backend/pkg/realtime"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Batas jumlah order lawan yang dicocokkan dalam satu transaksi; sisanya dicocokkan di batch
+	// berikutnya (continueListingMatch / continueBidMatch) sampai tidak ada lagi yang menyilang
+	maxMatchesPerOrder = 50
+	orderBookDepth     = 20
+)
+
+var orderBookItems = map[string]bool{domain.CurrencyGrass: true, domain.CurrencyMilk: true}
+
+var activeOrderStatuses = []string{domain.ListingOpen, domain.ListingPartiallyFilled}
+
+var activeBidStatuses = []string{domain.BidOpen, domain.BidPartiallyFilled}
+
+// lockCrossingBids mengunci bid aktif yang harganya >= unitPrice dengan prioritas harga-waktu
+// (harga tertinggi, lalu terlama) secukupnya untuk mengisi `quantity`. Bid milik seller dilewati.
+func lockCrossingBids(tx *gorm.DB, itemType string, sellerID uuid.UUID, unitPrice decimal.Decimal, quantity int) ([]domain.MarketBid, error) {
+	var bids []domain.MarketBid
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("item_type = ? AND status IN ? AND unit_price_usdt >= ? AND buyer_id <> ?", itemType, activeBidStatuses, unitPrice, sellerID).
+		Order("unit_price_usdt DESC, created_at, id").Limit(maxMatchesPerOrder).Find(&bids).Error; err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for need := quantity; n < len(bids) && need > 0; n++ {
+		need -= bids[n].RemainingQuantity
+	}
+	return bids[:n], nil
+}
+
+// askQuery memilih listing aktif yang bisa dibeli buyer dengan prioritas harga-waktu (harga terendah,
+// lalu terlama), dikunci FOR UPDATE dengan urutan yang sama sehingga pembeli paralel tidak deadlock.
+// Listing milik buyer dan yang sudah kedaluwarsa dilewati.
+func askQuery(tx *gorm.DB, itemType string, buyerID uuid.UUID) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("item_type = ? AND status IN ? AND seller_id <> ?", itemType, activeOrderStatuses, buyerID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("unit_price_usdt, created_at, id").Limit(maxMatchesPerOrder)
+}
+
+// lockCrossingListings mengunci listing aktif yang harganya <= unitPrice secukupnya untuk mengisi `quantity`.
+func lockCrossingListings(tx *gorm.DB, itemType string, buyerID uuid.UUID, unitPrice decimal.Decimal, quantity int) ([]domain.MarketListing, error) {
+	var listings []domain.MarketListing
+	if err := askQuery(tx, itemType, buyerID).
+		Where("unit_price_usdt <= ?", unitPrice).Find(&listings).Error; err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for need := quantity; n < len(listings) && need > 0; n++ {
+		need -= listings[n].RemainingQuantity
+	}
+	return listings[:n], nil
+}
+
+// lockUsersSorted mengunci baris users secara leksikografis agar transaksi yang melibatkan banyak
+// user (BuyItem, matching order book, settlement lelang) tidak saling deadlock.
+func lockUsersSorted(tx *gorm.DB, ids ...uuid.UUID) error {
+	seen := make(map[string]bool, len(ids))
+	sorted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if s := id.String(); !seen[s] {
+			seen[s] = true
+			sorted = append(sorted, s)
+		}
+	}
+	sort.Strings(sorted)
+
+	for _, id := range sorted {
+		var user domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&user).Error; err != nil {
+			return errors.New("User tidak ditemukan")
+		}
+	}
+	return nil
+}
+
+// executeTrade memindahkan `qty` item dari escrow listing ke buyer dan USDT ke seller pada harga `price`.
+// Jika bid nil (beli manual) USDT diambil langsung dari saldo buyer; jika tidak, dari escrow bid dan
+// selisih harga bid dengan harga eksekusi (price improvement) dikembalikan ke buyer.
+// Fee maker/taker dipotong dari hasil penjualan seller dan ditambahkan ke pembayaran buyer (untuk bid,
+// dibatasi cadangan fee yang ditahan saat bid dipasang), lalu masuk ke akun treasury.
+// Semua baris (listing, bid, users) harus sudah dikunci oleh pemanggil.
+func executeTrade(tx *gorm.DB, fees *marketFees, listing *domain.MarketListing, bid *domain.MarketBid, buyerID uuid.UUID, qty int, price decimal.Decimal, takerSide string) (*domain.MarketFill, error) {
+	if listing.ItemType == domain.ItemCattle {
+		// Listing lama bisa aktif melewati masa hidup sapi; sapi yang sudah mati tidak boleh dijual
+		var cow domain.Cow
+		if err := tx.Where("id = ?", *listing.CowID).First(&cow).Error; err != nil {
+			return nil, errors.New("Sapi pada listing ini tidak ditemukan")
+		}
+		if !cow.ExpectedLifespan.After(time.Now()) {
+			return nil, errors.New("Sapi sudah melewati masa hidupnya")
+		}
+	}
+
+	total := price.Mul(decimal.NewFromInt(int64(qty)))
+	buyerIsTaker := takerSide == domain.TakerBuy
+	fill := domain.MarketFill{
+		ID:            uuid.New(),
+		ListingID:     listing.ID,
+		TakerSide:     takerSide,
+		BuyerID:       buyerID,
+		SellerID:      listing.SellerID,
+		ItemType:      listing.ItemType,
+		Quantity:      qty,
+		UnitPriceUSDT: price,
+		TotalUSDT:     total,
+		BuyerFeeUSDT:  fees.charge(total, buyerIsTaker),
+		SellerFeeUSDT: fees.charge(total, !buyerIsTaker),
+	}
+
+	legs := []ledger.Leg{
+		{Account: ledger.User(listing.SellerID, domain.CurrencyUSDT), Amount: total.Sub(fill.SellerFeeUSDT), TxType: "MARKET_SALE"},
+	}
+	if listing.ItemType != domain.ItemCattle {
+		items := decimal.NewFromInt(int64(qty))
+		legs = append(legs,
+			ledger.Leg{Account: ledger.Escrow(domain.BucketMarket, listing.ItemType), Amount: items.Neg()},
+			ledger.Leg{Account: ledger.User(buyerID, listing.ItemType), Amount: items},
+		)
+	}
+	if bid == nil {
+		legs = append(legs, ledger.Leg{Account: ledger.User(buyerID, domain.CurrencyUSDT), Amount: total.Add(fill.BuyerFeeUSDT).Neg()})
+	} else {
+		fill.BidID = &bid.ID
+		held := bidHold(bid, bid.RemainingQuantity).Sub(bidHold(bid, bid.RemainingQuantity-qty))
+		reserve := held.Sub(bid.UnitPriceUSDT.Mul(decimal.NewFromInt(int64(qty))))
+		fill.BuyerFeeUSDT = decimal.Min(fill.BuyerFeeUSDT, reserve)
+		legs = append(legs,
+			ledger.Leg{Account: ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT), Amount: held.Neg()},
+			ledger.Leg{Account: ledger.User(buyerID, domain.CurrencyUSDT), Amount: held.Sub(total).Sub(fill.BuyerFeeUSDT), TxType: "MARKET_BID_REFUND"},
+		)
+	}
+	legs = append(legs, fees.treasuryLegs(fill.BuyerFeeUSDT.Add(fill.SellerFeeUSDT))...)
+
+	entryRef := "market_fill:" + fill.ID.String()
+	entry, err := ledger.Post(tx, ledger.Entry{
+		Type:          "MARKET_BUY",
+		ReferenceID:   &entryRef,
+		ConfigVersion: &fees.Version,
+		Legs:          legs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fill.EntryID = entry.ID
+	if err := tx.Create(&fill).Error; err != nil {
+		return nil, err
+	}
+
+	if err := recordCandles(tx, fill.ItemType, price, qty, total, fill.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := recordTradingStats(tx, &fill, listing.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if listing.ItemType == domain.ItemCattle {
+		if err := transferListedCow(tx, *listing.CowID, listing.ID, buyerID, "cow_fill:"+fill.ID.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	listing.RemainingQuantity -= qty
+	listing.Status = domain.ListingPartiallyFilled
+	if listing.RemainingQuantity == 0 {
+		listing.Status = domain.ListingSold
+	}
+	if err := tx.Model(listing).Updates(map[string]interface{}{
+		"remaining_quantity": listing.RemainingQuantity,
+		"status":             listing.Status,
+	}).Error; err != nil {
+		return nil, err
+	}
+	realtime.Emit(tx.Statement.Context, realtime.EventListingSold, "", ListingSold{
+		ListingID:     listing.ID,
+		ItemType:      listing.ItemType,
+		Quantity:      qty,
+		UnitPriceUSDT: price,
+		Remaining:     listing.RemainingQuantity,
+		Status:        listing.Status,
+	})
+	if err := closeStaleOffers(tx, listing); err != nil {
+		return nil, err
+	}
+
+	if bid != nil {
+		bid.RemainingQuantity -= qty
+		bid.Status = domain.BidPartiallyFilled
+		if bid.RemainingQuantity == 0 {
+			bid.Status = domain.BidFilled
+		}
+		if err := tx.Model(bid).Updates(map[string]interface{}{
+			"remaining_quantity": bid.RemainingQuantity,
+			"status":             bid.Status,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &fill, nil
+}
+
+// matchListingAgainstBids mengisi listing (ask) yang baru dibuat / di-reprice dengan bid yang sudah
+// dikunci, pada harga bid (maker). Mengembalikan fill yang terjadi.
+func matchListingAgainstBids(tx *gorm.DB, fees *marketFees, listing *domain.MarketListing, bids []domain.MarketBid) ([]domain.MarketFill, error) {
+	fills := make([]domain.MarketFill, 0, len(bids))
+	for i := range bids {
+		if listing.RemainingQuantity == 0 {
+			break
+		}
+		bid := &bids[i]
+		qty := min(listing.RemainingQuantity, bid.RemainingQuantity)
+		fill, err := executeTrade(tx, fees, listing, bid, bid.BuyerID, qty, bid.UnitPriceUSDT, domain.TakerSell)
+		if err != nil {
+			return nil, err
+		}
+		fills = append(fills, *fill)
+	}
+	return fills, nil
+}
+
+// matchBidAgainstListings mengisi bid dengan listing yang sudah dikunci, pada harga listing (maker).
+func matchBidAgainstListings(tx *gorm.DB, fees *marketFees, bid *domain.MarketBid, listings []domain.MarketListing) ([]domain.MarketFill, error) {
+	fills := make([]domain.MarketFill, 0, len(listings))
+	for i := range listings {
+		if bid.RemainingQuantity == 0 {
+			break
+		}
+		listing := &listings[i]
+		qty := min(bid.RemainingQuantity, listing.RemainingQuantity)
+		fill, err := executeTrade(tx, fees, listing, bid, bid.BuyerID, qty, listing.UnitPriceUSDT, domain.TakerBuy)
+		if err != nil {
+			return nil, err
+		}
+		fills = append(fills, *fill)
+	}
+	return fills, nil
+}
+
+// batchFull bernilai true jika satu batch matching memakai seluruh maxMatchesPerOrder order lawan dan
+// order masih bersisa, artinya mungkin masih ada order lawan lain yang menyilang.
+func batchFull(locked, remaining int) bool {
+	return locked == maxMatchesPerOrder && remaining > 0
+}
+
+// continueListingMatch mencocokkan sisa listing dengan batch bid berikutnya (satu transaksi per batch)
+// sampai listing habis atau tidak ada lagi bid yang menyilang, agar buku tidak tertinggal crossed setelah
+// batas maxMatchesPerOrder. Dipanggil setelah transaksi awal commit; kegagalan hanya dicatat karena
+// listing sudah tersimpan dan sisanya tetap resting di buku.
+func continueListingMatch(ctx context.Context, db *gorm.DB, listingID uuid.UUID) []domain.MarketFill {
+	fills := make([]domain.MarketFill, 0)
+	for {
+		batch, more, err := matchListingBatch(ctx, db, listingID)
+		if err != nil {
+			log.Printf("[MARKET] Gagal melanjutkan matching listing %s: %v", listingID, err)
+			return fills
+		}
+		fills = append(fills, batch...)
+		if !more {
+			return fills
+		}
+	}
+}
+
+func matchListingBatch(ctx context.Context, db *gorm.DB, listingID uuid.UUID) ([]domain.MarketFill, bool, error) {
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	var fills []domain.MarketFill
+	more := false
+	err := db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		var listing domain.MarketListing
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status IN ?", listingID, activeOrderStatuses).First(&listing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil // Sudah terjual / dibatalkan di antara batch
+			}
+			return err
+		}
+		if listing.IsExpired(time.Now()) {
+			return nil
+		}
+
+		bids, err := lockCrossingBids(tx, listing.ItemType, listing.SellerID, listing.UnitPriceUSDT, listing.RemainingQuantity)
+		if err != nil || len(bids) == 0 {
+			return err
+		}
+		userIDs := []uuid.UUID{listing.SellerID}
+		for _, b := range bids {
+			userIDs = append(userIDs, b.BuyerID)
+		}
+		if err := lockUsersSorted(tx, userIDs...); err != nil {
+			return err
+		}
+		fees, err := loadMarketFees(tx, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if fills, err = matchListingAgainstBids(tx, fees, &listing, bids); err != nil {
+			return err
+		}
+		more = batchFull(len(bids), listing.RemainingQuantity)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return fills, more, nil
+}
+
+// continueBidMatch adalah pasangan continueListingMatch untuk sisi bid.
+func continueBidMatch(ctx context.Context, db *gorm.DB, bidID uuid.UUID) []domain.MarketFill {
+	fills := make([]domain.MarketFill, 0)
+	for {
+		batch, more, err := matchBidBatch(ctx, db, bidID)
+		if err != nil {
+			log.Printf("[MARKET] Gagal melanjutkan matching bid %s: %v", bidID, err)
+			return fills
+		}
+		fills = append(fills, batch...)
+		if !more {
+			return fills
+		}
+	}
+}
+
+func matchBidBatch(ctx context.Context, db *gorm.DB, bidID uuid.UUID) ([]domain.MarketFill, bool, error) {
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	var fills []domain.MarketFill
+	more := false
+	err := db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		var bid domain.MarketBid
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status IN ?", bidID, activeBidStatuses).First(&bid).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil // Sudah terisi / dibatalkan di antara batch
+			}
+			return err
+		}
+
+		listings, err := lockCrossingListings(tx, bid.ItemType, bid.BuyerID, bid.UnitPriceUSDT, bid.RemainingQuantity)
+		if err != nil || len(listings) == 0 {
+			return err
+		}
+		userIDs := []uuid.UUID{bid.BuyerID}
+		for _, l := range listings {
+			userIDs = append(userIDs, l.SellerID)
+		}
+		if err := lockUsersSorted(tx, userIDs...); err != nil {
+			return err
+		}
+		fees, err := loadMarketFees(tx, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if fills, err = matchBidAgainstListings(tx, fees, &bid, listings); err != nil {
+			return err
+		}
+		more = batchFull(len(listings), bid.RemainingQuantity)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return fills, more, nil
+}
+
+type OrderBookUsecase struct {
+	db *gorm.DB
+}
+
+func NewOrderBookUsecase(db *gorm.DB) *OrderBookUsecase {
+	return &OrderBookUsecase{db: db}
+}
+
+type BidResult struct {
+	Bid   domain.MarketBid    `json:"bid"`
+	Fills []domain.MarketFill `json:"fills"`
+}
+
+// PlaceBid memasang order beli. Bid langsung dicocokkan dengan listing termurah (harga listing/maker),
+// sisanya resting di buku dengan USDT ditahan di escrow MARKET.
+func (uc *OrderBookUsecase) PlaceBid(ctx context.Context, buyerID uuid.UUID, itemType string, quantity int, unitPriceUSDT decimal.Decimal) (*BidResult, error) {
+	if !orderBookItems[itemType] {
+		return nil, errors.New("Tipe item tidak valid, hanya GRASS atau MILK")
+	}
+	if quantity <= 0 {
+		return nil, errors.New("Jumlah item harus lebih dari 0")
+	}
+	if err := validateListingPrice(unitPriceUSDT); err != nil {
+		return nil, err
+	}
+
+	// Lock yang sama dengan BuyItem: satu pembelian per buyer dalam satu waktu
+	lockKey := "market_buy:" + buyerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return nil, errors.New("Transaksi pembelian sedang diproses...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	result := BidResult{Fills: make([]domain.MarketFill, 0)}
+	err := uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		listings, err := lockCrossingListings(tx, itemType, buyerID, unitPriceUSDT, quantity)
+		if err != nil {
+			return err
+		}
+
+		userIDs := []uuid.UUID{buyerID}
+		for _, l := range listings {
+			userIDs = append(userIDs, l.SellerID)
+		}
+		if err := lockUsersSorted(tx, userIDs...); err != nil {
+			return err
+		}
+
+		fees, err := loadMarketFees(tx, time.Now())
+		if err != nil {
+			return err
+		}
+
+		result.Bid = domain.MarketBid{
+			BuyerID:           buyerID,
+			ItemType:          itemType,
+			Quantity:          quantity,
+			RemainingQuantity: quantity,
+			UnitPriceUSDT:     unitPriceUSDT,
+			FeeRate:           fees.reserveRate(),
+			Status:            domain.BidOpen,
+		}
+
+		// Seluruh nilai bid + cadangan fee ditahan dulu; fill di bawah harga bid (atau dengan fee lebih
+		// rendah dari cadangan) mengembalikan selisihnya
+		held := bidHold(&result.Bid, quantity)
+		var buyer domain.User
+		if err := tx.Where("id = ?", buyerID).First(&buyer).Error; err != nil {
+			return errors.New("User tidak ditemukan")
+		}
+		if buyer.USDTBalance.LessThan(held) {
+			return errors.New("Saldo USDT tidak mencukupi")
+		}
+
+		if err := tx.Create(&result.Bid).Error; err != nil {
+			return err
+		}
+
+		entryRef := "market_bid:" + result.Bid.ID.String()
+		entry := ledger.Transfer("MARKET_BID",
+			ledger.User(buyerID, domain.CurrencyUSDT),
+			ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT),
+			held)
+		entry.ReferenceID = &entryRef
+		if _, err := ledger.Post(tx, entry); err != nil {
+			return err
+		}
+
+		fills, err := matchBidAgainstListings(tx, fees, &result.Bid, listings)
+		if err != nil {
+			return err
+		}
+		result.Fills = append(result.Fills, fills...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Masih ada listing menyilang di luar batch pertama: lanjutkan setelah bid tersimpan
+	if batchFull(len(result.Fills), result.Bid.RemainingQuantity) {
+		result.Fills = append(result.Fills, continueBidMatch(ctx, uc.db, result.Bid.ID)...)
+		if err := uc.db.WithContext(ctx).Where("id = ?", result.Bid.ID).First(&result.Bid).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// CancelBid menarik bid aktif dan mengembalikan USDT yang masih ditahan di escrow.
+func (uc *OrderBookUsecase) CancelBid(ctx context.Context, buyerID, bidID uuid.UUID) error {
+	lockKey := "market_buy:" + buyerID.String()
+	token, acquired := customRedis.AcquireLock(ctx, lockKey, 5*time.Second)
+	if !acquired {
+		return errors.New("Transaksi pembelian sedang diproses...")
+	}
+	defer customRedis.ReleaseLock(ctx, lockKey, token)
+
+	ctxDB, cancel := context.WithTimeout(ctx, 4*time.Second)
+	defer cancel()
+
+	return uc.db.WithContext(ctxDB).Transaction(func(tx *gorm.DB) error {
+		var bid domain.MarketBid
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", bidID).First(&bid).Error; err != nil {
+			return errors.New("Bid tidak ditemukan")
+		}
+		if bid.BuyerID != buyerID {
+			return errors.New("Bid ini bukan milik Anda")
+		}
+		if !bid.IsActive() {
+			return errors.New("Bid sudah tidak aktif")
+		}
+
+		entryRef := "market_bid_cancel:" + bidID.String()
+		entry := ledger.Transfer("MARKET_BID_CANCEL",
+			ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT),
+			ledger.User(buyerID, domain.CurrencyUSDT),
+			bidHold(&bid, bid.RemainingQuantity))
+		entry.ReferenceID = &entryRef
+		if _, err := ledger.Post(tx, entry); err != nil {
+			return err
+		}
+
+		return tx.Model(&bid).Updates(map[string]interface{}{
+			"remaining_quantity": 0,
+			"status":             domain.BidCancelled,
+		}).Error
+	})
+}
+
+// GetMyBids mengembalikan bid milik user (terbaru dulu).
+func (uc *OrderBookUsecase) GetMyBids(ctx context.Context, buyerID uuid.UUID) ([]domain.MarketBid, error) {
+	var bids []domain.MarketBid
+	if err := uc.db.WithContext(ctx).Where("buyer_id = ?", buyerID).
+		Order("created_at DESC").Limit(100).Find(&bids).Error; err != nil {
+		return nil, err
+	}
+	return bids, nil
+}
+
+// GetOrderBook mengembalikan level harga teratas kedua sisi untuk satu item.
+func (uc *OrderBookUsecase) GetOrderBook(ctx context.Context, itemType string) (*domain.OrderBook, error) {
+	if !orderBookItems[itemType] {
+		return nil, fmt.Errorf("Order book tidak tersedia untuk %s", itemType)
+	}
+
+	db := uc.db.WithContext(ctx)
+	book := domain.OrderBook{ItemType: itemType, Bids: make([]domain.OrderBookLevel, 0), Asks: make([]domain.OrderBookLevel, 0)}
+
+	if err := db.Model(&domain.MarketBid{}).
+		Select("unit_price_usdt, SUM(remaining_quantity) AS quantity, COUNT(*) AS orders").
+		Where("item_type = ? AND status IN ?", itemType, activeBidStatuses).
+		Group("unit_price_usdt").Order("unit_price_usdt DESC").Limit(orderBookDepth).
+		Scan(&book.Bids).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&domain.MarketListing{}).
+		Select("unit_price_usdt, SUM(remaining_quantity) AS quantity, COUNT(*) AS orders").
+		Where("item_type = ? AND status IN ?", itemType, activeOrderStatuses).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Group("unit_price_usdt").Order("unit_price_usdt").Limit(orderBookDepth).
+		Scan(&book.Asks).Error; err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
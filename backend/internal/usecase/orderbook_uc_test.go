@@ -0,0 +1,259 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// newMarketTestDB menyiapkan skema marketplace dengan fee maker 1% dan taker 2%.
+func newMarketTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := newTestDB(t, &domain.MarketListing{}, &domain.MarketBid{}, &domain.MarketFill{}, &domain.MarketOffer{},
+		&domain.MarketListingPriceChange{}, &domain.MarketCandle{}, &domain.TradingStat{}, &domain.TradingCounterparty{},
+		&domain.EconomyConfig{}, &domain.Auction{}, &domain.AuctionBid{})
+	economy := NewEconomyUsecase(db)
+	economy.SeedDefault(context.Background())
+	if _, err := economy.Schedule(context.Background(), uuid.New(),
+		json.RawMessage(`{"market_maker_fee":"0.01","market_taker_fee":"0.02"}`), nil, "test fees"); err != nil {
+		t.Fatalf("schedule fees: %v", err)
+	}
+	return db
+}
+
+// newTrader membuat user dengan 100 USDT dan 100 GRASS.
+func newTrader(t *testing.T, db *gorm.DB) uuid.UUID {
+	t.Helper()
+	user := domain.User{WalletAddress: "0x" + uuid.NewString()[:8], Nonce: "x"}
+	db.Create(&user)
+	db.Create(&domain.Inventory{UserID: user.ID})
+	if _, err := ledger.Post(db, ledger.Entry{Type: "TEST_FUND", Legs: []ledger.Leg{
+		{Account: ledger.System(domain.BucketOpening, domain.CurrencyUSDT), Amount: decimal.NewFromInt(-100)},
+		{Account: ledger.User(user.ID, domain.CurrencyUSDT), Amount: decimal.NewFromInt(100)},
+		{Account: ledger.System(domain.BucketOpening, domain.CurrencyGrass), Amount: decimal.NewFromInt(-100)},
+		{Account: ledger.User(user.ID, domain.CurrencyGrass), Amount: decimal.NewFromInt(100)},
+	}}); err != nil {
+		t.Fatalf("fund trader: %v", err)
+	}
+	return user.ID
+}
+
+// restListing menaruh listing GRASS di buku tanpa matching, dengan CreatedAt tertentu untuk prioritas waktu.
+func restListing(t *testing.T, db *gorm.DB, sellerID uuid.UUID, qty int, price string, createdAt time.Time) *domain.MarketListing {
+	t.Helper()
+	unit := decimal.RequireFromString(price)
+	listing := domain.MarketListing{
+		SellerID:          sellerID,
+		ItemType:          domain.CurrencyGrass,
+		Quantity:          qty,
+		RemainingQuantity: qty,
+		UnitPriceUSDT:     unit,
+		PriceUSDT:         unit.Mul(decimal.NewFromInt(int64(qty))),
+		Status:            domain.ListingOpen,
+		CreatedAt:         createdAt,
+	}
+	if err := db.Create(&listing).Error; err != nil {
+		t.Fatalf("create listing: %v", err)
+	}
+	if _, err := ledger.Post(db, ledger.Transfer("MARKET_SELL",
+		ledger.User(sellerID, domain.CurrencyGrass),
+		ledger.Escrow(domain.BucketMarket, domain.CurrencyGrass),
+		decimal.NewFromInt(int64(qty)))); err != nil {
+		t.Fatalf("escrow listing: %v", err)
+	}
+	return &listing
+}
+
+func accountBalance(t *testing.T, db *gorm.DB, a ledger.Account) decimal.Decimal {
+	t.Helper()
+	balance, err := ledger.Balance(db, a)
+	if err != nil {
+		t.Fatalf("balance %s: %v", a.Code(), err)
+	}
+	return balance
+}
+
+var marketEscrowUSDT = ledger.Escrow(domain.BucketMarket, domain.CurrencyUSDT)
+
+func TestLockCrossingListingsPriceTimePriority(t *testing.T) {
+	db := newMarketTestDB(t)
+	seller, buyer := newTrader(t, db), newTrader(t, db)
+	t0 := time.Now().Add(-time.Hour)
+	cheapLate := restListing(t, db, seller, 2, "1.0", t0.Add(2*time.Minute))
+	cheapEarly := restListing(t, db, seller, 3, "1.0", t0)
+	pricey := restListing(t, db, seller, 4, "1.5", t0.Add(time.Minute))
+	own := restListing(t, db, buyer, 5, "0.5", t0)
+	expired := restListing(t, db, seller, 5, "0.5", t0)
+	past := time.Now().Add(-time.Minute)
+	db.Model(expired).Update("expires_at", past)
+
+	cases := []struct {
+		name     string
+		price    string
+		quantity int
+		want     []uuid.UUID
+	}{
+		{"satu listing cukup", "1.0", 3, []uuid.UUID{cheapEarly.ID}},
+		{"waktu memutus harga sama", "1.0", 4, []uuid.UUID{cheapEarly.ID, cheapLate.ID}},
+		{"harga membatasi", "1.2", 9, []uuid.UUID{cheapEarly.ID, cheapLate.ID}},
+		{"level berikutnya", "1.5", 6, []uuid.UUID{cheapEarly.ID, cheapLate.ID, pricey.ID}},
+		{"tidak menyilang", "0.9", 1, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []domain.MarketListing
+			err := db.Transaction(func(tx *gorm.DB) error {
+				var err error
+				got, err = lockCrossingListings(tx, domain.CurrencyGrass, buyer, decimal.RequireFromString(tc.price), tc.quantity)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("lock: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("dapat %d listing, harus %d", len(got), len(tc.want))
+			}
+			for i, l := range got {
+				if l.ID != tc.want[i] || l.ID == own.ID || l.ID == expired.ID {
+					t.Fatalf("listing ke-%d = %s, harus %s", i, l.ID, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPlaceBidMatchesAndCancelRefundsReserve(t *testing.T) {
+	type fill struct {
+		listing int
+		qty     int
+	}
+	cases := []struct {
+		name          string
+		quantity      int
+		price         string
+		wantFills     []fill
+		wantRemaining int
+		wantStatus    string
+		wantSpent     string // Nilai fill + fee taker 2%
+	}{
+		{"terisi penuh dari listing terlama", 4, "1.0", []fill{{0, 3}, {1, 1}}, 0, domain.BidFilled, "4.08"},
+		{"partial, sisa resting", 10, "1.2", []fill{{0, 3}, {1, 2}}, 5, domain.BidPartiallyFilled, "5.1"},
+		{"tidak menyilang", 2, "0.9", nil, 2, domain.BidOpen, "0"},
+		{"menyapu dua level harga", 7, "1.5", []fill{{0, 3}, {1, 2}, {2, 2}}, 0, domain.BidFilled, "8.16"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newMarketTestDB(t)
+			seller, buyer := newTrader(t, db), newTrader(t, db)
+			t0 := time.Now().Add(-time.Hour)
+			listings := []*domain.MarketListing{
+				restListing(t, db, seller, 3, "1.0", t0),
+				restListing(t, db, seller, 2, "1.0", t0.Add(time.Minute)),
+				restListing(t, db, seller, 4, "1.5", t0.Add(2*time.Minute)),
+			}
+
+			result, err := NewOrderBookUsecase(db).PlaceBid(context.Background(), buyer, domain.CurrencyGrass, tc.quantity, decimal.RequireFromString(tc.price))
+			if err != nil {
+				t.Fatalf("place bid: %v", err)
+			}
+			if len(result.Fills) != len(tc.wantFills) {
+				t.Fatalf("dapat %d fill, harus %d", len(result.Fills), len(tc.wantFills))
+			}
+			for i, f := range tc.wantFills {
+				got := result.Fills[i]
+				if got.ListingID != listings[f.listing].ID || got.Quantity != f.qty || !got.UnitPriceUSDT.Equal(listings[f.listing].UnitPriceUSDT) {
+					t.Fatalf("fill ke-%d = %s x%d @%s, harus listing %d x%d pada harga listing", i, got.ListingID, got.Quantity, got.UnitPriceUSDT, f.listing, f.qty)
+				}
+			}
+			if result.Bid.RemainingQuantity != tc.wantRemaining || result.Bid.Status != tc.wantStatus {
+				t.Fatalf("bid = sisa %d %s, harus sisa %d %s", result.Bid.RemainingQuantity, result.Bid.Status, tc.wantRemaining, tc.wantStatus)
+			}
+
+			// Escrow hanya menahan nilai sisa bid ditambah cadangan fee-nya
+			spent := decimal.RequireFromString(tc.wantSpent)
+			held := bidHold(&result.Bid, result.Bid.RemainingQuantity)
+			if got := accountBalance(t, db, marketEscrowUSDT); !got.Equal(held) {
+				t.Fatalf("escrow USDT = %s, harus %s", got, held)
+			}
+			if got := usdtBalance(t, db, buyer); !got.Equal(decimal.NewFromInt(100).Sub(spent).Sub(held)) {
+				t.Fatalf("saldo buyer = %s, harus 100 - %s - %s", got, spent, held)
+			}
+
+			if result.Bid.IsActive() {
+				if err := NewOrderBookUsecase(db).CancelBid(context.Background(), buyer, result.Bid.ID); err != nil {
+					t.Fatalf("cancel: %v", err)
+				}
+			}
+			if got := accountBalance(t, db, marketEscrowUSDT); !got.IsZero() {
+				t.Fatalf("escrow USDT setelah cancel = %s, harus 0", got)
+			}
+			if got := usdtBalance(t, db, buyer); !got.Equal(decimal.NewFromInt(100).Sub(spent)) {
+				t.Fatalf("saldo buyer setelah cancel = %s, harus 100 - %s", got, spent)
+			}
+		})
+	}
+}
+
+func TestMatchingContinuesPastBatchLimit(t *testing.T) {
+	orders := maxMatchesPerOrder + 10
+
+	t.Run("bid menyapu listing", func(t *testing.T) {
+		db := newMarketTestDB(t)
+		seller, buyer := newTrader(t, db), newTrader(t, db)
+		t0 := time.Now().Add(-time.Hour)
+		for i := 0; i < orders; i++ {
+			restListing(t, db, seller, 1, "1", t0.Add(time.Duration(i)*time.Second))
+		}
+
+		result, err := NewOrderBookUsecase(db).PlaceBid(context.Background(), buyer, domain.CurrencyGrass, orders, decimal.NewFromInt(1))
+		if err != nil {
+			t.Fatalf("place bid: %v", err)
+		}
+		if len(result.Fills) != orders || result.Bid.Status != domain.BidFilled {
+			t.Fatalf("dapat %d fill (%s), harus %d dan FILLED", len(result.Fills), result.Bid.Status, orders)
+		}
+		if got := accountBalance(t, db, marketEscrowUSDT); !got.IsZero() {
+			t.Fatalf("escrow USDT = %s, harus 0", got)
+		}
+	})
+
+	t.Run("listing menyapu bid", func(t *testing.T) {
+		db := newMarketTestDB(t)
+		seller, buyer := newTrader(t, db), newTrader(t, db)
+		book := NewOrderBookUsecase(db)
+		for i := 0; i < orders; i++ {
+			if _, err := book.PlaceBid(context.Background(), buyer, domain.CurrencyGrass, 1, decimal.NewFromInt(1)); err != nil {
+				t.Fatalf("place bid %d: %v", i, err)
+			}
+		}
+
+		listing, err := NewMarketUsecase(db).SellItem(context.Background(), seller, domain.CurrencyGrass, orders, decimal.NewFromInt(1), nil)
+		if err != nil {
+			t.Fatalf("sell: %v", err)
+		}
+		if listing.RemainingQuantity != 0 || listing.Status != domain.ListingSold {
+			t.Fatalf("listing = sisa %d %s, harus habis terjual", listing.RemainingQuantity, listing.Status)
+		}
+		var open int64
+		db.Model(&domain.MarketBid{}).Where("status IN ?", activeBidStatuses).Count(&open)
+		if open != 0 {
+			t.Fatalf("%d bid masih aktif, buku tertinggal crossed", open)
+		}
+		// Bid terisi sebagai maker (1%): selisih dengan cadangan 2% dikembalikan, escrow kosong
+		if got := accountBalance(t, db, marketEscrowUSDT); !got.IsZero() {
+			t.Fatalf("escrow USDT = %s, harus 0", got)
+		}
+		want := decimal.NewFromInt(100).Sub(decimal.NewFromInt(int64(orders)).Mul(decimal.RequireFromString("1.01")))
+		if got := usdtBalance(t, db, buyer); !got.Equal(want) {
+			t.Fatalf("saldo buyer = %s, harus %s", got, want)
+		}
+	})
+}
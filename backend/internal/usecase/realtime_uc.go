@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/pkg/realtime"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	harvestCooldown        = time.Hour // Sama dengan syarat minimal 1 jam di HarvestFarm
+	harvestNotifyInterval  = time.Minute
+	standardCowCareTimeout = 24 * time.Hour
+)
+
+// BalanceUpdate adalah payload event balance.updated.
+type BalanceUpdate struct {
+	GoldBalance decimal.Decimal `json:"gold_balance"`
+	USDTBalance decimal.Decimal `json:"usdt_balance"`
+	Points      decimal.Decimal `json:"points"`
+}
+
+// InventoryUpdate adalah payload event inventory.updated.
+type InventoryUpdate struct {
+	Grass           int   `json:"grass"`
+	Milk            int   `json:"milk"`
+	LandSlots       int   `json:"land_slots"`
+	HasBarn         bool  `json:"has_barn"`
+	Cows            int64 `json:"cows"`
+	ListedCows      int64 `json:"listed_cows"`
+	HarvestableCows int64 `json:"harvestable_cows"`
+}
+
+// HarvestReady adalah payload event harvest.ready.
+type HarvestReady struct {
+	CowIDs []uuid.UUID `json:"cow_ids"`
+}
+
+// ListingSold adalah payload event listing.sold untuk setiap fill.
+type ListingSold struct {
+	ListingID     uuid.UUID       `json:"listing_id"`
+	ItemType      string          `json:"item_type"`
+	Quantity      int             `json:"quantity"`
+	UnitPriceUSDT decimal.Decimal `json:"unit_price_usdt"`
+	Remaining     int             `json:"remaining"`
+	Status        string          `json:"status"`
+}
+
+type RealtimeUsecase struct {
+	db *gorm.DB
+}
+
+func NewRealtimeUsecase(db *gorm.DB) *RealtimeUsecase {
+	return &RealtimeUsecase{db: db}
+}
+
+// readyCowsQuery memilih sapi yang bisa dipanen dengan aturan yang sama seperti HarvestFarm:
+// tidak sedang dijual, sudah lewat cooldown, sapi STANDARD butuh vitamin iklan dalam 24 jam, dan
+// sapi tidak sedang STARVING (tidak berproduksi) pada `now`.
+func readyCowsQuery(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Model(&domain.Cow{}).
+		Joins("JOIN users ON users.id = cows.owner_id").
+		Where("cows.listing_id IS NULL").
+		Where("cows.type <> ? OR users.last_ad_watched_at >= ?", domain.TypeStandard, now.Add(-standardCowCareTimeout)).
+		Where("COALESCE(cows.last_fed_at, cows.created_at) > ?", now.Add(-domain.CowStarvingAfter))
+}
+
+func walletEvents(db *gorm.DB, userID uuid.UUID) ([]realtime.Event, error) {
+	var user domain.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	var inventory domain.Inventory
+	if err := db.Where("user_id = ?", userID).First(&inventory).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+	update := InventoryUpdate{
+		Grass:     inventory.Grass,
+		Milk:      inventory.Milk,
+		LandSlots: inventory.LandSlots,
+		HasBarn:   inventory.HasBarn,
+	}
+	if err := db.Model(&domain.Cow{}).Where("owner_id = ?", userID).Count(&update.Cows).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&domain.Cow{}).Where("owner_id = ? AND listing_id IS NOT NULL", userID).Count(&update.ListedCows).Error; err != nil {
+		return nil, err
+	}
+	if err := readyCowsQuery(db, now).
+		Where("cows.owner_id = ? AND COALESCE(cows.last_harvested_at, cows.created_at) <= ?", userID, now.Add(-harvestCooldown)).
+		Count(&update.HarvestableCows).Error; err != nil {
+		return nil, err
+	}
+
+	id := userID.String()
+	balance, err := realtime.NewEvent(realtime.EventBalanceUpdated, id, BalanceUpdate{
+		GoldBalance: user.GoldBalance,
+		USDTBalance: user.USDTBalance,
+		Points:      user.Points,
+	})
+	if err != nil {
+		return nil, err
+	}
+	inv, err := realtime.NewEvent(realtime.EventInventoryUpdated, id, update)
+	if err != nil {
+		return nil, err
+	}
+	return []realtime.Event{balance, inv}, nil
+}
+
+// flushRealtime mempublikasikan isi Collector setelah transaksi commit: event yang diantrekan,
+// lalu saldo & inventory terbaru setiap user yang tersentuh.
+func flushRealtime(ctx context.Context, db *gorm.DB, col *realtime.Collector) {
+	if col == nil {
+		return
+	}
+	users, events := col.Drain()
+	for _, evt := range events {
+		realtime.Publish(ctx, evt)
+	}
+	for _, userID := range users {
+		wallet, err := walletEvents(db.WithContext(ctx), userID)
+		if err != nil {
+			log.Printf("[REALTIME] Gagal memuat saldo user %s: %v", userID, err)
+			continue
+		}
+		for _, evt := range wallet {
+			realtime.Publish(ctx, evt)
+		}
+	}
+}
+
+// Flush dipanggil middleware setelah request selesai.
+func (uc *RealtimeUsecase) Flush(ctx context.Context, col *realtime.Collector) {
+	flushRealtime(ctx, uc.db, col)
+}
+
+// Snapshot adalah event awal yang dikirim saat stream baru terhubung, agar klien tidak perlu
+// memanggil /farm/status terpisah.
+func (uc *RealtimeUsecase) Snapshot(ctx context.Context, userID uuid.UUID) ([]realtime.Event, error) {
+	return walletEvents(uc.db.WithContext(ctx), userID)
+}
+
+// NotifyHarvestReady mengirim harvest.ready untuk sapi yang cooldown panennya berakhir di [from, to).
+func (uc *RealtimeUsecase) NotifyHarvestReady(ctx context.Context, from, to time.Time) (int, error) {
+	type readyCow struct {
+		ID      uuid.UUID
+		OwnerID uuid.UUID
+	}
+	var cows []readyCow
+	if err := readyCowsQuery(uc.db.WithContext(ctx), to).
+		Select("cows.id, cows.owner_id").
+		Where("COALESCE(cows.last_harvested_at, cows.created_at) >= ? AND COALESCE(cows.last_harvested_at, cows.created_at) < ?",
+			from.Add(-harvestCooldown), to.Add(-harvestCooldown)).
+		Scan(&cows).Error; err != nil {
+		return 0, err
+	}
+
+	byOwner := make(map[uuid.UUID][]uuid.UUID)
+	order := make([]uuid.UUID, 0)
+	for _, c := range cows {
+		if _, ok := byOwner[c.OwnerID]; !ok {
+			order = append(order, c.OwnerID)
+		}
+		byOwner[c.OwnerID] = append(byOwner[c.OwnerID], c.ID)
+	}
+	for _, ownerID := range order {
+		evt, err := realtime.NewEvent(realtime.EventHarvestReady, ownerID.String(), HarvestReady{CowIDs: byOwner[ownerID]})
+		if err != nil {
+			return 0, err
+		}
+		realtime.Publish(ctx, evt)
+	}
+	return len(order), nil
+}
+
+// StartHarvestNotifier memeriksa sapi yang baru siap panen setiap menit. Setiap jendela waktu dikunci
+// dengan Redlock yang tidak dilepas, sehingga dengan banyak replica tiap jendela hanya dinotifikasi sekali.
+func (uc *RealtimeUsecase) StartHarvestNotifier(ctx context.Context) {
+	ticker := time.NewTicker(harvestNotifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			to := now.UTC().Truncate(harvestNotifyInterval)
+			from := to.Add(-harvestNotifyInterval)
+
+			lockKey := "harvest_ready_notify:" + to.Format(time.RFC3339)
+			if _, acquired := customRedis.AcquireLock(ctx, lockKey, 2*harvestNotifyInterval); !acquired {
+				continue
+			}
+			if _, err := uc.NotifyHarvestReady(ctx, from, to); err != nil {
+				log.Printf("[REALTIME] Gagal mengirim notifikasi panen: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// reconciledCurrencies adalah saldo yang dibandingkan terhadap histori TxLog.
+var reconciledCurrencies = []string{
+	domain.CurrencyGold,
+	domain.CurrencyUSDT,
+	domain.CurrencyCOW,
+	domain.CurrencyGrass,
+	domain.CurrencyMilk,
+}
+
+// legacyTxSign menerjemahkan TxLog era sebelum ledger (Amount tidak bertanda) ke arah mutasinya.
+// Tipe yang tidak ada di sini (mis. MARKET_SELL, TREASURY_*) tidak mengubah saldo pemain.
+var legacyTxSign = map[string]int64{
+	"DEPOSIT":                  1,
+	"WITHDRAW":                 -1,
+	"SELL_MILK_GOLD":           1,
+	"BUY_ITEM_GOLD":            -1,
+	"GOLD_SWAP":                -1,
+	"PLATFORM_BUY":             -1,
+	"REFERRAL_BONUS":           1,
+	"MARKET_BUY":               -1,
+	"FEED_COW":                 -1,
+	"HARVEST_MILK":             1,
+	"ADMIN_TRANSFER_GOLD":      1,
+	"ADMIN_TRANSFER_USDT":      1,
+	"ADMIN_TRANSFER_COW_TOKEN": 1,
+	"ADMIN_TRANSFER_GRASS":     1,
+	"ADMIN_TRANSFER_MILK":      1,
+}
+
+// legacySign mengembalikan tanda TxLog lama untuk saldo. TxLog CATTLE adalah audit perpindahan sapi
+// yang Amount-nya sudah bertanda dan tidak pernah memengaruhi saldo, termasuk baris lama bertipe MARKET_BUY.
+func legacySign(txType, currency string) (int64, bool) {
+	if currency == domain.ItemCattle {
+		return 0, false
+	}
+	sign, ok := legacyTxSign[txType]
+	return sign, ok
+}
+
+// legacyTxCurrency menormalkan currency TxLog lama ke kode ledger.
+var legacyTxCurrency = map[string]string{
+	"COW_TOKEN": domain.CurrencyCOW,
+}
+
+var ErrNoReconciliationRun = errors.New("Belum ada hasil rekonsiliasi")
+
+type ReconciliationUsecase struct {
+	db *gorm.DB
+}
+
+func NewReconciliationUsecase(db *gorm.DB) *ReconciliationUsecase {
+	return &ReconciliationUsecase{db: db}
+}
+
+type balanceKey struct {
+	UserID   uuid.UUID
+	Currency string
+}
+
+// txLogSums adalah agregat TxLog per user & currency.
+type txLogSums struct {
+	ledger  map[balanceKey]decimal.Decimal // TxLog hasil ledger (bertanda), termasuk OPENING_BALANCE
+	legacy  map[balanceKey]decimal.Decimal // TxLog lama yang diberi tanda lewat legacyTxSign
+	opening map[balanceKey]decimal.Decimal // OPENING_BALANCE yang disalin dari projection saat akun dibuka
+}
+
+// Run menghitung ulang saldo GOLD, USDT, COW, GRASS dan MILK setiap user dari TxLog
+// lalu menyimpan selisih terhadap kolom User/Inventory ke tabel reconciliation_reports.
+//
+// TxLog yang dihasilkan ledger (EntryID terisi) sudah bertanda dan termasuk OPENING_BALANCE,
+// sehingga cukup dijumlahkan apa pun statusnya: hold withdrawal yang PENDING atau ROLLED_BACK
+// tetap merupakan posting nyata (refund dibukukan sebagai entry terpisah). User yang belum pernah
+// menyentuh ledger untuk suatu currency dihitung dari TxLog lama dengan tabel legacyTxSign.
+//
+// OPENING_BALANCE menyalin projection saat akun dibuka, sehingga drift dari era sebelum ledger ikut
+// terbawa. Karena itu saldo pembukaan juga dibandingkan terhadap total TxLog lama (Kind OPENING_BALANCE).
+// Agregat TxLog dan saldo user dibaca dari satu snapshot REPEATABLE READ agar trade yang berjalan
+// selama job tidak terbaca sebagai drift.
+func (uc *ReconciliationUsecase) Run(ctx context.Context, trigger string) (*domain.ReconciliationRun, error) {
+	run := domain.ReconciliationRun{
+		Trigger:   trigger,
+		Status:    domain.TxPending,
+		StartedAt: time.Now(),
+	}
+	if err := uc.db.WithContext(ctx).Create(&run).Error; err != nil {
+		return nil, err
+	}
+
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sums, err := sumTxLogs(tx)
+		if err != nil {
+			return err
+		}
+
+		var users []domain.User
+		return tx.Preload("Inventory").FindInBatches(&users, 500, func(batch *gorm.DB, _ int) error {
+			reports := make([]domain.ReconciliationReport, 0)
+			for _, u := range users {
+				run.UsersChecked++
+				stored := map[string]decimal.Decimal{
+					domain.CurrencyGold:  u.GoldBalance,
+					domain.CurrencyUSDT:  u.USDTBalance,
+					domain.CurrencyCOW:   u.Points,
+					domain.CurrencyGrass: decimal.NewFromInt(int64(u.Inventory.Grass)),
+					domain.CurrencyMilk:  decimal.NewFromInt(int64(u.Inventory.Milk)),
+				}
+
+				for _, currency := range reconciledCurrencies {
+					key := balanceKey{UserID: u.ID, Currency: currency}
+					expected, inLedger := sums.ledger[key]
+					if !inLedger {
+						expected = sums.legacy[key]
+					}
+					if !stored[currency].Equal(expected) {
+						reports = append(reports, domain.ReconciliationReport{
+							RunID:    run.ID,
+							UserID:   u.ID,
+							Currency: currency,
+							Kind:     domain.ReconcileProjection,
+							Expected: expected,
+							Stored:   stored[currency],
+							Drift:    stored[currency].Sub(expected),
+						})
+					}
+
+					// Saldo pembukaan harus bisa dijelaskan oleh histori TxLog sebelum ledger aktif
+					if opening, legacy := sums.opening[key], sums.legacy[key]; inLedger && !opening.Equal(legacy) {
+						reports = append(reports, domain.ReconciliationReport{
+							RunID:    run.ID,
+							UserID:   u.ID,
+							Currency: currency,
+							Kind:     domain.ReconcileOpening,
+							Expected: legacy,
+							Stored:   opening,
+							Drift:    opening.Sub(legacy),
+						})
+					}
+				}
+			}
+
+			if len(reports) == 0 {
+				return nil
+			}
+			run.Mismatches += len(reports)
+			return tx.Create(&reports).Error
+		}).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		uc.finishRun(ctx, &run, domain.TxFailed)
+		return nil, err
+	}
+
+	uc.finishRun(ctx, &run, domain.TxSuccess)
+	return &run, nil
+}
+
+// sumTxLogs mengagregasi TxLog SUCCESS per user & currency, dipisah antara era ledger dan era lama.
+func sumTxLogs(tx *gorm.DB) (*txLogSums, error) {
+	type row struct {
+		UserID   uuid.UUID
+		Type     string
+		Currency string
+		Total    decimal.Decimal
+	}
+
+	var ledgerRows []row
+	if err := tx.Model(&domain.TxLog{}).
+		Select("user_id, type, currency, SUM(amount) AS total").
+		Where("entry_id IS NOT NULL").
+		Group("user_id, type, currency").Scan(&ledgerRows).Error; err != nil {
+		return nil, err
+	}
+
+	var legacyRows []row
+	if err := tx.Model(&domain.TxLog{}).
+		Select("user_id, type, currency, SUM(amount) AS total").
+		Where("status = ? AND entry_id IS NULL", domain.TxSuccess).
+		Group("user_id, type, currency").Scan(&legacyRows).Error; err != nil {
+		return nil, err
+	}
+
+	sums := &txLogSums{
+		ledger:  make(map[balanceKey]decimal.Decimal, len(ledgerRows)),
+		legacy:  make(map[balanceKey]decimal.Decimal, len(legacyRows)),
+		opening: make(map[balanceKey]decimal.Decimal),
+	}
+	for _, r := range ledgerRows {
+		key := balanceKey{UserID: r.UserID, Currency: r.Currency}
+		sums.ledger[key] = sums.ledger[key].Add(r.Total)
+		if r.Type == "OPENING_BALANCE" {
+			sums.opening[key] = sums.opening[key].Add(r.Total)
+		}
+	}
+
+	for _, r := range legacyRows {
+		sign, ok := legacySign(r.Type, r.Currency)
+		if !ok {
+			continue
+		}
+		currency := r.Currency
+		if normalized, ok := legacyTxCurrency[currency]; ok {
+			currency = normalized
+		}
+		key := balanceKey{UserID: r.UserID, Currency: currency}
+		sums.legacy[key] = sums.legacy[key].Add(r.Total.Mul(decimal.NewFromInt(sign)))
+	}
+
+	return sums, nil
+}
+
+func (uc *ReconciliationUsecase) finishRun(ctx context.Context, run *domain.ReconciliationRun, status domain.TxStatus) {
+	now := time.Now()
+	run.Status = status
+	run.FinishedAt = &now
+	if err := uc.db.WithContext(ctx).Save(run).Error; err != nil {
+		log.Printf("[RECONCILIATION] Gagal menyimpan status run %s: %v", run.ID, err)
+	}
+}
+
+type ReconciliationResult struct {
+	Run     *domain.ReconciliationRun     `json:"run"`
+	Reports []domain.ReconciliationReport `json:"reports"`
+}
+
+// GetReports mengembalikan run tertentu (atau run terakhir jika runID nil) beserta drift-nya.
+func (uc *ReconciliationUsecase) GetReports(ctx context.Context, runID *uuid.UUID) (*ReconciliationResult, error) {
+	var run domain.ReconciliationRun
+	query := uc.db.WithContext(ctx)
+	if runID != nil {
+		query = query.Where("id = ?", *runID)
+	}
+	if err := query.Order("started_at DESC").First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoReconciliationRun
+		}
+		return nil, err
+	}
+
+	var reports []domain.ReconciliationReport
+	if err := uc.db.WithContext(ctx).Where("run_id = ?", run.ID).
+		Order("user_id, currency").Find(&reports).Error; err != nil {
+		return nil, err
+	}
+
+	return &ReconciliationResult{Run: &run, Reports: reports}, nil
+}
+
+// StartNightly menjalankan Run setiap hari pada jam RECONCILIATION_HOUR_UTC (default 02:00 UTC).
+// Aman untuk banyak replica: hanya replica yang memegang Redlock harian yang mengeksekusi.
+func (uc *ReconciliationUsecase) StartNightly(ctx context.Context) {
+	hour := 2
+	if v, err := strconv.Atoi(os.Getenv("RECONCILIATION_HOUR_UTC")); err == nil && v >= 0 && v < 24 {
+		hour = v
+	}
+
+	for {
+		now := time.Now().UTC()
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.Add(24 * time.Hour)
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Lock tidak dilepas: replica lain yang bangun beberapa detik kemudian harus melewati hari ini
+		lockKey := "reconciliation_nightly:" + next.Format("2006-01-02")
+		if _, acquired := customRedis.AcquireLock(ctx, lockKey, 23*time.Hour); !acquired {
+			continue
+		}
+
+		run, err := uc.Run(ctx, "NIGHTLY")
+		if err != nil {
+			log.Printf("[RECONCILIATION] Gagal menjalankan rekonsiliasi: %v", err)
+			continue
+		}
+		log.Printf("[RECONCILIATION] Selesai: %d user diperiksa, %d selisih ditemukan", run.UsersChecked, run.Mismatches)
+	}
+}
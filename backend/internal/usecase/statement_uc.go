@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type StatementUsecase struct {
+	db *gorm.DB
+}
+
+func NewStatementUsecase(db *gorm.DB) *StatementUsecase {
+	return &StatementUsecase{db: db}
+}
+
+// StatementLine adalah satu mutasi saldo beserta saldo berjalan currency-nya.
+type StatementLine struct {
+	TxID        uuid.UUID       `json:"tx_id"`
+	Date        time.Time       `json:"date"`
+	Type        string          `json:"type"`
+	Currency    string          `json:"currency"`
+	Amount      decimal.Decimal `json:"amount"`
+	Balance     decimal.Decimal `json:"balance"`
+	Status      domain.TxStatus `json:"status"`
+	ReferenceID *string         `json:"reference_id,omitempty"`
+}
+
+type CurrencyStatement struct {
+	Currency string          `json:"currency"`
+	Opening  decimal.Decimal `json:"opening_balance"`
+	Credits  decimal.Decimal `json:"credits"`
+	Debits   decimal.Decimal `json:"debits"`
+	Closing  decimal.Decimal `json:"closing_balance"`
+	Lines    []StatementLine `json:"lines"`
+}
+
+// Statement adalah laporan bulanan satu user: saldo awal, setiap mutasi dan saldo akhir per currency.
+type Statement struct {
+	UserID        uuid.UUID           `json:"user_id"`
+	WalletAddress string              `json:"wallet_address"`
+	Month         string              `json:"month"` // YYYY-MM
+	PeriodStart   time.Time           `json:"period_start"`
+	PeriodEnd     time.Time           `json:"period_end"` // Eksklusif
+	GeneratedAt   time.Time           `json:"generated_at"`
+	Currencies    []CurrencyStatement `json:"currencies"`
+}
+
+// statementRow adalah TxLog (atau agregatnya) yang sudah dinormalisasi untuk perhitungan saldo.
+type statementRow struct {
+	Type     string
+	Currency string
+	Ledger   bool
+	Status   domain.TxStatus
+	Amount   decimal.Decimal
+}
+
+// balanceDelta mengembalikan perubahan saldo dari satu baris, memakai aturan yang sama dengan rekonsiliasi:
+// currency yang sudah punya akun ledger hanya dihitung dari TxLog ledger (termasuk OPENING_BALANCE),
+// currency lain dari TxLog lama yang SUCCESS dengan tanda dari legacyTxSign.
+func (r statementRow) balanceDelta(ledgerCurrencies map[string]bool) (decimal.Decimal, bool) {
+	if ledgerCurrencies[r.Currency] {
+		return r.Amount, r.Ledger
+	}
+	if r.Ledger || r.Status != domain.TxSuccess {
+		return decimal.Zero, false
+	}
+	sign, ok := legacySign(r.Type, r.Currency)
+	if !ok {
+		return decimal.Zero, false
+	}
+	return r.Amount.Mul(decimal.NewFromInt(sign)), true
+}
+
+func normalizeTxCurrency(currency string) string {
+	if normalized, ok := legacyTxCurrency[currency]; ok {
+		return normalized
+	}
+	return currency
+}
+
+// ParseStatementMonth memvalidasi bulan (YYYY-MM, UTC). Bulan kosong berarti bulan berjalan.
+func ParseStatementMonth(raw string) (time.Time, error) {
+	now := time.Now().UTC()
+	if raw == "" {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	month, err := time.Parse("2006-01", raw)
+	if err != nil {
+		return time.Time{}, errors.New("Format bulan tidak valid (YYYY-MM)")
+	}
+	if month.After(now) {
+		return time.Time{}, errors.New("Bulan belum berjalan")
+	}
+	return month, nil
+}
+
+// Monthly membuat statement untuk bulan `month` (tanggal 1, UTC).
+func (uc *StatementUsecase) Monthly(ctx context.Context, userID uuid.UUID, month time.Time) (*Statement, error) {
+	db := uc.db.WithContext(ctx)
+
+	var user domain.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("User tidak ditemukan")
+		}
+		return nil, err
+	}
+
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	var ledgerCurrencyList []string
+	if err := db.Model(&domain.TxLog{}).Where("user_id = ? AND entry_id IS NOT NULL", userID).
+		Distinct("currency").Pluck("currency", &ledgerCurrencyList).Error; err != nil {
+		return nil, err
+	}
+	ledgerCurrencies := make(map[string]bool, len(ledgerCurrencyList))
+	for _, c := range ledgerCurrencyList {
+		ledgerCurrencies[normalizeTxCurrency(c)] = true
+	}
+
+	// Saldo awal: agregat semua baris sebelum periode
+	var openingRows []statementRow
+	if err := db.Model(&domain.TxLog{}).
+		Select("type, currency, entry_id IS NOT NULL AS ledger, status, SUM(amount) AS amount").
+		Where("user_id = ? AND created_at < ?", userID, start).
+		Group("type, currency, entry_id IS NOT NULL, status").Scan(&openingRows).Error; err != nil {
+		return nil, err
+	}
+
+	statements := make(map[string]*CurrencyStatement)
+	get := func(currency string) *CurrencyStatement {
+		s, ok := statements[currency]
+		if !ok {
+			s = &CurrencyStatement{Currency: currency, Lines: make([]StatementLine, 0)}
+			statements[currency] = s
+		}
+		return s
+	}
+
+	for _, r := range openingRows {
+		r.Currency = normalizeTxCurrency(r.Currency)
+		if delta, ok := r.balanceDelta(ledgerCurrencies); ok {
+			s := get(r.Currency)
+			s.Opening = s.Opening.Add(delta)
+		}
+	}
+	for _, s := range statements {
+		s.Closing = s.Opening
+	}
+
+	var logs []domain.TxLog
+	if err := db.Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, start, end).
+		Order("created_at, id").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range logs {
+		row := statementRow{
+			Type:     l.Type,
+			Currency: normalizeTxCurrency(l.Currency),
+			Ledger:   l.EntryID != nil,
+			Status:   l.Status,
+			Amount:   l.Amount,
+		}
+		delta, ok := row.balanceDelta(ledgerCurrencies)
+		if !ok {
+			continue
+		}
+
+		s := get(row.Currency)
+		s.Closing = s.Closing.Add(delta)
+		if delta.IsPositive() {
+			s.Credits = s.Credits.Add(delta)
+		} else {
+			s.Debits = s.Debits.Add(delta.Neg())
+		}
+		s.Lines = append(s.Lines, StatementLine{
+			TxID:        l.ID,
+			Date:        l.CreatedAt,
+			Type:        l.Type,
+			Currency:    row.Currency,
+			Amount:      delta,
+			Balance:     s.Closing,
+			Status:      l.Status,
+			ReferenceID: l.ReferenceID,
+		})
+	}
+
+	currencies := make([]string, 0, len(statements))
+	for currency := range statements {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	statement := &Statement{
+		UserID:        user.ID,
+		WalletAddress: user.WalletAddress,
+		Month:         start.Format("2006-01"),
+		PeriodStart:   start,
+		PeriodEnd:     end,
+		GeneratedAt:   time.Now().UTC(),
+		Currencies:    make([]CurrencyStatement, 0, len(currencies)),
+	}
+	for _, currency := range currencies {
+		statement.Currencies = append(statement.Currencies, *statements[currency])
+	}
+	return statement, nil
+}
@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTxPageSize = 20
+	maxTxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("Cursor tidak valid")
+
+type TransactionUsecase struct {
+	db *gorm.DB
+}
+
+func NewTransactionUsecase(db *gorm.DB) *TransactionUsecase {
+	return &TransactionUsecase{db: db}
+}
+
+// TransactionFilter adalah filter riwayat TxLog milik satu user. Field kosong berarti tanpa filter.
+type TransactionFilter struct {
+	Types    []string
+	Currency string
+	Status   domain.TxStatus
+	From     *time.Time // Inklusif
+	To       *time.Time // Eksklusif
+	Cursor   string
+	Limit    int
+}
+
+// TransactionItem adalah satu baris TxLog untuk pemain. Amount selalu bertanda (negatif = keluar),
+// termasuk TxLog era sebelum ledger yang diterjemahkan dengan legacyTxSign.
+type TransactionItem struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          string          `json:"type"`
+	Amount        decimal.Decimal `json:"amount"`
+	Currency      string          `json:"currency"`
+	Status        domain.TxStatus `json:"status"`
+	ReferenceID   *string         `json:"reference_id,omitempty"`
+	EntryID       *uuid.UUID      `json:"entry_id,omitempty"`
+	ConfigVersion *int            `json:"config_version,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// CurrencyTotal adalah jumlah masuk/keluar untuk satu currency pada seluruh hasil filter (bukan hanya halaman ini).
+type CurrencyTotal struct {
+	Currency string          `json:"currency"`
+	Credit   decimal.Decimal `json:"credit"`
+	Debit    decimal.Decimal `json:"debit"`
+	Net      decimal.Decimal `json:"net"`
+	Count    int64           `json:"count"`
+}
+
+type TransactionPage struct {
+	Transactions []TransactionItem `json:"transactions"`
+	Totals       []CurrencyTotal   `json:"totals"`
+	NextCursor   string            `json:"-"`
+	HasMore      bool              `json:"-"`
+	Limit        int               `json:"-"`
+}
+
+// encodeTxCursor membuat cursor opaque dari posisi baris terakhir (created_at DESC, id DESC).
+func encodeTxCursor(tx domain.TxLog) string {
+	raw := fmt.Sprintf("%d|%s", tx.CreatedAt.UnixNano(), tx.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTxCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return time.Unix(0, nanos), id, nil
+}
+
+// signedTxAmount mengembalikan Amount bertanda. TxLog ledger dan TxLog CATTLE sudah bertanda; TxLog lama
+// diberi tanda dari legacyTxSign; tipe yang tidak dikenal (mis. MARKET_SELL) ditampilkan apa adanya.
+func signedTxAmount(tx domain.TxLog) decimal.Decimal {
+	if tx.EntryID != nil {
+		return tx.Amount
+	}
+	if sign, ok := legacySign(tx.Type, tx.Currency); ok {
+		return tx.Amount.Mul(decimal.NewFromInt(sign))
+	}
+	return tx.Amount
+}
+
+// filteredTxLogs membangun query TxLog user sesuai filter (tanpa cursor & limit).
+func (uc *TransactionUsecase) filteredTxLogs(ctx context.Context, userID uuid.UUID, f TransactionFilter) *gorm.DB {
+	query := uc.db.WithContext(ctx).Model(&domain.TxLog{}).Where("user_id = ?", userID)
+	// TxLog TREASURY_* lama adalah kas platform yang dulu dicatat atas nama pembeli, bukan milik user;
+	// MARKET_REPRICE lama hanya audit harga (Amount = harga baru), bukan mutasi saldo
+	query = query.Where("type NOT IN ?", []string{"TREASURY_LP_BUYBACK", "TREASURY_DEV_FEE", "MARKET_REPRICE"})
+	if len(f.Types) > 0 {
+		query = query.Where("type IN ?", f.Types)
+	}
+	if f.Currency != "" {
+		currencies := []string{f.Currency}
+		for legacy, normalized := range legacyTxCurrency {
+			if normalized == f.Currency {
+				currencies = append(currencies, legacy)
+			}
+		}
+		query = query.Where("currency IN ?", currencies)
+	}
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
+	}
+	if f.From != nil {
+		query = query.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("created_at < ?", *f.To)
+	}
+	return query
+}
+
+// ListTransactions mengembalikan riwayat TxLog user (terbaru dulu) dengan cursor pagination
+// dan total per currency untuk seluruh hasil filter.
+func (uc *TransactionUsecase) ListTransactions(ctx context.Context, userID uuid.UUID, f TransactionFilter) (*TransactionPage, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultTxPageSize
+	}
+	if f.Limit > maxTxPageSize {
+		f.Limit = maxTxPageSize
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return nil, errors.New("Rentang tanggal tidak valid")
+	}
+
+	query := uc.filteredTxLogs(ctx, userID, f)
+	if f.Cursor != "" {
+		createdAt, id, err := decodeTxCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", createdAt, createdAt, id)
+	}
+
+	var logs []domain.TxLog
+	if err := query.Order("created_at DESC, id DESC").Limit(f.Limit + 1).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	page := &TransactionPage{Transactions: make([]TransactionItem, 0, len(logs)), Limit: f.Limit}
+	if len(logs) > f.Limit {
+		logs = logs[:f.Limit]
+		page.HasMore = true
+		page.NextCursor = encodeTxCursor(logs[len(logs)-1])
+	}
+	for _, l := range logs {
+		page.Transactions = append(page.Transactions, TransactionItem{
+			ID:            l.ID,
+			Type:          l.Type,
+			Amount:        signedTxAmount(l),
+			Currency:      normalizeTxCurrency(l.Currency),
+			Status:        l.Status,
+			ReferenceID:   l.ReferenceID,
+			EntryID:       l.EntryID,
+			ConfigVersion: l.ConfigVersion,
+			CreatedAt:     l.CreatedAt,
+		})
+	}
+
+	totals, err := uc.totals(ctx, userID, f)
+	if err != nil {
+		return nil, err
+	}
+	page.Totals = totals
+	return page, nil
+}
+
+// totals mengagregasi hasil filter per currency. Baris dikelompokkan per tipe agar TxLog lama
+// bisa diberi tanda dengan legacyTxSign seperti pada rekonsiliasi.
+func (uc *TransactionUsecase) totals(ctx context.Context, userID uuid.UUID, f TransactionFilter) ([]CurrencyTotal, error) {
+	type row struct {
+		Type     string
+		Currency string
+		Ledger   bool
+		Credit   decimal.Decimal
+		Debit    decimal.Decimal
+		Count    int64
+	}
+
+	var rows []row
+	if err := uc.filteredTxLogs(ctx, userID, f).
+		Select(`type, currency, entry_id IS NOT NULL AS ledger,
+			COALESCE(SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), 0) AS credit,
+			COALESCE(SUM(CASE WHEN amount < 0 THEN -amount ELSE 0 END), 0) AS debit,
+			COUNT(*) AS count`).
+		Group("type, currency, entry_id IS NOT NULL").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	byCurrency := make(map[string]*CurrencyTotal)
+	order := make([]string, 0)
+	for _, r := range rows {
+		currency := normalizeTxCurrency(r.Currency)
+		total, ok := byCurrency[currency]
+		if !ok {
+			total = &CurrencyTotal{Currency: currency}
+			byCurrency[currency] = total
+			order = append(order, currency)
+		}
+		total.Count += r.Count
+
+		credit, debit := r.Credit, r.Debit
+		if !r.Ledger && r.Currency != domain.ItemCattle {
+			// TxLog lama tidak bertanda: seluruh jumlah masuk ke satu sisi sesuai tipenya (CATTLE sudah bertanda)
+			switch legacyTxSign[r.Type] {
+			case 1:
+				credit, debit = r.Credit, decimal.Zero
+			case -1:
+				credit, debit = decimal.Zero, r.Credit
+			default:
+				credit, debit = decimal.Zero, decimal.Zero
+			}
+		}
+		total.Credit = total.Credit.Add(credit)
+		total.Debit = total.Debit.Add(debit)
+	}
+
+	sort.Strings(order)
+	result := make([]CurrencyTotal, 0, len(order))
+	for _, currency := range order {
+		total := byCurrency[currency]
+		total.Net = total.Credit.Sub(total.Debit)
+		result = append(result, *total)
+	}
+	return result, nil
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// legacyTreasuryTxTypes memetakan TxLog TREASURY_* lama (dicatat atas nama pembeli sebelum ledger aktif)
+// ke bucket treasury tujuannya.
+var legacyTreasuryTxTypes = map[string]string{
+	"TREASURY_LP_BUYBACK": domain.BucketLPBuyback,
+	"TREASURY_DEV_FEE":    domain.BucketDev,
+}
+
+// TreasuryBalance adalah saldo satu akun treasury platform per currency.
+type TreasuryBalance struct {
+	Bucket      string          `json:"bucket"`
+	Description string          `json:"description"`
+	Currency    string          `json:"currency"`
+	Balance     decimal.Decimal `json:"balance"`
+}
+
+// ListTreasury mengembalikan saldo akun treasury (LP buyback, dev, referral pool) dari ledger.
+// Bucket yang belum pernah menerima dana tetap ditampilkan dengan saldo USDT 0.
+func (uc *AdminUsecase) ListTreasury(ctx context.Context) ([]TreasuryBalance, error) {
+	buckets := make([]string, 0, len(domain.TreasuryBuckets))
+	for bucket := range domain.TreasuryBuckets {
+		buckets = append(buckets, bucket)
+	}
+
+	var accounts []domain.LedgerAccount
+	if err := uc.db.WithContext(ctx).
+		Where("kind = ? AND bucket IN ?", domain.AccountTreasury, buckets).
+		Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	balances := make([]TreasuryBalance, 0, len(accounts)+len(buckets))
+	for _, acc := range accounts {
+		seen[acc.Bucket] = true
+		balances = append(balances, TreasuryBalance{
+			Bucket:      acc.Bucket,
+			Description: domain.TreasuryBuckets[acc.Bucket],
+			Currency:    acc.Currency,
+			Balance:     acc.Balance,
+		})
+	}
+	for _, bucket := range buckets {
+		if !seen[bucket] {
+			balances = append(balances, TreasuryBalance{
+				Bucket:      bucket,
+				Description: domain.TreasuryBuckets[bucket],
+				Currency:    domain.CurrencyUSDT,
+				Balance:     decimal.Zero,
+			})
+		}
+	}
+
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].Bucket != balances[j].Bucket {
+			return balances[i].Bucket < balances[j].Bucket
+		}
+		return balances[i].Currency < balances[j].Currency
+	})
+	return balances, nil
+}
+
+// MigrateLegacyTreasury memindahkan total TxLog TREASURY_* lama ke saldo pembukaan akun treasury.
+// Idempoten lewat ReferenceID per bucket+currency, aman dipanggil setiap startup.
+func (uc *AdminUsecase) MigrateLegacyTreasury(ctx context.Context) {
+	types := make([]string, 0, len(legacyTreasuryTxTypes))
+	for txType := range legacyTreasuryTxTypes {
+		types = append(types, txType)
+	}
+
+	var rows []struct {
+		Type     string
+		Currency string
+		Total    decimal.Decimal
+	}
+	if err := uc.db.WithContext(ctx).Model(&domain.TxLog{}).
+		Select("type, currency, COALESCE(SUM(amount), 0) AS total").
+		Where("type IN ? AND entry_id IS NULL AND status = ?", types, domain.TxSuccess).
+		Group("type, currency").
+		Scan(&rows).Error; err != nil {
+		log.Printf("[SEED] Failed to read legacy treasury logs: %v", err)
+		return
+	}
+
+	// Gabungkan per bucket + currency (COW_TOKEN lama dinormalisasi ke COW)
+	totals := map[[2]string]decimal.Decimal{}
+	for _, row := range rows {
+		currency := row.Currency
+		if normalized, ok := legacyTxCurrency[currency]; ok {
+			currency = normalized
+		}
+		key := [2]string{legacyTreasuryTxTypes[row.Type], currency}
+		totals[key] = totals[key].Add(row.Total)
+	}
+
+	for key, total := range totals {
+		bucket, currency := key[0], key[1]
+		if !total.IsPositive() {
+			continue
+		}
+		ref := "treasury_opening:" + bucket + ":" + currency
+		err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			_, err := ledger.Post(tx, ledger.Entry{
+				Type:        "OPENING_BALANCE",
+				ReferenceID: &ref,
+				Legs: []ledger.Leg{
+					{Account: ledger.System(domain.BucketOpening, currency), Amount: total.Neg()},
+					{Account: ledger.Treasury(bucket, currency), Amount: total},
+				},
+			})
+			return err
+		})
+		if errors.Is(err, ledger.ErrDuplicateEntry) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[SEED] Failed to migrate legacy treasury %s/%s: %v", bucket, currency, err)
+			continue
+		}
+		log.Printf("[SEED] Legacy treasury %s/%s migrated: %s", bucket, currency, total.String())
+	}
+}
@@ -45,8 +45,8 @@ func (uc *UserUsecase) BindReferrer(ctx context.Context, userID uuid.UUID, refer
 			return errors.New("Cyclical referrals are not allowed")
 		}
 
-		user.ReferrerID = &referrer.ID
-		return tx.Save(&user).Error
+		// Update kolom referrer saja agar projection saldo ledger tidak tertimpa
+		return tx.Model(&user).Update("referrer_id", referrer.ID).Error
 	})
 }
 
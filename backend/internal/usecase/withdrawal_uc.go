@@ -0,0 +1,984 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+	"cashcowvalley/backend/pkg/chain"
+	"cashcowvalley/backend/pkg/realtime"
+	customRedis "cashcowvalley/backend/pkg/redis"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// withdrawalBatchSize membatasi jumlah withdrawal yang diproses per state per tick.
+const withdrawalBatchSize = 50
+
+// cancelTxGas adalah gas untuk tx pembatalan (transfer native 0 ke diri sendiri).
+const cancelTxGas = 21000
+
+// errNonceInUse menandakan nonce withdrawal tidak bisa dikembalikan ke SignerNonce dan harus dibatalkan on-chain.
+var errNonceInUse = errors.New("nonce withdraw sudah dipakai")
+
+// WithdrawalToken adalah kontrak ERC-20 yang dipakai untuk payout satu currency.
+type WithdrawalToken struct {
+	Contract common.Address
+	Decimals int32
+}
+
+// WithdrawalConfig adalah konfigurasi worker withdrawal.
+type WithdrawalConfig struct {
+	Key           *ecdsa.PrivateKey // Hot wallet penandatangan payout
+	Confirmations uint64
+	PollInterval  time.Duration
+	GasLimit      uint64 // Dipakai jika eth_estimateGas tidak tersedia di node
+	Tokens        map[string]WithdrawalToken
+	MaxAttempts   int           // Batas percobaan gagal sebelum withdrawal pindah ke REVIEW
+	BumpAfter     time.Duration // Tx BROADCAST yang belum ter-mine selama ini diganti dengan gas price lebih tinggi
+	BumpPercent   int64         // Kenaikan gas price per fee bump (node mensyaratkan minimal 10%)
+	MaxGasPrice   *big.Int      // Batas gas price fee bump (wei); nil = tanpa batas
+}
+
+// LoadWithdrawalConfig membaca konfigurasi dari ENV:
+// WITHDRAW_PRIVATE_KEY, WITHDRAW_CONFIRMATIONS, WITHDRAW_POLL_SECONDS, WITHDRAW_GAS_LIMIT,
+// WITHDRAW_MAX_ATTEMPTS, WITHDRAW_BUMP_SECONDS, WITHDRAW_BUMP_PERCENT, WITHDRAW_MAX_GAS_GWEI.
+// Kontrak token diambil dari konfigurasi deposit agar satu sumber kebenaran.
+func LoadWithdrawalConfig(tokens map[common.Address]DepositToken) WithdrawalConfig {
+	cfg := WithdrawalConfig{
+		Confirmations: envUint("WITHDRAW_CONFIRMATIONS", 12),
+		PollInterval:  time.Duration(envUint("WITHDRAW_POLL_SECONDS", 15)) * time.Second,
+		GasLimit:      envUint("WITHDRAW_GAS_LIMIT", 100000),
+		Tokens:        map[string]WithdrawalToken{},
+		MaxAttempts:   int(envUint("WITHDRAW_MAX_ATTEMPTS", 10)),
+		BumpAfter:     time.Duration(envUint("WITHDRAW_BUMP_SECONDS", 300)) * time.Second,
+		BumpPercent:   int64(envUint("WITHDRAW_BUMP_PERCENT", 20)),
+	}
+	if cfg.BumpPercent < 10 {
+		cfg.BumpPercent = 10
+	}
+	if gwei := envUint("WITHDRAW_MAX_GAS_GWEI", 0); gwei > 0 {
+		cfg.MaxGasPrice = new(big.Int).Mul(new(big.Int).SetUint64(gwei), big.NewInt(1_000_000_000))
+	}
+
+	for addr, token := range tokens {
+		cfg.Tokens[token.Currency] = WithdrawalToken{Contract: addr, Decimals: token.Decimals}
+	}
+
+	if hexKey := strings.TrimPrefix(os.Getenv("WITHDRAW_PRIVATE_KEY"), "0x"); hexKey != "" {
+		key, err := crypto.HexToECDSA(hexKey)
+		if err != nil {
+			log.Fatalf("[WITHDRAW] WITHDRAW_PRIVATE_KEY tidak valid: %v", err)
+		}
+		cfg.Key = key
+	}
+	return cfg
+}
+
+// WithdrawalUsecase mengelola payout on-chain dengan state machine
+// REQUESTED -> HELD -> SIGNED -> BROADCAST -> CONFIRMED / FAILED.
+// Saldo ditahan di escrow WITHDRAWAL sejak HELD dan baru keluar ke EXTERNAL saat CONFIRMED;
+// FAILED mengembalikan escrow ke user dan menandai TxLog hold sebagai ROLLED_BACK.
+// Worker hanya me-refund otomatis jika tx terbukti revert atau tx pembatalan operator ter-mine; semua
+// kasus ragu (receipt hilang, batas percobaan) berakhir di REVIEW agar operator memutuskan.
+type WithdrawalUsecase struct {
+	db     *gorm.DB
+	client chain.Writer
+	cfg    WithdrawalConfig
+}
+
+// NewWithdrawalUsecase menerima chain.Writer sehingga bisa memakai ethclient asli atau simulated backend.
+// client boleh nil (mode DEV): permintaan withdraw akan ditolak.
+func NewWithdrawalUsecase(db *gorm.DB, client chain.Writer, cfg WithdrawalConfig) *WithdrawalUsecase {
+	return &WithdrawalUsecase{db: db, client: client, cfg: cfg}
+}
+
+func (uc *WithdrawalUsecase) enabled() bool {
+	return uc.client != nil && uc.cfg.Key != nil && len(uc.cfg.Tokens) > 0
+}
+
+func (uc *WithdrawalUsecase) signerAddress() common.Address {
+	return crypto.PubkeyToAddress(uc.cfg.Key.PublicKey)
+}
+
+// RequestWithdrawal mencatat permintaan dan langsung menahan saldo user di escrow (HELD).
+// Payout on-chain dikerjakan oleh worker; user memantau progresnya lewat GetWithdrawal.
+func (uc *WithdrawalUsecase) RequestWithdrawal(ctx context.Context, userID uuid.UUID, asset string, amount decimal.Decimal) (*domain.Withdrawal, error) {
+	if asset != domain.CurrencyCOW && asset != domain.CurrencyUSDT {
+		return nil, errors.New("Aset tidak didukung, hanya COW atau USDT")
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, errors.New("Jumlah tidak valid")
+	}
+	if !amount.Equal(amount.Truncate(8)) {
+		return nil, errors.New("Jumlah maksimal 8 angka desimal")
+	}
+	if _, ok := uc.cfg.Tokens[asset]; !ok || !uc.enabled() {
+		return nil, errors.New("Withdraw on-chain belum dikonfigurasi")
+	}
+
+	var withdrawal domain.Withdrawal
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		if !common.IsHexAddress(user.WalletAddress) {
+			return errors.New("Wallet user tidak valid untuk withdraw")
+		}
+
+		if asset == domain.CurrencyCOW {
+			if user.Points.LessThan(amount) {
+				return errors.New("Saldo COW tidak mencukupi")
+			}
+		} else if asset == domain.CurrencyUSDT {
+			if user.USDTBalance.LessThan(amount) {
+				return errors.New("Saldo USDT tidak mencukupi")
+			}
+		}
+
+		withdrawal = domain.Withdrawal{
+			UserID:    userID,
+			Currency:  asset,
+			Amount:    amount,
+			ToAddress: strings.ToLower(user.WalletAddress),
+			State:     domain.WithdrawalRequested,
+			Status:    domain.TxPending,
+		}
+		if err := tx.Create(&withdrawal).Error; err != nil {
+			return err
+		}
+
+		// Hold: TxLog user berstatus PENDING sampai payout CONFIRMED atau di-rollback
+		entryRef := "withdraw_hold:" + withdrawal.ID.String()
+		legRef := "withdraw:" + withdrawal.ID.String()
+		entry := ledger.Transfer("WITHDRAW",
+			ledger.User(userID, asset),
+			ledger.Escrow(domain.BucketWithdrawal, asset),
+			amount)
+		entry.ReferenceID = &entryRef
+		entry.Legs[0].ReferenceID = &legRef
+		entry.Legs[0].Status = domain.TxPending
+		if _, err := ledger.Post(tx, entry); err != nil {
+			return err
+		}
+
+		withdrawal.State = domain.WithdrawalHeld
+		return tx.Model(&withdrawal).Update("state", withdrawal.State).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &withdrawal, nil
+}
+
+// GetWithdrawals mengembalikan riwayat withdrawal user (terbaru dulu).
+func (uc *WithdrawalUsecase) GetWithdrawals(ctx context.Context, userID uuid.UUID) ([]domain.Withdrawal, error) {
+	var withdrawals []domain.Withdrawal
+	if err := uc.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("created_at DESC").Limit(50).Find(&withdrawals).Error; err != nil {
+		return nil, errors.New("Gagal mengambil riwayat withdraw")
+	}
+	return withdrawals, nil
+}
+
+// GetWithdrawal mengembalikan status satu withdrawal milik user.
+func (uc *WithdrawalUsecase) GetWithdrawal(ctx context.Context, userID, withdrawalID uuid.UUID) (*domain.Withdrawal, error) {
+	var withdrawal domain.Withdrawal
+	if err := uc.db.WithContext(ctx).Where("id = ? AND user_id = ?", withdrawalID, userID).
+		First(&withdrawal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("Withdraw tidak ditemukan")
+		}
+		return nil, err
+	}
+	return &withdrawal, nil
+}
+
+// Start menjalankan Process secara periodik sampai ctx dibatalkan.
+func (uc *WithdrawalUsecase) Start(ctx context.Context) {
+	if !uc.enabled() {
+		log.Println("[WITHDRAW] Worker tidak aktif (RPC, WITHDRAW_PRIVATE_KEY, atau kontrak token belum dikonfigurasi)")
+		return
+	}
+	log.Printf("[WITHDRAW] Worker aktif, hot wallet %s", uc.signerAddress().Hex())
+
+	ticker := time.NewTicker(uc.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Redlock wajib: dua replica yang menandatangani bersamaan akan berebut nonce
+		lockKey := "withdrawal_worker"
+		if token, acquired := customRedis.AcquireLock(ctx, lockKey, uc.cfg.PollInterval+60*time.Second); acquired {
+			if err := uc.Process(ctx); err != nil {
+				log.Printf("[WITHDRAW] Proses gagal: %v", err)
+			}
+			customRedis.ReleaseLock(ctx, lockKey, token)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process memajukan semua withdrawal satu langkah: HELD -> SIGNED -> BROADCAST -> CONFIRMED / FAILED,
+// dan CANCELLING -> FAILED (atau CONFIRMED jika payout yang ter-mine).
+func (uc *WithdrawalUsecase) Process(ctx context.Context) error {
+	if !uc.enabled() {
+		return errors.New("Withdraw on-chain belum dikonfigurasi")
+	}
+	if err := uc.signHeld(ctx); err != nil {
+		return err
+	}
+	if err := uc.broadcastSigned(ctx); err != nil {
+		return err
+	}
+	if err := uc.confirmBroadcast(ctx); err != nil {
+		return err
+	}
+	return uc.confirmCancelling(ctx)
+}
+
+func (uc *WithdrawalUsecase) findByState(ctx context.Context, state domain.WithdrawalState, order string) ([]domain.Withdrawal, error) {
+	var withdrawals []domain.Withdrawal
+	err := uc.db.WithContext(ctx).Where("state = ?", state).
+		Order(order).Limit(withdrawalBatchSize).Find(&withdrawals).Error
+	return withdrawals, err
+}
+
+// signHeld menandatangani transfer ERC-20 untuk setiap withdrawal HELD.
+func (uc *WithdrawalUsecase) signHeld(ctx context.Context) error {
+	held, err := uc.findByState(ctx, domain.WithdrawalHeld, "created_at")
+	if err != nil || len(held) == 0 {
+		return err
+	}
+
+	chainID, err := uc.client.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	gasPrice, err := uc.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range held {
+		if err := uc.sign(ctx, &held[i], chainID, gasPrice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uc *WithdrawalUsecase) sign(ctx context.Context, w *domain.Withdrawal, chainID, gasPrice *big.Int) error {
+	token, ok := uc.cfg.Tokens[w.Currency]
+	if !ok {
+		return uc.fail(ctx, w.ID, "Kontrak token tidak dikonfigurasi")
+	}
+	if !common.IsHexAddress(w.ToAddress) {
+		return uc.fail(ctx, w.ID, "Alamat tujuan tidak valid")
+	}
+	value := chain.ToWei(w.Amount, token.Decimals)
+	if value.Sign() <= 0 {
+		return uc.fail(ctx, w.ID, "Jumlah terlalu kecil untuk desimal token")
+	}
+
+	from := uc.signerAddress()
+	data := chain.PackTransfer(common.HexToAddress(w.ToAddress), value)
+	gas, err := uc.client.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &token.Contract, Data: data})
+	if err != nil {
+		// Biasanya saldo hot wallet kurang: tetap HELD dan coba lagi di tick berikutnya
+		log.Printf("[WITHDRAW] Estimasi gas %s gagal: %v", w.ID, err)
+		return uc.recordAttempt(ctx, w, err.Error())
+	}
+	if gas == 0 {
+		gas = uc.cfg.GasLimit
+	}
+
+	return uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked domain.Withdrawal
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND state = ?", w.ID, domain.WithdrawalHeld).First(&locked).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		nonce, err := uc.allocateNonce(ctx, tx, from)
+		if err != nil {
+			return err
+		}
+
+		signed, err := types.SignTx(types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			To:       &token.Contract,
+			Value:    big.NewInt(0),
+			Gas:      gas,
+			GasPrice: gasPrice,
+			Data:     data,
+		}), types.LatestSignerForChainID(chainID), uc.cfg.Key)
+		if err != nil {
+			return err
+		}
+		raw, err := signed.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		fromAddr := strings.ToLower(from.Hex())
+		txHash := signed.Hash().Hex()
+		return tx.Model(&locked).Updates(map[string]interface{}{
+			"state":        domain.WithdrawalSigned,
+			"from_address": fromAddr,
+			"nonce":        nonce,
+			"tx_hash":      txHash,
+			"raw_tx":       hexutil.Encode(raw),
+			"last_error":   "",
+		}).Error
+	})
+}
+
+// allocateNonce mengambil nonce berikutnya untuk hot wallet di dalam transaksi penandatanganan.
+// Jika nonce pending di node lebih tinggi (mis. hot wallet dipakai manual), nonce lokal ikut maju.
+func (uc *WithdrawalUsecase) allocateNonce(ctx context.Context, tx *gorm.DB, from common.Address) (uint64, error) {
+	chainNonce, err := uc.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+
+	address := strings.ToLower(from.Hex())
+	var row domain.SignerNonce
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("address = ?", address).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		row = domain.SignerNonce{Address: address, NextNonce: chainNonce + 1}
+		return chainNonce, tx.Create(&row).Error
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	nonce := row.NextNonce
+	if chainNonce > nonce {
+		nonce = chainNonce
+	}
+	return nonce, tx.Model(&row).Update("next_nonce", nonce+1).Error
+}
+
+// broadcastSigned mengirim tx yang sudah ditandatangani, berurutan menurut nonce.
+func (uc *WithdrawalUsecase) broadcastSigned(ctx context.Context) error {
+	signed, err := uc.findByState(ctx, domain.WithdrawalSigned, "nonce")
+	if err != nil {
+		return err
+	}
+
+	for _, w := range signed {
+		if err := uc.send(ctx, w.RawTx); err != nil {
+			log.Printf("[WITHDRAW] Broadcast %s gagal: %v", w.ID, err)
+			// Nonce setelahnya tidak akan ter-mine sebelum yang ini, jadi berhenti dan ulangi tick berikutnya
+			return uc.recordAttempt(ctx, &w, err.Error())
+		}
+
+		if err := uc.db.WithContext(ctx).Model(&domain.Withdrawal{}).
+			Where("id = ? AND state = ?", w.ID, domain.WithdrawalSigned).
+			Updates(map[string]interface{}{"state": domain.WithdrawalBroadcast, "broadcast_at": time.Now(), "last_error": ""}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordAttempt mencatat satu percobaan gagal. Setelah MaxAttempts, withdrawal pindah ke REVIEW
+// dan berhenti diproses otomatis sampai operator me-retry atau me-refund-nya.
+func (uc *WithdrawalUsecase) recordAttempt(ctx context.Context, w *domain.Withdrawal, reason string) error {
+	return uc.db.WithContext(ctx).Model(&domain.Withdrawal{}).
+		Where("id = ? AND state = ?", w.ID, w.State).
+		Updates(uc.attemptUpdates(w, reason, nil)).Error
+}
+
+// attemptUpdates menyusun kolom percobaan berikutnya, termasuk pindah ke REVIEW jika batas tercapai.
+func (uc *WithdrawalUsecase) attemptUpdates(w *domain.Withdrawal, reason string, updates map[string]interface{}) map[string]interface{} {
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["attempts"] = gorm.Expr("attempts + 1")
+	updates["last_error"] = reason
+	if uc.cfg.MaxAttempts > 0 && w.Attempts+1 >= uc.cfg.MaxAttempts {
+		updates["state"] = domain.WithdrawalReview
+		log.Printf("[WITHDRAW] %s dipindah ke REVIEW setelah %d percobaan: %s", w.ID, w.Attempts+1, reason)
+	}
+	return updates
+}
+
+func decodeRawTx(rawTx string) (*types.Transaction, error) {
+	raw, err := hexutil.Decode(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	var signed types.Transaction
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	return &signed, nil
+}
+
+// send mengirim raw tx. Tx yang sudah ada di mempool/terlanjur dikirim dianggap sukses.
+func (uc *WithdrawalUsecase) send(ctx context.Context, rawTx string) error {
+	signed, err := decodeRawTx(rawTx)
+	if err != nil {
+		return err
+	}
+
+	err = uc.client.SendTransaction(ctx, signed)
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "already known") {
+		return nil
+	}
+	return err
+}
+
+// confirmBroadcast menunggu receipt dengan N konfirmasi lalu menyelesaikan atau me-refund withdrawal.
+func (uc *WithdrawalUsecase) confirmBroadcast(ctx context.Context) error {
+	broadcast, err := uc.findByState(ctx, domain.WithdrawalBroadcast, "nonce")
+	if err != nil || len(broadcast) == 0 {
+		return err
+	}
+
+	head, err := uc.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	minedNonce, err := uc.client.NonceAt(ctx, uc.signerAddress(), nil)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range broadcast {
+		receipt, err := uc.findReceipt(ctx, &w)
+		if errors.Is(err, ethereum.NotFound) {
+			if *w.Nonce < minedNonce {
+				// Nonce sudah ter-mine tetapi receipt belum terlihat (RPC tertinggal, pruned atau load-balanced).
+				// Nonce ini hampir pasti dipakai withdrawal ini sendiri, jadi tidak pernah di-refund otomatis:
+				// cek ulang di tick berikutnya dan serahkan ke operator setelah MaxAttempts.
+				if err := uc.recordAttempt(ctx, &w, "Nonce sudah ter-mine tetapi receipt belum ditemukan"); err != nil {
+					return err
+				}
+				continue
+			}
+			broadcastAt := w.UpdatedAt // Baris lama sebelum kolom broadcast_at ada
+			if w.BroadcastAt != nil {
+				broadcastAt = *w.BroadcastAt
+			}
+			if time.Since(broadcastAt) >= uc.cfg.BumpAfter {
+				// Tertahan di mempool terlalu lama: ganti dengan gas price lebih tinggi (nonce sama)
+				if err := uc.bump(ctx, &w); err != nil {
+					return err
+				}
+				continue
+			}
+			// Kemungkinan ter-drop dari mempool: kirim ulang tx yang sama
+			if err := uc.send(ctx, w.RawTx); err != nil {
+				log.Printf("[WITHDRAW] Re-broadcast %s gagal: %v", w.ID, err)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		mined := receipt.BlockNumber.Uint64()
+		if head < mined+uc.cfg.Confirmations {
+			continue
+		}
+
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			err = uc.fail(ctx, w.ID, "Transaksi on-chain gagal (reverted)")
+		} else {
+			err = uc.settle(ctx, w.ID, receipt.TxHash.Hex(), mined, domain.WithdrawalBroadcast)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// confirmCancelling menyelesaikan withdrawal CANCELLING setelah N konfirmasi. Jika tx payout yang ternyata
+// ter-mine, withdrawal diselesaikan seperti biasa; jika tx pembatalan (atau payout yang revert) yang ter-mine,
+// nonce terbukti terpakai tanpa memindahkan token sehingga saldo aman di-refund.
+func (uc *WithdrawalUsecase) confirmCancelling(ctx context.Context) error {
+	cancelling, err := uc.findByState(ctx, domain.WithdrawalCancelling, "nonce")
+	if err != nil || len(cancelling) == 0 {
+		return err
+	}
+
+	head, err := uc.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range cancelling {
+		payout := true
+		receipt, err := uc.findReceipt(ctx, &w)
+		if errors.Is(err, ethereum.NotFound) && w.CancelTxHash != nil {
+			payout = false
+			receipt, err = uc.client.TransactionReceipt(ctx, common.HexToHash(*w.CancelTxHash))
+		}
+		if errors.Is(err, ethereum.NotFound) {
+			// Belum ada tx dengan nonce ini yang ter-mine: kirim ulang tx pembatalan
+			if err := uc.send(ctx, w.CancelRawTx); err != nil {
+				log.Printf("[WITHDRAW] Re-broadcast pembatalan %s gagal: %v", w.ID, err)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		mined := receipt.BlockNumber.Uint64()
+		if head < mined+uc.cfg.Confirmations {
+			continue
+		}
+
+		if payout && receipt.Status == types.ReceiptStatusSuccessful {
+			log.Printf("[WITHDRAW] %s: payout ter-mine sebelum tx pembatalan, refund dibatalkan", w.ID)
+			err = uc.settle(ctx, w.ID, receipt.TxHash.Hex(), mined, domain.WithdrawalCancelling)
+		} else {
+			err = uc.fail(ctx, w.ID, w.LastError, domain.WithdrawalCancelling)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// txHashes mengembalikan hash tx aktif beserta semua hash yang sudah diganti fee bump (nonce sama).
+func txHashes(w *domain.Withdrawal) []string {
+	hashes := make([]string, 0, 1)
+	if w.TxHash != nil {
+		hashes = append(hashes, *w.TxHash)
+	}
+	if w.ReplacedTxHashes != "" {
+		hashes = append(hashes, strings.Split(w.ReplacedTxHashes, ",")...)
+	}
+	return hashes
+}
+
+// findReceipt mencari receipt dari tx aktif maupun tx yang sudah diganti; hanya satu yang bisa ter-mine.
+func (uc *WithdrawalUsecase) findReceipt(ctx context.Context, w *domain.Withdrawal) (*types.Receipt, error) {
+	for _, hash := range txHashes(w) {
+		receipt, err := uc.client.TransactionReceipt(ctx, common.HexToHash(hash))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		return receipt, err
+	}
+	return nil, ethereum.NotFound
+}
+
+// bump mengganti tx BROADCAST yang tertahan dengan tx bernonce sama dan gas price minimal BumpPercent lebih
+// tinggi. Tx baru disimpan sebelum dikirim, dan hash lama tetap dipantau karena masih bisa ter-mine duluan.
+func (uc *WithdrawalUsecase) bump(ctx context.Context, w *domain.Withdrawal) error {
+	old, err := decodeRawTx(w.RawTx)
+	if err != nil {
+		return err
+	}
+	suggested, err := uc.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+
+	gasPrice := new(big.Int).Mul(old.GasPrice(), big.NewInt(100+uc.cfg.BumpPercent))
+	gasPrice.Div(gasPrice, big.NewInt(100))
+	if suggested.Cmp(gasPrice) > 0 {
+		gasPrice = suggested
+	}
+	if uc.cfg.MaxGasPrice != nil && gasPrice.Cmp(uc.cfg.MaxGasPrice) > 0 {
+		if old.GasPrice().Cmp(uc.cfg.MaxGasPrice) >= 0 {
+			if err := uc.send(ctx, w.RawTx); err != nil {
+				log.Printf("[WITHDRAW] Re-broadcast %s gagal: %v", w.ID, err)
+			}
+			return uc.recordAttempt(ctx, w, "Gas price maksimum tercapai, tx belum ter-mine")
+		}
+		gasPrice = uc.cfg.MaxGasPrice
+	}
+
+	chainID, err := uc.client.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	replacement, err := types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    old.Nonce(),
+		To:       old.To(),
+		Value:    old.Value(),
+		Gas:      old.Gas(),
+		GasPrice: gasPrice,
+		Data:     old.Data(),
+	}), types.LatestSignerForChainID(chainID), uc.cfg.Key)
+	if err != nil {
+		return err
+	}
+	raw, err := replacement.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	replaced := *w.TxHash
+	if w.ReplacedTxHashes != "" {
+		replaced = w.ReplacedTxHashes + "," + replaced
+	}
+	rawTx := hexutil.Encode(raw)
+	result := uc.db.WithContext(ctx).Model(&domain.Withdrawal{}).
+		Where("id = ? AND state = ? AND tx_hash = ?", w.ID, domain.WithdrawalBroadcast, *w.TxHash).
+		Updates(uc.attemptUpdates(w, "Fee bump ke gas price "+gasPrice.String(), map[string]interface{}{
+			"tx_hash":            replacement.Hash().Hex(),
+			"raw_tx":             rawTx,
+			"replaced_tx_hashes": replaced,
+			"broadcast_at":       time.Now(),
+		}))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	log.Printf("[WITHDRAW] %s fee bump nonce %d: %s -> %s", w.ID, old.Nonce(), *w.TxHash, replacement.Hash().Hex())
+	if err := uc.send(ctx, rawTx); err != nil {
+		log.Printf("[WITHDRAW] Broadcast fee bump %s gagal: %v", w.ID, err)
+	}
+	return nil
+}
+
+// settle memindahkan escrow ke EXTERNAL dan menandai withdrawal CONFIRMED dengan hash tx yang ter-mine.
+func (uc *WithdrawalUsecase) settle(ctx context.Context, withdrawalID uuid.UUID, txHash string, blockNumber uint64, from domain.WithdrawalState) error {
+	return uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var w domain.Withdrawal
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND state = ?", withdrawalID, from).First(&w).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		entryRef := "withdraw_settle:" + w.ID.String()
+		entry := ledger.Transfer("WITHDRAW_SETTLE",
+			ledger.Escrow(domain.BucketWithdrawal, w.Currency),
+			ledger.System(domain.BucketExternal, w.Currency),
+			w.Amount)
+		entry.ReferenceID = &entryRef
+		if _, err := ledger.Post(tx, entry); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&domain.TxLog{}).Where("reference_id = ?", "withdraw:"+w.ID.String()).
+			Update("status", domain.TxSuccess).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&w).Updates(map[string]interface{}{
+			"state":        domain.WithdrawalConfirmed,
+			"status":       domain.TxSuccess,
+			"tx_hash":      txHash,
+			"block_number": blockNumber,
+			"last_error":   "",
+		}).Error
+	})
+}
+
+// fail mengembalikan escrow ke user, menandai TxLog hold ROLLED_BACK dan withdrawal FAILED.
+// onlyFrom (opsional) membatasi state asal yang boleh di-fail, dicek di bawah lock.
+func (uc *WithdrawalUsecase) fail(ctx context.Context, withdrawalID uuid.UUID, reason string, onlyFrom ...domain.WithdrawalState) error {
+	return uc.failWith(ctx, withdrawalID, reason, nil, onlyFrom...)
+}
+
+// failWith sama dengan fail, dengan `check` (opsional) yang dijalankan di bawah lock withdrawal sebelum
+// refund; error dari check membatalkan seluruh refund.
+func (uc *WithdrawalUsecase) failWith(ctx context.Context, withdrawalID uuid.UUID, reason string, check func(tx *gorm.DB, w *domain.Withdrawal) error, onlyFrom ...domain.WithdrawalState) error {
+	log.Printf("[WITHDRAW] %s gagal: %s", withdrawalID, reason)
+
+	ctx, events := realtime.WithCollector(ctx)
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var w domain.Withdrawal
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", withdrawalID).First(&w).Error; err != nil {
+			return err
+		}
+		if w.State == domain.WithdrawalConfirmed || w.State == domain.WithdrawalFailed {
+			return nil
+		}
+		if len(onlyFrom) > 0 && !slices.Contains(onlyFrom, w.State) {
+			return errors.New("Status withdraw sudah berubah, muat ulang data")
+		}
+		if check != nil {
+			if err := check(tx, &w); err != nil {
+				return err
+			}
+		}
+
+		status := domain.TxFailed
+		if w.State != domain.WithdrawalRequested {
+			entryRef := "withdraw_refund:" + w.ID.String()
+			entry := ledger.Transfer("WITHDRAW_REFUND",
+				ledger.Escrow(domain.BucketWithdrawal, w.Currency),
+				ledger.User(w.UserID, w.Currency),
+				w.Amount)
+			entry.ReferenceID = &entryRef
+			entry.Legs[1].ReferenceID = &entryRef
+			if _, err := ledger.Post(tx, entry); err != nil {
+				return fmt.Errorf("refund withdraw: %w", err)
+			}
+
+			if err := tx.Model(&domain.TxLog{}).Where("reference_id = ?", "withdraw:"+w.ID.String()).
+				Update("status", domain.TxRolledBack).Error; err != nil {
+				return err
+			}
+			status = domain.TxRolledBack
+		}
+
+		return tx.Model(&w).Updates(map[string]interface{}{
+			"state":      domain.WithdrawalFailed,
+			"status":     status,
+			"last_error": reason,
+		}).Error
+	})
+	if err == nil {
+		flushRealtime(ctx, uc.db, events)
+	}
+	return err
+}
+
+// ListWithdrawals mengembalikan withdrawal pada state tertentu untuk operator (terlama dulu).
+// State kosong berarti REVIEW, antrean yang menunggu tindakan operator.
+func (uc *WithdrawalUsecase) ListWithdrawals(ctx context.Context, state string) ([]domain.Withdrawal, error) {
+	if state == "" {
+		state = string(domain.WithdrawalReview)
+	}
+	withdrawals := make([]domain.Withdrawal, 0)
+	if err := uc.db.WithContext(ctx).Where("state = ?", strings.ToUpper(state)).
+		Order("created_at ASC").Limit(200).Find(&withdrawals).Error; err != nil {
+		return nil, err
+	}
+	return withdrawals, nil
+}
+
+func (uc *WithdrawalUsecase) lockReview(tx *gorm.DB, withdrawalID uuid.UUID) (*domain.Withdrawal, error) {
+	var w domain.Withdrawal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", withdrawalID).First(&w).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("Withdraw tidak ditemukan")
+		}
+		return nil, err
+	}
+	if w.State != domain.WithdrawalReview {
+		return nil, errors.New("Hanya withdraw berstatus REVIEW yang bisa ditindaklanjuti operator")
+	}
+	return &w, nil
+}
+
+// RetryWithdrawal mengembalikan withdrawal REVIEW ke antrean worker dengan hitungan percobaan direset.
+// Withdrawal yang belum ditandatangani kembali ke HELD; yang sudah punya tx kembali ke BROADCAST
+// sehingga receipt semua hash dicek ulang, lalu dikirim ulang atau di-bump jika belum ter-mine.
+func (uc *WithdrawalUsecase) RetryWithdrawal(ctx context.Context, withdrawalID uuid.UUID) (*domain.Withdrawal, error) {
+	var w *domain.Withdrawal
+	err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if w, err = uc.lockReview(tx, withdrawalID); err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"state": domain.WithdrawalHeld, "attempts": 0}
+		if w.TxHash != nil {
+			updates["state"] = domain.WithdrawalBroadcast
+			updates["broadcast_at"] = time.Now()
+		}
+		if err := tx.Model(w).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", w.ID).First(w).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[WITHDRAW] %s di-retry operator, kembali ke %s", w.ID, w.State)
+	return w, nil
+}
+
+// RefundWithdrawal me-refund withdrawal REVIEW hanya jika nonce-nya terbukti tidak akan memindahkan token:
+//   - belum ditandatangani (tanpa nonce), atau tx payout-nya ter-mine dengan status revert;
+//   - belum pernah di-broadcast dan nonce-nya yang terakhir dialokasikan: nonce dikembalikan ke SignerNonce;
+//   - selain itu tx pembatalan (self-transfer 0 dengan nonce sama dan gas price lebih tinggi) dikirim dan
+//     withdrawal menjadi CANCELLING. Worker me-refund setelah tx pembatalan ter-mine, atau menyelesaikan
+//     withdrawal jika tx payout yang lebih dulu ter-mine.
+//
+// Ditolak jika salah satu hash sudah punya receipt sukses.
+func (uc *WithdrawalUsecase) RefundWithdrawal(ctx context.Context, withdrawalID uuid.UUID, reason string) (*domain.Withdrawal, error) {
+	var w *domain.Withdrawal
+	if err := uc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		w, err = uc.lockReview(tx, withdrawalID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	reason = "Refund operator: " + reason
+
+	if w.Nonce == nil {
+		return uc.refundReview(ctx, w.ID, reason, nil)
+	}
+	if !uc.enabled() {
+		return nil, errors.New("Withdraw on-chain belum dikonfigurasi")
+	}
+
+	receipt, err := uc.findReceipt(ctx, w)
+	switch {
+	case err == nil && receipt.Status == types.ReceiptStatusSuccessful:
+		return nil, errors.New("Transaksi withdraw sudah ter-mine, gunakan retry agar diselesaikan")
+	case err == nil:
+		// Payout revert: nonce sudah terpakai oleh tx ini sendiri
+		head, err := uc.client.BlockNumber(ctx)
+		if err != nil {
+			return nil, errors.New("Gagal memeriksa receipt on-chain, coba lagi")
+		}
+		if head < receipt.BlockNumber.Uint64()+uc.cfg.Confirmations {
+			return nil, errors.New("Transaksi withdraw yang gagal belum cukup konfirmasi, coba lagi nanti")
+		}
+		return uc.refundReview(ctx, w.ID, reason, nil)
+	case !errors.Is(err, ethereum.NotFound):
+		return nil, errors.New("Gagal memeriksa receipt on-chain, coba lagi")
+	}
+
+	if w.BroadcastAt == nil {
+		refunded, err := uc.refundReview(ctx, w.ID, reason, uc.releaseNonce(ctx))
+		if !errors.Is(err, errNonceInUse) {
+			return refunded, err
+		}
+	}
+	return uc.cancel(ctx, w, reason)
+}
+
+// refundReview me-refund withdrawal REVIEW lalu mengembalikan datanya yang terbaru.
+func (uc *WithdrawalUsecase) refundReview(ctx context.Context, withdrawalID uuid.UUID, reason string, check func(tx *gorm.DB, w *domain.Withdrawal) error) (*domain.Withdrawal, error) {
+	if err := uc.failWith(ctx, withdrawalID, reason, check, domain.WithdrawalReview); err != nil {
+		return nil, err
+	}
+	var w domain.Withdrawal
+	if err := uc.db.WithContext(ctx).Where("id = ?", withdrawalID).First(&w).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// releaseNonce mengembalikan nonce withdrawal yang belum pernah di-broadcast ke SignerNonce (di bawah lock
+// baris nonce), hanya jika nonce itu yang terakhir dialokasikan dan node belum melihat tx apa pun dengan
+// nonce tersebut. Selain itu errNonceInUse: nonce harus dipakai tx pembatalan agar payout berikutnya
+// tidak tertahan di belakang celah nonce.
+func (uc *WithdrawalUsecase) releaseNonce(ctx context.Context) func(tx *gorm.DB, w *domain.Withdrawal) error {
+	return func(tx *gorm.DB, w *domain.Withdrawal) error {
+		if w.BroadcastAt != nil || w.FromAddress == nil || w.Nonce == nil {
+			return errNonceInUse
+		}
+		var row domain.SignerNonce
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("address = ?", *w.FromAddress).First(&row).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errNonceInUse
+			}
+			return err
+		}
+		if row.NextNonce != *w.Nonce+1 {
+			return errNonceInUse
+		}
+		pending, err := uc.client.PendingNonceAt(ctx, common.HexToAddress(*w.FromAddress))
+		if err != nil {
+			return err
+		}
+		if pending > *w.Nonce {
+			return errNonceInUse
+		}
+		log.Printf("[WITHDRAW] %s: nonce %d dikembalikan ke hot wallet", w.ID, *w.Nonce)
+		return tx.Model(&row).Update("next_nonce", *w.Nonce).Error
+	}
+}
+
+// cancel mengirim self-transfer 0 dengan nonce withdrawal dan gas price minimal BumpPercent di atas tx
+// terakhir, sehingga nonce pasti terpakai tanpa memindahkan token. Tidak dibatasi MaxGasPrice karena
+// tx pembatalan harus menggantikan payout di mempool. Refund dilakukan worker setelah tx ini ter-mine.
+func (uc *WithdrawalUsecase) cancel(ctx context.Context, w *domain.Withdrawal, reason string) (*domain.Withdrawal, error) {
+	old, err := decodeRawTx(w.RawTx)
+	if err != nil {
+		return nil, err
+	}
+	suggested, err := uc.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chainID, err := uc.client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	gasPrice := new(big.Int).Mul(old.GasPrice(), big.NewInt(100+uc.cfg.BumpPercent))
+	gasPrice.Div(gasPrice, big.NewInt(100))
+	if suggested.Cmp(gasPrice) > 0 {
+		gasPrice = suggested
+	}
+	from := uc.signerAddress()
+	signed, err := types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    old.Nonce(),
+		To:       &from,
+		Value:    big.NewInt(0),
+		Gas:      cancelTxGas,
+		GasPrice: gasPrice,
+	}), types.LatestSignerForChainID(chainID), uc.cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	rawTx := hexutil.Encode(raw)
+	result := uc.db.WithContext(ctx).Model(&domain.Withdrawal{}).
+		Where("id = ? AND state = ?", w.ID, domain.WithdrawalReview).
+		Updates(map[string]interface{}{
+			"state":          domain.WithdrawalCancelling,
+			"cancel_tx_hash": signed.Hash().Hex(),
+			"cancel_raw_tx":  rawTx,
+			"broadcast_at":   time.Now(),
+			"last_error":     reason,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("Status withdraw sudah berubah, muat ulang data")
+	}
+
+	log.Printf("[WITHDRAW] %s dibatalkan operator, tx pembatalan nonce %d: %s", w.ID, old.Nonce(), signed.Hash().Hex())
+	if err := uc.send(ctx, rawTx); err != nil {
+		log.Printf("[WITHDRAW] Broadcast pembatalan %s gagal: %v", w.ID, err)
+	}
+
+	var updated domain.Withdrawal
+	if err := uc.db.WithContext(ctx).Where("id = ?", w.ID).First(&updated).Error; err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
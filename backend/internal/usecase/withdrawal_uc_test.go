@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"cashcowvalley/backend/internal/domain"
+	"cashcowvalley/backend/internal/ledger"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func newWithdrawalTest(t *testing.T) (*WithdrawalUsecase, *simChain, *gorm.DB, uuid.UUID) {
+	t.Helper()
+	db := newTestDB(t, &domain.Withdrawal{}, &domain.SignerNonce{})
+
+	key, _ := crypto.GenerateKey()
+	hotWallet := crypto.PubkeyToAddress(key.PublicKey)
+	backend := simulated.NewBackend(types.GenesisAlloc{hotWallet: {Balance: big.NewInt(1e18)}})
+	t.Cleanup(func() { backend.Close() })
+	sim := &simChain{backend: backend, client: backend.Client(), key: key, from: hotWallet}
+	token := sim.send(t, nil, transferEmitterCode()).ContractAddress
+
+	uc := NewWithdrawalUsecase(db, sim.client, WithdrawalConfig{
+		Key:           key,
+		Confirmations: 1,
+		GasLimit:      100000,
+		Tokens:        map[string]WithdrawalToken{domain.CurrencyUSDT: {Contract: token, Decimals: 6}},
+		MaxAttempts:   10,
+		BumpAfter:     time.Hour,
+		BumpPercent:   20,
+	})
+
+	user := domain.User{WalletAddress: "0x00000000000000000000000000000000000000aa", Nonce: "x"}
+	db.Create(&user)
+	db.Create(&domain.Inventory{UserID: user.ID})
+	if _, err := ledger.Post(db, ledger.Transfer("TEST_FUND",
+		ledger.System(domain.BucketOpening, domain.CurrencyUSDT),
+		ledger.User(user.ID, domain.CurrencyUSDT),
+		decimal.NewFromInt(10))); err != nil {
+		t.Fatalf("fund user: %v", err)
+	}
+	return uc, sim, db, user.ID
+}
+
+func requestReview(t *testing.T, uc *WithdrawalUsecase, db *gorm.DB, userID uuid.UUID, steps func(ctx context.Context) error) *domain.Withdrawal {
+	t.Helper()
+	ctx := context.Background()
+	w, err := uc.RequestWithdrawal(ctx, userID, domain.CurrencyUSDT, decimal.NewFromInt(4))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if err := steps(ctx); err != nil {
+		t.Fatalf("proses worker: %v", err)
+	}
+	// Operator review: mis. batas percobaan tercapai
+	db.Model(&domain.Withdrawal{}).Where("id = ?", w.ID).Update("state", domain.WithdrawalReview)
+	db.First(w, "id = ?", w.ID)
+	return w
+}
+
+func usdtBalance(t *testing.T, db *gorm.DB, userID uuid.UUID) decimal.Decimal {
+	t.Helper()
+	balance, err := ledger.Balance(db, ledger.User(userID, domain.CurrencyUSDT))
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	return balance
+}
+
+func TestRefundUnbroadcastWithdrawalReleasesNonce(t *testing.T) {
+	uc, _, db, userID := newWithdrawalTest(t)
+	w := requestReview(t, uc, db, userID, uc.signHeld)
+	if w.Nonce == nil || w.BroadcastAt != nil {
+		t.Fatalf("withdrawal harus SIGNED tanpa broadcast: %+v", w)
+	}
+
+	refunded, err := uc.RefundWithdrawal(context.Background(), w.ID, "salah alamat")
+	if err != nil || refunded.State != domain.WithdrawalFailed {
+		t.Fatalf("refund: state=%v err=%v", refunded, err)
+	}
+	var row domain.SignerNonce
+	db.First(&row, "address = ?", *w.FromAddress)
+	if row.NextNonce != *w.Nonce {
+		t.Fatalf("NextNonce = %d, harus kembali ke %d", row.NextNonce, *w.Nonce)
+	}
+	if got := usdtBalance(t, db, userID); !got.Equal(decimal.NewFromInt(10)) {
+		t.Fatalf("saldo = %s, harus kembali 10", got)
+	}
+}
+
+func TestRefundBroadcastWithdrawalWaitsForCancelTx(t *testing.T) {
+	uc, sim, db, userID := newWithdrawalTest(t)
+	w := requestReview(t, uc, db, userID, uc.Process) // Tx payout ada di mempool, belum ter-mine
+	if w.BroadcastAt == nil {
+		t.Fatalf("withdrawal harus sudah di-broadcast: %+v", w)
+	}
+	payoutHash := *w.TxHash
+
+	cancelling, err := uc.RefundWithdrawal(context.Background(), w.ID, "user minta batal")
+	if err != nil || cancelling.State != domain.WithdrawalCancelling || cancelling.CancelTxHash == nil {
+		t.Fatalf("refund harus menunggu tx pembatalan: %+v err=%v", cancelling, err)
+	}
+	if got := usdtBalance(t, db, userID); !got.Equal(decimal.NewFromInt(6)) {
+		t.Fatalf("saldo = %s, tidak boleh di-refund sebelum tx pembatalan ter-mine", got)
+	}
+
+	sim.mine(2)
+	if err := uc.Process(context.Background()); err != nil {
+		t.Fatalf("proses pembatalan: %v", err)
+	}
+	db.First(w, "id = ?", w.ID)
+	if w.State != domain.WithdrawalFailed || w.Status != domain.TxRolledBack {
+		t.Fatalf("state = %s/%s, harus FAILED/ROLLED_BACK", w.State, w.Status)
+	}
+	if got := usdtBalance(t, db, userID); !got.Equal(decimal.NewFromInt(10)) {
+		t.Fatalf("saldo = %s, harus kembali 10", got)
+	}
+	if _, err := sim.client.TransactionReceipt(context.Background(), common.HexToHash(payoutHash)); err == nil {
+		t.Fatal("tx payout tidak boleh ter-mine setelah dibatalkan")
+	}
+}
+
+func TestRefundRejectsMinedWithdrawal(t *testing.T) {
+	uc, sim, db, userID := newWithdrawalTest(t)
+	w := requestReview(t, uc, db, userID, func(ctx context.Context) error {
+		if err := uc.Process(ctx); err != nil {
+			return err
+		}
+		sim.mine(1)
+		return nil
+	})
+
+	if _, err := uc.RefundWithdrawal(context.Background(), w.ID, "dobel"); err == nil || !strings.Contains(err.Error(), "ter-mine") {
+		t.Fatalf("refund payout yang sudah ter-mine harus ditolak, err=%v", err)
+	}
+	if got := usdtBalance(t, db, userID); !got.Equal(decimal.NewFromInt(6)) {
+		t.Fatalf("saldo = %s, tidak boleh di-refund", got)
+	}
+}
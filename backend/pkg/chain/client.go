@@ -0,0 +1,56 @@
+package chain
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Reader adalah subset RPC yang dipakai indexer deposit.
+// *ethclient.Client dan simulated.Backend.Client() sama-sama memenuhinya,
+// sehingga watcher bisa dijalankan offline di atas simulated backend.
+type Reader interface {
+	ethereum.BlockNumberReader
+	ethereum.LogFilterer
+	ethereum.TransactionReader
+}
+
+// Writer adalah subset RPC yang dipakai worker withdrawal untuk menandatangani dan mengirim transaksi.
+type Writer interface {
+	Reader
+	ethereum.ChainIDReader
+	ethereum.GasPricer
+	ethereum.GasEstimator
+	ethereum.ChainStateReader
+	ethereum.PendingStateReader
+	ethereum.TransactionSender
+}
+
+// Dial menghubungkan ke node EVM dari ETH_RPC_URL.
+// Mengembalikan nil (mode DEV) jika ETH_RPC_URL kosong, sama seperti Redis.
+func Dial() *ethclient.Client {
+	rpcURL := os.Getenv("ETH_RPC_URL")
+	if rpcURL == "" {
+		log.Println("[CHAIN] WARNING: ETH_RPC_URL kosong. Mode DEV: Deposit/Withdraw on-chain dinonaktifkan.")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("[CHAIN] Gagal terhubung ke RPC: %v", err)
+	}
+
+	if _, err := client.BlockNumber(ctx); err != nil {
+		log.Fatalf("[CHAIN] RPC tidak merespon: %v", err)
+	}
+
+	log.Println("[CHAIN] Koneksi RPC berhasil!")
+	return client
+}
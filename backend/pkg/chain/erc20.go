@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+)
+
+// TransferEventSig adalah topic[0] dari event ERC-20 Transfer(address,address,uint256).
+var TransferEventSig = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// transferMethodID adalah selector fungsi ERC-20 transfer(address,uint256).
+var transferMethodID = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+
+// Transfer adalah hasil decode satu log ERC-20 Transfer.
+type Transfer struct {
+	Token       common.Address
+	From        common.Address
+	To          common.Address
+	Value       *big.Int
+	TxHash      common.Hash
+	LogIndex    uint
+	BlockNumber uint64
+}
+
+// ParseTransferLog men-decode log Transfer standar (from & to ter-index, value di data).
+func ParseTransferLog(l types.Log) (*Transfer, error) {
+	if len(l.Topics) != 3 || l.Topics[0] != TransferEventSig {
+		return nil, errors.New("bukan log ERC-20 Transfer")
+	}
+	if len(l.Data) != 32 {
+		return nil, errors.New("data log Transfer tidak valid")
+	}
+
+	return &Transfer{
+		Token:       l.Address,
+		From:        common.BytesToAddress(l.Topics[1].Bytes()),
+		To:          common.BytesToAddress(l.Topics[2].Bytes()),
+		Value:       new(big.Int).SetBytes(l.Data),
+		TxHash:      l.TxHash,
+		LogIndex:    l.Index,
+		BlockNumber: l.BlockNumber,
+	}, nil
+}
+
+// ToDecimal mengubah nilai on-chain (wei) menjadi decimal dengan jumlah desimal token.
+func ToDecimal(value *big.Int, decimals int32) decimal.Decimal {
+	return decimal.NewFromBigInt(value, -decimals)
+}
+
+// ToWei mengubah decimal menjadi nilai on-chain dengan jumlah desimal token (dibulatkan ke bawah).
+func ToWei(amount decimal.Decimal, decimals int32) *big.Int {
+	return amount.Shift(decimals).Truncate(0).BigInt()
+}
+
+// PackTransfer menyusun calldata ERC-20 transfer(to, value).
+func PackTransfer(to common.Address, value *big.Int) []byte {
+	data := make([]byte, 0, 4+32+32)
+	data = append(data, transferMethodID...)
+	data = append(data, common.LeftPadBytes(to.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(value.Bytes(), 32)...)
+	return data
+}
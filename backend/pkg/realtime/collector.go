@@ -0,0 +1,96 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ContextKey adalah key gin.Context tempat Collector request disimpan (handler meneruskan `c` sebagai context).
+const ContextKey = "realtime_collector"
+
+type collectorCtxKey struct{}
+
+// Collector menampung event dan user yang saldonya berubah selama satu operasi, untuk dipublikasikan
+// setelah transaksi DB commit. Event yang dikirim sebelum commit bisa membuat klien membaca data lama.
+type Collector struct {
+	mu     sync.Mutex
+	users  map[uuid.UUID]struct{}
+	events []Event
+}
+
+func NewCollector() *Collector {
+	return &Collector{users: map[uuid.UUID]struct{}{}}
+}
+
+// WithCollector memasang Collector baru ke ctx.
+func WithCollector(ctx context.Context) (context.Context, *Collector) {
+	col := NewCollector()
+	return context.WithValue(ctx, collectorCtxKey{}, col), col
+}
+
+// FromContext mengambil Collector dari context biasa maupun gin.Context.
+func FromContext(ctx context.Context) *Collector {
+	if ctx == nil {
+		return nil
+	}
+	if col, ok := ctx.Value(collectorCtxKey{}).(*Collector); ok {
+		return col
+	}
+	if col, ok := ctx.Value(ContextKey).(*Collector); ok {
+		return col
+	}
+	return nil
+}
+
+// Touch menandai saldo/inventory user berubah. Tanpa Collector di ctx, panggilan diabaikan.
+func Touch(ctx context.Context, userIDs ...uuid.UUID) {
+	col := FromContext(ctx)
+	if col == nil {
+		return
+	}
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	for _, id := range userIDs {
+		col.users[id] = struct{}{}
+	}
+}
+
+// Emit mengantrekan event sampai Collector di-flush. Tanpa Collector, event langsung dipublikasikan,
+// jadi pemanggil di luar request/worker harus memanggilnya setelah commit.
+func Emit(ctx context.Context, eventType, userID string, data interface{}) {
+	evt, err := NewEvent(eventType, userID, data)
+	if err != nil {
+		log.Printf("[REALTIME] Gagal encode event %s: %v", eventType, err)
+		return
+	}
+	col := FromContext(ctx)
+	if col == nil {
+		Publish(ctx, evt)
+		return
+	}
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	col.events = append(col.events, evt)
+}
+
+// Drain mengambil dan mengosongkan isi Collector.
+func (col *Collector) Drain() ([]uuid.UUID, []Event) {
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	users := make([]uuid.UUID, 0, len(col.users))
+	for id := range col.users {
+		users = append(users, id)
+	}
+	events := col.events
+	col.users = map[uuid.UUID]struct{}{}
+	col.events = nil
+	return users, events
+}
+
+// Discard membuang isi Collector, mis. saat transaksi gagal dan tidak ada yang berubah.
+func (col *Collector) Discard() {
+	col.Drain()
+}
@@ -0,0 +1,171 @@
+// Package realtime menyalurkan event pasar & farm ke koneksi stream klien.
+//
+// Event dipublikasikan ke channel Redis agar sampai ke klien yang terhubung di replica API mana pun.
+// Jika Redis tidak aktif (mode DEV), event langsung dikirim ke subscriber di proses ini.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	customRedis "cashcowvalley/backend/pkg/redis"
+)
+
+// Jenis event
+const (
+	EventListingCreated   = "listing.created"   // Broadcast: listing baru di marketplace
+	EventListingSold      = "listing.sold"      // Broadcast: listing terisi sebagian/penuh
+	EventBalanceUpdated   = "balance.updated"   // Per user: saldo GOLD/USDT/Points berubah
+	EventInventoryUpdated = "inventory.updated" // Per user: GRASS/MILK/sapi berubah
+	EventHarvestReady     = "harvest.ready"     // Per user: ada sapi yang baru siap dipanen
+	EventOfferUpdated     = "offer.updated"     // Per user: offer pada listing dibuat, di-counter atau selesai
+)
+
+const (
+	redisChannel     = "cashcow:realtime"
+	subscriberBuffer = 64
+)
+
+// Event adalah satu pesan stream. UserID kosong berarti broadcast ke semua koneksi.
+type Event struct {
+	Type   string          `json:"type"`
+	UserID string          `json:"-"`
+	Data   json.RawMessage `json:"data"`
+	At     time.Time       `json:"at"`
+}
+
+// envelope adalah bentuk Event di channel Redis (UserID ikut dikirim, tidak seperti ke klien).
+type envelope struct {
+	Type   string          `json:"type"`
+	UserID string          `json:"user_id,omitempty"`
+	Data   json.RawMessage `json:"data"`
+	At     time.Time       `json:"at"`
+}
+
+type subscriber struct {
+	userID string
+	ch     chan Event
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = map[*subscriber]struct{}{}
+	closed      bool
+)
+
+// NewEvent membuat Event dengan payload JSON dari `data`.
+func NewEvent(eventType, userID string, data interface{}) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: eventType, UserID: userID, Data: raw, At: time.Now().UTC()}, nil
+}
+
+// Publish mengirim event ke semua replica. Kegagalan hanya dicatat: event real-time bersifat best-effort,
+// klien tetap bisa memuat ulang data lewat REST.
+func Publish(ctx context.Context, evt Event) {
+	if customRedis.Client == nil {
+		dispatch(evt)
+		return
+	}
+	payload, err := json.Marshal(envelope(evt))
+	if err != nil {
+		log.Printf("[REALTIME] Gagal encode event %s: %v", evt.Type, err)
+		return
+	}
+	if err := customRedis.Client.Publish(ctx, redisChannel, payload).Err(); err != nil {
+		log.Printf("[REALTIME] Gagal publish event %s: %v", evt.Type, err)
+	}
+}
+
+// Subscribe mendaftarkan satu koneksi stream untuk user. Channel ditutup saat unsubscribe
+// dipanggil atau saat server berhenti.
+func Subscribe(userID string) (<-chan Event, func()) {
+	sub := &subscriber{userID: userID, ch: make(chan Event, subscriberBuffer)}
+
+	mu.Lock()
+	if closed {
+		mu.Unlock()
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	subscribers[sub] = struct{}{}
+	mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := subscribers[sub]; ok {
+				delete(subscribers, sub)
+				close(sub.ch)
+			}
+		})
+	}
+}
+
+// dispatch meneruskan event ke subscriber lokal yang berhak menerimanya.
+// Subscriber yang lambat (buffer penuh) dilewati agar satu koneksi tidak menahan yang lain.
+func dispatch(evt Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for sub := range subscribers {
+		if evt.UserID != "" && evt.UserID != sub.userID {
+			continue
+		}
+		select {
+		case sub.ch <- evt:
+		default:
+			log.Printf("[REALTIME] Buffer penuh, event %s untuk user %s dilewati", evt.Type, sub.userID)
+		}
+	}
+}
+
+// Start meneruskan event dari channel Redis ke subscriber lokal sampai ctx selesai,
+// lalu menutup semua koneksi stream agar graceful shutdown tidak tertahan.
+func Start(ctx context.Context) {
+	defer closeAll()
+
+	if customRedis.Client == nil {
+		log.Println("[REALTIME] Redis tidak aktif, event hanya dikirim ke koneksi di proses ini")
+		<-ctx.Done()
+		return
+	}
+
+	pubsub := customRedis.Client.Subscribe(ctx, redisChannel)
+	defer pubsub.Close()
+	log.Println("[REALTIME] Subscriber Redis aktif")
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var env envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				log.Printf("[REALTIME] Payload tidak valid: %v", err)
+				continue
+			}
+			dispatch(Event(env))
+		}
+	}
+}
+
+func closeAll() {
+	mu.Lock()
+	defer mu.Unlock()
+	closed = true
+	for sub := range subscribers {
+		delete(subscribers, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// devOnce menggantikan Redis untuk key sekali pakai di mode DEV (satu instance, tanpa REDIS_URL),
+// supaya tiket tetap sekali pakai walau tanpa Redis.
+var devOnce = struct {
+	sync.Mutex
+	keys map[string]time.Time
+}{keys: map[string]time.Time{}}
+
+// StoreOnce mendaftarkan key sekali pakai yang kedaluwarsa setelah expiration.
+// Mengembalikan false jika key sudah terdaftar (mis. jti bentrok) atau Redis gagal.
+func StoreOnce(ctx context.Context, key string, expiration time.Duration) bool {
+	if Client == nil {
+		devOnce.Lock()
+		defer devOnce.Unlock()
+		now := time.Now()
+		for k, exp := range devOnce.keys {
+			if !now.Before(exp) {
+				delete(devOnce.keys, k)
+			}
+		}
+		if _, exists := devOnce.keys[key]; exists {
+			return false
+		}
+		devOnce.keys[key] = now.Add(expiration)
+		return true
+	}
+
+	success, err := Client.SetNX(ctx, "once:"+key, 1, expiration).Result()
+	return err == nil && success
+}
+
+// ConsumeOnce menghapus key sekali pakai. Hanya pemanggil pertama yang mendapat true;
+// key yang sudah dipakai, kedaluwarsa, atau tidak pernah didaftarkan mengembalikan false.
+func ConsumeOnce(ctx context.Context, key string) bool {
+	if Client == nil {
+		devOnce.Lock()
+		defer devOnce.Unlock()
+		exp, exists := devOnce.keys[key]
+		delete(devOnce.keys, key)
+		return exists && time.Now().Before(exp)
+	}
+
+	deleted, err := Client.Del(ctx, "once:"+key).Result()
+	return err == nil && deleted == 1
+}